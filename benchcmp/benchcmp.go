@@ -0,0 +1,569 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package benchcmp runs benchmarks on several configurations and compares
+// their go test -bench results, as benchstat does.
+//
+// It is the comparison engine of cmd/ba, usable by other tools without
+// shelling out to it.
+package benchcmp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/benchfmt"
+	"golang.org/x/perf/benchmath"
+	"golang.org/x/perf/benchproc"
+	"golang.org/x/perf/benchunit"
+)
+
+// confidence is the confidence level of the intervals.
+const confidence = 0.95
+
+// Options are the statistical settings used to compare the results.
+type Options struct {
+	// Alpha is the p-value cutoff to report a change as significant.
+	Alpha float64
+	// DeltaTest is the test to decide if a change is significant: utest,
+	// ttest or none.
+	DeltaTest string
+	// Geomean adds a row with the geometric mean of all the benchmarks.
+	Geomean bool
+	// Trim is the percentage of the worst values of each benchmark and side to
+	// discard before computing the statistics.
+	Trim float64
+	// Winsorize replaces the trimmed values with the worst value kept instead
+	// of discarding them.
+	Winsorize bool
+	// Renames maps the name of a benchmark, without the "Benchmark" prefix and
+	// the GOMAXPROCS suffix, to its new name so the benchmarks renamed between
+	// the configurations are compared in a single row. The sub-benchmarks are
+	// renamed too.
+	Renames map[string]string
+	// MaxCV, when set, is the coefficient of variation in percent above which
+	// a benchmark is too noisy to be compared. Its delta is not computed and it
+	// is marked Noisy.
+	MaxCV float64
+	// Group groups the sub-benchmarks under their parent benchmark, e.g.
+	// "Encode/size=1K" and "Encode/size=1M" under "Encode", and adds a
+	// geomean row to each group of more than one.
+	Group bool
+	// Better overrides whether higher (1) or lower (-1) values of a tidied
+	// unit are better, e.g. {"hit-ratio": 1} for a custom metric reported
+	// with b.ReportMetric.
+	Better map[string]int
+}
+
+// rename returns the new name of the benchmark name.
+func (o *Options) rename(name string) string {
+	if n, ok := o.Renames[name]; ok {
+		return n
+	}
+	for old, n := range o.Renames {
+		if strings.HasPrefix(name, old+"/") {
+			return n + name[len(old):]
+		}
+	}
+	return name
+}
+
+// better returns whether higher (1) or lower (-1) values of the tidied unit
+// are better, or 0 when unknown.
+//
+// In order: o.Better, the "better" unit metadata printed by the benchmarks,
+// e.g. "Unit hit-ratio better=higher", then the unit itself. Rates, e.g.
+// "items/s", are better higher and durations and sizes, e.g. "p99-sec" or
+// "peak-B", are better lower.
+func (o *Options) better(meta benchfmt.UnitMetadataMap, unit string) int {
+	if b, ok := o.Better[unit]; ok {
+		return b
+	}
+	if b := meta.GetBetter(unit); b != 0 {
+		return b
+	}
+	switch {
+	case strings.HasSuffix(unit, "/s"):
+		return 1
+	case unit == "sec" || strings.HasSuffix(unit, "-sec") || strings.HasPrefix(unit, "sec/"),
+		unit == "B" || strings.HasSuffix(unit, "-B") || strings.HasPrefix(unit, "B/"):
+		return -1
+	default:
+		return 0
+	}
+}
+
+// BetterOf returns 1 when higher values of unit are better, -1 when lower
+// values are better, or 0 when unknown, as Compare infers it for outputs
+// without unit metadata.
+func (o *Options) BetterOf(unit string) int {
+	return o.better(nil, unit)
+}
+
+// DefaultOptions matches benchstat's defaults.
+var DefaultOptions = &Options{Alpha: 0.05, DeltaTest: "utest"}
+
+// Table compares one unit of all the benchmarks across the configurations.
+type Table struct {
+	// Unit is the tidied unit, e.g. sec/op or B/s.
+	Unit string
+	// Better is 1 when higher values are better, -1 when lower values are
+	// better and 0 when unknown.
+	Better int
+	// Configs are the names of the configurations. The first one is the
+	// baseline.
+	Configs []string
+	// Delta is true when the rows compare exactly two configurations.
+	Delta bool
+	// Procs is the GOMAXPROCS of all the rows when the benchmarks ran with
+	// multiple values, e.g. with -cpu 1,4. It is 0 otherwise and the value is
+	// kept as a suffix in the benchmark names instead.
+	Procs int
+	Rows  []*Row
+}
+
+// OneSided returns the index of the only configuration with values for the
+// row, when the table compares two configurations, e.g. a benchmark that was
+// added or removed. It returns -1 otherwise.
+func (t *Table) OneSided(r *Row) int {
+	if !t.Delta {
+		return -1
+	}
+	switch {
+	case r.Cells[0] == nil && r.Cells[1] != nil:
+		return 1
+	case r.Cells[0] != nil && r.Cells[1] == nil:
+		return 0
+	default:
+		return -1
+	}
+}
+
+// Name returns the benchmark name of the row, with the GOMAXPROCS suffix when
+// the table has one, e.g. "Encode-4".
+func (t *Table) Name(r *Row) string {
+	if t.Procs == 0 {
+		return r.Benchmark
+	}
+	return r.Benchmark + "-" + strconv.Itoa(t.Procs)
+}
+
+// Row is one benchmark of a table.
+type Row struct {
+	Benchmark string
+	// Cells is the summary of each configuration, nil when the benchmark did
+	// not run in it.
+	Cells []*Cell
+	// Delta is the formatted change of the center from the baseline, or "~"
+	// when not significant. PctDelta is the change in percent when
+	// significant.
+	Delta    string
+	PctDelta float64
+	// Change is 1 for an improvement, -1 for a regression, 0 when not
+	// significant or when the direction is unknown.
+	Change int
+	// Note is the p-value and sample sizes, e.g. "p=0.016 n=4+5".
+	Note string
+	// P is the p-value of the comparison, -1 when not computed.
+	P float64
+	// EffectSize is Cohen's d of the change from the baseline: the difference
+	// of the means in pooled standard deviations. It is NaN when not computed.
+	EffectSize float64
+	// Warnings are the statistical problems found, e.g. too few samples.
+	Warnings []string
+	// Noisy is set when the coefficient of variation of a configuration
+	// exceeds Options.MaxCV. The row has no delta.
+	Noisy bool
+	// Group is the parent benchmark of a sub-benchmark with Options.Group,
+	// e.g. "Encode" for "Encode/size=1K". The rows of a group are
+	// contiguous and end with its geomean row, "Encode/geomean".
+	Group string
+}
+
+// Cell is the summary of a benchmark in one configuration.
+type Cell struct {
+	benchmath.Summary
+	// Sample is nil for the geomean row.
+	Sample *benchmath.Sample
+}
+
+// Format formats the center with its confidence interval, e.g. "13.6m ± 1%".
+func (c *Cell) Format(unit string) string {
+	s := benchunit.Scale(c.Center, benchunit.ClassOf(unit))
+	if c.Sample == nil {
+		return s
+	}
+	return s + " ± " + c.PctRangeString()
+}
+
+// CV returns the coefficient of variation of the values in percent: their
+// standard deviation relative to their mean. It is NaN when there are fewer
+// than two values.
+func (c *Cell) CV() float64 {
+	if c.Sample == nil || len(c.Sample.Values) < 2 {
+		return math.NaN()
+	}
+	m, v := meanVar(c.Sample.Values)
+	if m == 0 {
+		return math.NaN()
+	}
+	return math.Sqrt(v) / math.Abs(m) * 100
+}
+
+// TrimValues returns the values without the worst o.Trim percent, or with
+// them replaced by the worst value kept when o.Winsorize is set. The lowest
+// values are the worst when better is positive, the highest otherwise.
+func (o *Options) TrimValues(v []float64, better int) []float64 {
+	n := int(float64(len(v)) * o.Trim / 100)
+	if n == 0 {
+		return v
+	}
+	s := append([]float64(nil), v...)
+	if better > 0 {
+		sort.Sort(sort.Reverse(sort.Float64Slice(s)))
+	} else {
+		sort.Float64s(s)
+	}
+	keep := len(s) - n
+	if !o.Winsorize {
+		return s[:keep]
+	}
+	for i := keep; i < len(s); i++ {
+		s[i] = s[keep-1]
+	}
+	return s
+}
+
+// Compare returns the tables comparing the go test -bench outputs of any
+// number of configurations, one table per unit, and per GOMAXPROCS when the
+// benchmarks ran with multiple values. Deltas are only computed when there
+// are exactly two.
+func Compare(names, outputs []string, o *Options) ([]*Table, error) {
+	var pp benchproc.ProjectionParser
+	// Projecting /gomaxprocs removes it from .fullname.
+	procsBy, err := pp.Parse("/gomaxprocs", nil)
+	if err != nil {
+		return nil, err
+	}
+	rowBy, err := pp.Parse(".fullname", nil)
+	if err != nil {
+		return nil, err
+	}
+	type key struct {
+		unit  string
+		procs string
+		row   string
+	}
+	// Values of each configuration for each unit and benchmark.
+	values := map[key][][]float64{}
+	var units, rows, procs []string
+	meta := benchfmt.UnitMetadataMap{}
+	for i, out := range outputs {
+		r := benchfmt.NewReader(strings.NewReader(out), names[i])
+		for r.Scan() {
+			res, ok := r.Result().(*benchfmt.Result)
+			if !ok {
+				continue
+			}
+			rk := o.rename(rowBy.Project(res).StringValues())
+			if !contains(rows, rk) {
+				rows = append(rows, rk)
+			}
+			pk := procsBy.Project(res).StringValues()
+			if pk == "" {
+				pk = "1"
+			}
+			if !contains(procs, pk) {
+				procs = append(procs, pk)
+			}
+			for _, v := range res.Values {
+				k := key{v.Unit, pk, rk}
+				vals := values[k]
+				if vals == nil {
+					vals = make([][]float64, len(outputs))
+					values[k] = vals
+					if !contains(units, v.Unit) {
+						units = append(units, v.Unit)
+					}
+				}
+				vals[i] = append(vals[i], v.Value)
+			}
+		}
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		for k, m := range r.Units() {
+			meta[k] = m
+		}
+	}
+	var out []*Table
+	for _, pk := range procs {
+		for _, u := range units {
+			tbl := &Table{Unit: u, Better: o.better(meta, u), Configs: names, Delta: len(names) == 2}
+			if len(procs) > 1 {
+				tbl.Procs, _ = strconv.Atoi(pk)
+			}
+			for _, rk := range rows {
+				vals := values[key{u, pk, rk}]
+				if vals == nil {
+					continue
+				}
+				name := rk
+				if tbl.Procs == 0 && pk != "1" {
+					name += "-" + pk
+				}
+				tbl.Rows = append(tbl.Rows, newRow(name, vals, meta, tbl, o))
+			}
+			if len(tbl.Rows) == 0 {
+				continue
+			}
+			var g *Row
+			if o.Geomean && len(tbl.Rows) > 1 {
+				g = geomeanRow(tbl)
+			}
+			if o.Group {
+				tbl.Rows = groupRows(tbl)
+			}
+			if g != nil {
+				tbl.Rows = append(tbl.Rows, g)
+			}
+			out = append(out, tbl)
+		}
+	}
+	return out, nil
+}
+
+// newRow summarizes the values of each configuration for one benchmark of
+// the table.
+func newRow(name string, vals [][]float64, meta benchfmt.UnitMetadataMap, tbl *Table, o *Options) *Row {
+	a := meta.GetAssumption(tbl.Unit)
+	if a == benchmath.AssumeNothing && o.DeltaTest == "ttest" {
+		a = benchmath.AssumeNormal
+	}
+	thr := benchmath.DefaultThresholds
+	thr.CompareAlpha = o.Alpha
+	r := &Row{Benchmark: name, Cells: make([]*Cell, len(tbl.Configs)), P: -1, EffectSize: math.NaN()}
+	for i, v := range vals {
+		if len(v) == 0 {
+			continue
+		}
+		c := &Cell{Sample: benchmath.NewSample(o.TrimValues(v, tbl.Better), &thr)}
+		c.Summary = a.Summary(c.Sample, confidence)
+		r.warn(c.Sample.Warnings)
+		r.warn(c.Summary.Warnings)
+		r.Cells[i] = c
+		if o.MaxCV > 0 && c.CV() > o.MaxCV {
+			r.Noisy = true
+		}
+	}
+	if tbl.Delta && !r.Noisy {
+		r.compare(a, o, tbl.Better)
+	}
+	return r
+}
+
+// compare fills the delta of a row comparing two configurations.
+func (r *Row) compare(a benchmath.Assumption, o *Options, better int) {
+	old, new := r.Cells[0], r.Cells[1]
+	if old == nil || new == nil {
+		return
+	}
+	r.EffectSize = cohensD(old.Sample.Values, new.Sample.Values)
+	if o.DeltaTest == "none" {
+		r.Delta = PctDelta(old.Center, new.Center)
+		r.Note = fmt.Sprintf("n=%d+%d", len(old.Sample.Values), len(new.Sample.Values))
+		return
+	}
+	c := a.Compare(old.Sample, new.Sample)
+	// Not all the assumptions set it.
+	c.Alpha = o.Alpha
+	r.warn(c.Warnings)
+	r.P = c.P
+	r.Delta = c.FormatDelta(old.Center, new.Center)
+	r.Note = c.String()
+	if r.Delta == "~" || old.Center == 0 || old.Center == new.Center {
+		return
+	}
+	r.PctDelta = (new.Center/old.Center - 1) * 100
+	if better != 0 {
+		r.Change = better
+		if r.PctDelta < 0 {
+			r.Change = -better
+		}
+	}
+}
+
+func (r *Row) warn(errs []error) {
+	for _, err := range errs {
+		if s := err.Error(); !contains(r.Warnings, s) {
+			r.Warnings = append(r.Warnings, s)
+		}
+	}
+}
+
+// geomeanRow returns the geometric mean of the benchmarks that ran in all
+// the configurations, or nil if there are none.
+func geomeanRow(t *Table) *Row {
+	sums := make([]float64, len(t.Configs))
+	n, noisy := 0, false
+	for _, r := range t.Rows {
+		if r.Noisy {
+			noisy = true
+			continue
+		}
+		ok := true
+		for _, c := range r.Cells {
+			if c == nil || c.Center <= 0 {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		for i, c := range r.Cells {
+			sums[i] += math.Log(c.Center)
+		}
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	g := &Row{Benchmark: "geomean", Cells: make([]*Cell, len(t.Configs)), P: -1, EffectSize: math.NaN()}
+	for i := range sums {
+		g.Cells[i] = &Cell{Summary: benchmath.Summary{Center: math.Exp(sums[i] / float64(n))}}
+	}
+	if t.Delta {
+		g.Delta = PctDelta(g.Cells[0].Center, g.Cells[1].Center)
+	}
+	if noisy {
+		g.Warnings = []string{"the benchmarks too noisy to compare are excluded"}
+	} else if n != len(t.Rows) {
+		g.Warnings = []string{"only the benchmarks present in all configurations are included"}
+	}
+	return g
+}
+
+// groupRows returns the rows of the table with the sub-benchmarks of each
+// parent benchmark together, in the order the parents first appear, each group
+// of more than one followed by its geomean row.
+func groupRows(t *Table) []*Row {
+	var names []string
+	groups := map[string][]*Row{}
+	for _, r := range t.Rows {
+		n := r.Benchmark
+		if i := strings.IndexByte(n, '/'); i != -1 {
+			n = n[:i]
+			r.Group = n
+		}
+		if _, ok := groups[n]; !ok {
+			names = append(names, n)
+		}
+		groups[n] = append(groups[n], r)
+	}
+	out := make([]*Row, 0, len(t.Rows)+len(names))
+	for _, n := range names {
+		rows := groups[n]
+		out = append(out, rows...)
+		if len(rows) < 2 || rows[0].Group == "" {
+			continue
+		}
+		sub := *t
+		sub.Rows = rows
+		if g := geomeanRow(&sub); g != nil {
+			g.Benchmark = n + "/geomean"
+			g.Group = n
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// cohensD returns the difference of the means of b and a divided by their
+// pooled standard deviation, or NaN when there are too few values or no
+// variance.
+func cohensD(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return math.NaN()
+	}
+	ma, va := meanVar(a)
+	mb, vb := meanVar(b)
+	sd := math.Sqrt((float64(len(a)-1)*va + float64(len(b)-1)*vb) / float64(len(a)+len(b)-2))
+	// Relative to the values to ignore rounding errors, e.g. with equal values.
+	if sd <= 1e-9*math.Max(math.Abs(ma), math.Abs(mb)) {
+		return math.NaN()
+	}
+	return (mb - ma) / sd
+}
+
+// meanVar returns the mean and the sample variance of v.
+func meanVar(v []float64) (float64, float64) {
+	m := 0.
+	for _, x := range v {
+		m += x
+	}
+	m /= float64(len(v))
+	s := 0.
+	for _, x := range v {
+		s += (x - m) * (x - m)
+	}
+	return m, s / float64(len(v)-1)
+}
+
+// PctDelta formats the change from old to new in percent, e.g. "+1.20%".
+func PctDelta(old, new float64) string {
+	if old == 0 {
+		return "?"
+	}
+	return fmt.Sprintf("%+.2f%%", (new/old-1)*100)
+}
+
+// Footnotes assigns a marker to each distinct warning of the table's rows.
+//
+// It returns the marker of each row and the footnotes to print after the
+// table.
+func Footnotes(t *Table) ([]string, []string) {
+	markers := make([]string, len(t.Rows))
+	var notes []string
+	for i, r := range t.Rows {
+		for _, w := range r.Warnings {
+			j := 0
+			for ; j < len(notes); j++ {
+				if notes[j] == w {
+					break
+				}
+			}
+			if j == len(notes) {
+				notes = append(notes, w)
+			}
+			markers[i] += superscript(j + 1)
+		}
+	}
+	for i := range notes {
+		notes[i] = superscript(i+1) + " " + notes[i]
+	}
+	return markers, notes
+}
+
+func superscript(i int) string {
+	const digits = "⁰¹²³⁴⁵⁶⁷⁸⁹"
+	d := []rune(digits)
+	s := ""
+	for ; i > 0; i /= 10 {
+		s = string(d[i%10]) + s
+	}
+	return s
+}
+
+func contains(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
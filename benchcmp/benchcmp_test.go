@@ -0,0 +1,352 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package benchcmp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	old := strings.Repeat("BenchmarkA \t1\t10 ns/op\nBenchmarkB \t1\t20 ns/op\n", 6)
+	new := strings.Repeat("BenchmarkA \t1\t12 ns/op\nBenchmarkB \t1\t20 ns/op\n", 6)
+	tables, err := Compare([]string{"old", "new"}, []string{old, new}, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].Unit != "sec/op" || !tables[0].Delta || len(tables[0].Rows) != 2 {
+		t.Fatalf("%+v", tables)
+	}
+	if r := tables[0].Rows[0]; r.Delta != "+20.00%" || r.Change != -1 {
+		t.Fatalf("%+v", r)
+	}
+	if r := tables[0].Rows[1]; r.Delta != "~" || r.Change != 0 {
+		t.Fatalf("%+v", r)
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, tables, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "A     10.00n ± 0%  12.00n ± 0%  +20.00%  (p=0.002 n=6)\n") {
+		t.Fatal(buf.String())
+	}
+	buf.Reset()
+	if err := WriteText(&buf, tables, &TextOptions{Stats: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "delta      p    n  d\n") || !strings.Contains(buf.String(), "+20.00%  0.002  6+6") {
+		t.Fatal(buf.String())
+	}
+	buf.Reset()
+	if err := WriteJSON(&buf, tables); err != nil {
+		t.Fatal(err)
+	}
+	var got []*JSONTable
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Rows[0].Cells[1].N != 6 || got[0].Rows[0].PValue == nil {
+		t.Fatal(buf.String())
+	}
+}
+
+func TestCohensD(t *testing.T) {
+	if d := cohensD([]float64{1, 2, 3}, []float64{3, 4, 5}); d != 2 {
+		t.Fatal(d)
+	}
+	if d := cohensD([]float64{1}, []float64{3, 4, 5}); !math.IsNaN(d) {
+		t.Fatal(d)
+	}
+	if d := cohensD([]float64{1, 1}, []float64{1, 1}); !math.IsNaN(d) {
+		t.Fatal(d)
+	}
+}
+
+func TestCompareRenames(t *testing.T) {
+	old := strings.Repeat("BenchmarkParse/small \t1\t10 ns/op\nBenchmarkB \t1\t20 ns/op\n", 6)
+	new := strings.Repeat("BenchmarkParseJSON/small \t1\t12 ns/op\nBenchmarkB \t1\t20 ns/op\n", 6)
+	o := &Options{Alpha: 0.05, DeltaTest: "utest", Renames: map[string]string{"Parse": "ParseJSON"}}
+	tables, err := Compare([]string{"old", "new"}, []string{old, new}, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || len(tables[0].Rows) != 2 {
+		t.Fatalf("%+v", tables)
+	}
+	if r := tables[0].Rows[0]; r.Benchmark != "ParseJSON/small" || r.Delta != "+20.00%" {
+		t.Fatalf("%+v", r)
+	}
+}
+
+func TestWriteTextOneSided(t *testing.T) {
+	old := strings.Repeat("BenchmarkA \t1\t10 ns/op\nBenchmarkOld \t1\t5 ns/op\n", 6)
+	new := strings.Repeat("BenchmarkA \t1\t10 ns/op\nBenchmarkNew \t1\t7 ns/op\n", 6)
+	tables, err := Compare([]string{"old", "new"}, []string{old, new}, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := tables[0].OneSided(tables[0].Rows[0]); i != -1 {
+		t.Fatal(i)
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, tables, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "name   old sec/op   new sec/op  delta\n" +
+		"A     10.00n ± 0%  10.00n ± 0%      ~  (p=1.000 n=6) ¹\n" +
+		"\n" +
+		"only in old       sec/op\n" +
+		"Old          5.000n ± 0%\n" +
+		"\n" +
+		"only in new       sec/op\n" +
+		"New          7.000n ± 0%\n" +
+		"¹ all samples are equal\n"
+	if got := buf.String(); got != want {
+		t.Fatal(got)
+	}
+}
+
+func TestWriteTextNoisy(t *testing.T) {
+	old := strings.Repeat("BenchmarkA \t1\t10 ns/op\n", 6) + strings.Repeat("BenchmarkB \t1\t10 ns/op\nBenchmarkB \t1\t20 ns/op\n", 3)
+	new := strings.Repeat("BenchmarkA \t1\t10 ns/op\nBenchmarkB \t1\t10 ns/op\n", 6)
+	o := *DefaultOptions
+	o.MaxCV = 5
+	o.Geomean = true
+	tables, err := Compare([]string{"old", "new"}, []string{old, new}, &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := tables[0].Rows[1]
+	if !r.Noisy || r.Delta != "" || r.Change != 0 {
+		t.Fatalf("%+v", r)
+	}
+	if cv := r.Cells[0].CV(); cv < 36 || cv > 37 {
+		t.Fatal(cv)
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, tables, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "name      old sec/op   new sec/op   delta\n" +
+		"A        10.00n ± 0%  10.00n ± 0%       ~  (p=1.000 n=6) ¹\n" +
+		"geomean       10.00n       10.00n  +0.00%  ²\n" +
+		"\n" +
+		"too noisy to compare  old CV  new CV\n" +
+		"B                      36.5%    0.0%\n" +
+		"¹ all samples are equal\n" +
+		"² the benchmarks too noisy to compare are excluded\n"
+	if got := buf.String(); got != want {
+		t.Fatal(got)
+	}
+}
+
+func TestWriteTextGroup(t *testing.T) {
+	old := strings.Repeat("BenchmarkEncode/size=1K \t1\t10 ns/op\nBenchmarkFlat \t1\t5 ns/op\nBenchmarkEncode/size=1M \t1\t1000 ns/op\n", 6)
+	new := strings.Repeat("BenchmarkEncode/size=1K \t1\t10 ns/op\nBenchmarkFlat \t1\t5 ns/op\nBenchmarkEncode/size=1M \t1\t4000 ns/op\n", 6)
+	o := *DefaultOptions
+	o.Group = true
+	tables, err := Compare([]string{"old", "new"}, []string{old, new}, &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range tables[0].Rows {
+		names = append(names, r.Group+":"+r.Benchmark)
+	}
+	if got := strings.Join(names, ","); got != "Encode:Encode/size=1K,Encode:Encode/size=1M,Encode:Encode/geomean,:Flat" {
+		t.Fatal(got)
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, tables, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "name        old sec/op   new sec/op     delta\n" +
+		"Encode\n" +
+		"  size=1K  10.00n ± 0%  10.00n ± 0%         ~  (p=1.000 n=6) ¹\n" +
+		"  size=1M  1.000µ ± 0%  4.000µ ± 0%  +300.00%  (p=0.002 n=6)\n" +
+		"  geomean       100.0n       200.0n  +100.00%\n" +
+		"Flat       5.000n ± 0%  5.000n ± 0%         ~  (p=1.000 n=6) ¹\n" +
+		"¹ all samples are equal\n"
+	if got := buf.String(); got != want {
+		t.Fatal(got)
+	}
+}
+
+func TestCompareBetter(t *testing.T) {
+	out := "Unit score better=higher\n" +
+		"BenchmarkA \t1\t10 ns/op\t5 items/s\t100 p99-ns\t3 peak-B\t7 score\t9 hits/op\t1 misses/op\n"
+	o := *DefaultOptions
+	o.Better = map[string]int{"misses/op": -1}
+	tables, err := Compare([]string{"old", "new"}, []string{out, out}, &o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"sec/op": -1, "items/s": 1, "p99-sec": -1, "peak-B": -1, "score": 1, "hits/op": 0, "misses/op": -1}
+	if len(tables) != len(want) {
+		t.Fatal(len(tables))
+	}
+	for _, tbl := range tables {
+		if b, ok := want[tbl.Unit]; !ok || tbl.Better != b {
+			t.Fatalf("%s: %d", tbl.Unit, tbl.Better)
+		}
+	}
+}
+
+func TestTrimValues(t *testing.T) {
+	v := []float64{3, 1, 10, 2, 4}
+	data := []struct {
+		trim      float64
+		winsorize bool
+		better    int
+		want      []float64
+	}{
+		{0, false, -1, []float64{3, 1, 10, 2, 4}},
+		{10, false, -1, []float64{3, 1, 10, 2, 4}},
+		{20, false, -1, []float64{1, 2, 3, 4}},
+		{20, true, -1, []float64{1, 2, 3, 4, 4}},
+		{40, false, 1, []float64{10, 4, 3}},
+	}
+	for i, l := range data {
+		o := &Options{Trim: l.trim, Winsorize: l.winsorize}
+		if got := o.TrimValues(v, l.better); !reflect.DeepEqual(got, l.want) {
+			t.Fatal(i, got)
+		}
+	}
+}
+
+func TestRunnerArgs(t *testing.T) {
+	r := &Runner{}
+	want := []string{"-run", "^$", "-bench", ".", "-benchtime", "100ms", "-count", "2", "-cpu", "1", "./..."}
+	if got := r.args(); !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+	r = &Runner{Pkg: "./x", Bench: "Foo", Benchtime: time.Second, Count: 5, CPU: "1,4", Benchmem: true}
+	want = []string{"-run", "^$", "-bench", "Foo", "-benchtime", "1s", "-count", "5", "-cpu", "1,4", "-benchmem", "./x"}
+	if got := r.args(); !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestRunnerRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go test")
+	}
+	var sides []*Side
+	for _, n := range []string{"old", "new"} {
+		d := t.TempDir()
+		for f, c := range map[string]string{
+			"go.mod":    "module example.com/x\n",
+			"x_test.go": "package x\n\nimport \"testing\"\n\nfunc BenchmarkX(b *testing.B) {\n\tfor i := 0; i < b.N; i++ {\n\t}\n}\n",
+		} {
+			if err := os.WriteFile(filepath.Join(d, f), []byte(c), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sides = append(sides, &Side{Name: n, Dir: d})
+	}
+	r := &Runner{Benchtime: time.Millisecond, Count: 1, Series: 2}
+	res, err := r.Run(context.Background(), sides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Sides) != 2 || res.Series != 2 || strings.Count(res.Sides[1].Output, "BenchmarkX") != 2 {
+		t.Fatalf("%+v", res.Sides[1])
+	}
+	tables, err := res.Compare(DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].Configs[0] != "old" || tables[0].Rows[0].Benchmark != "X" {
+		t.Fatalf("%+v", tables)
+	}
+}
+
+func TestRunnerHooks(t *testing.T) {
+	sides := []*Side{{Name: "old", Output: "s0\n"}, {Name: "new", Output: "s0\n"}}
+	// Series 2 fails once, series 3 is discarded, series 4 is run again once
+	// and series 1 is a warmup outlier.
+	calls := 0
+	var done []int
+	r := &Runner{
+		Series:  5,
+		Retries: 1,
+		Done:    1,
+		RunSeries: func(ctx context.Context, i int, out []string) (int, error) {
+			calls++
+			if calls == 2 {
+				out[0] += "partial\n"
+				return 1, errors.New("fail")
+			}
+			for j := range out {
+				out[j] += fmt.Sprintf("s%d\n", i)
+			}
+			return 1, nil
+		},
+		Check: func(i int, out []string) (Verdict, error) {
+			switch {
+			case i == 3:
+				return Discard, nil
+			case i == 4 && calls == 5:
+				return Rerun, nil
+			}
+			return Keep, nil
+		},
+		SeriesDone: func(n int, out []string) error {
+			done = append(done, n)
+			return nil
+		},
+		Warmup: func(out []string, marks [][]int) (int, error) {
+			return 1, nil
+		},
+	}
+	res, err := r.Run(context.Background(), sides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 3, 4, 4, 5}; !reflect.DeepEqual(want, done) {
+		t.Fatal(done)
+	}
+	for _, s := range res.Sides {
+		if s.Output != "s0\ns2\ns4\n" {
+			t.Fatalf("%q", s.Output)
+		}
+	}
+	if res.Series != 3 || res.Partial {
+		t.Fatalf("%+v", res)
+	}
+
+	// The series completed by all the sides are kept when a series still
+	// fails after the retries.
+	r = &Runner{
+		Series: 3,
+		RunSeries: func(ctx context.Context, i int, out []string) (int, error) {
+			if i == 1 {
+				out[0] += "s1\n"
+				return 1, errors.New("fail")
+			}
+			for j := range out {
+				out[j] += "s0\n"
+			}
+			return 1, nil
+		},
+	}
+	res, err = r.Run(context.Background(), []*Side{{Name: "old"}, {Name: "new"}})
+	if err == nil || !res.Partial || res.Series != 1 {
+		t.Fatalf("%+v %v", res, err)
+	}
+	if res.Sides[0].Output != "s0\n" || res.Sides[0].Completed != 2 || res.Sides[1].Completed != 1 {
+		t.Fatalf("%+v %+v", res.Sides[0], res.Sides[1])
+	}
+}
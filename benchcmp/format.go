@@ -0,0 +1,374 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package benchcmp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mgutz/ansi"
+)
+
+// TextOptions controls how WriteText formats the tables.
+type TextOptions struct {
+	// Color writes improvements in green, regressions in red and
+	// insignificant changes dimmed, with ANSI escape codes.
+	Color bool
+	// Stats adds the p-value, the sample sizes and the effect size as
+	// Cohen's d in their own columns when comparing two configurations,
+	// instead of the note.
+	Stats bool
+}
+
+// WriteText writes the tables as aligned text, like benchstat. o may be nil.
+func WriteText(w io.Writer, tables []*Table, o *TextOptions) error {
+	if o == nil {
+		o = &TextOptions{}
+	}
+	for i, t := range tables {
+		if i != 0 {
+			if _, err := fmt.Fprintf(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if t.Procs != 0 {
+			if _, err := fmt.Fprintf(w, "GOMAXPROCS=%d\n", t.Procs); err != nil {
+				return err
+			}
+		}
+		hdr := []string{"name"}
+		for _, c := range t.Configs {
+			if t.Delta {
+				// Shorter.
+				c = "old"
+				if len(hdr) == 2 {
+					c = "new"
+				}
+			}
+			hdr = append(hdr, c+" "+t.Unit)
+		}
+		stats := o.Stats && t.Delta
+		if t.Delta {
+			hdr = append(hdr, "delta")
+		}
+		if stats {
+			hdr = append(hdr, "p", "n", "d")
+		}
+		hdr = append(hdr, "")
+		lines := [][]string{hdr}
+		var colors []string
+		if o.Color {
+			colors = []string{""}
+		}
+		markers, notes := Footnotes(t)
+		oneSided, noisy := false, false
+		group := ""
+		for j, r := range t.Rows {
+			if r.Noisy {
+				noisy = true
+				continue
+			}
+			if t.OneSided(r) != -1 {
+				oneSided = true
+				continue
+			}
+			if r.Group != group {
+				group = r.Group
+				if group != "" {
+					// The heading of the group.
+					lines = append(lines, append([]string{group}, make([]string, len(hdr)-1)...))
+					if o.Color {
+						colors = append(colors, "")
+					}
+				}
+			}
+			if o.Color {
+				colors = append(colors, rowColor(t, r))
+			}
+			l := []string{r.Benchmark}
+			if group != "" {
+				l[0] = "  " + r.Benchmark[len(group)+1:]
+			}
+			for _, c := range r.Cells {
+				if c == nil {
+					l = append(l, "")
+				} else {
+					l = append(l, c.Format(t.Unit))
+				}
+			}
+			note := r.Note
+			if note != "" {
+				note = "(" + note + ")"
+			}
+			if t.Delta {
+				l = append(l, r.Delta)
+			}
+			if stats {
+				l = append(l, statsColumns(r)...)
+				note = ""
+			}
+			if markers[j] != "" {
+				note += " " + markers[j]
+			}
+			lines = append(lines, append(l, strings.TrimSpace(note)))
+		}
+		sep := false
+		if len(lines) > 1 || (!oneSided && !noisy) {
+			if err := writeColumns(w, lines, colors); err != nil {
+				return err
+			}
+			sep = true
+		}
+		if oneSided {
+			var err error
+			if sep, err = writeOneSided(w, t, markers, sep); err != nil {
+				return err
+			}
+		}
+		if noisy {
+			if err := writeNoisy(w, t, hdr[1:1+len(t.Configs)], markers, sep); err != nil {
+				return err
+			}
+		}
+		for _, n := range notes {
+			if _, err := fmt.Fprintf(w, "%s\n", n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeOneSided lists the rows of the table that only have values in one of
+// the two configurations apart, by configuration, since they have no delta.
+// sep adds an empty line first to separate them from the other rows. It
+// returns whether a following section needs to be separated.
+func writeOneSided(w io.Writer, t *Table, markers []string, sep bool) (bool, error) {
+	for i, c := range []string{"old", "new"} {
+		lines := [][]string{{"only in " + c, t.Unit, ""}}
+		for j, r := range t.Rows {
+			if !r.Noisy && t.OneSided(r) == i {
+				lines = append(lines, []string{r.Benchmark, r.Cells[i].Format(t.Unit), markers[j]})
+			}
+		}
+		if len(lines) == 1 {
+			continue
+		}
+		if sep {
+			if _, err := fmt.Fprintf(w, "\n"); err != nil {
+				return sep, err
+			}
+		}
+		sep = true
+		if err := writeColumns(w, lines, nil); err != nil {
+			return sep, err
+		}
+	}
+	return sep, nil
+}
+
+// writeNoisy lists the rows of the table too noisy to compare apart, with the
+// coefficient of variation in each configuration. configs are the column
+// headers of the configurations.
+func writeNoisy(w io.Writer, t *Table, configs, markers []string, sep bool) error {
+	hdr := []string{"too noisy to compare"}
+	for _, c := range configs {
+		hdr = append(hdr, strings.TrimSuffix(c, " "+t.Unit)+" CV")
+	}
+	lines := [][]string{append(hdr, "")}
+	for j, r := range t.Rows {
+		if r.Noisy {
+			lines = append(lines, append(append([]string{r.Benchmark}, cvColumns(r)...), markers[j]))
+		}
+	}
+	if sep {
+		if _, err := fmt.Fprintf(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return writeColumns(w, lines, nil)
+}
+
+// cvColumns returns the coefficient of variation of each configuration of a
+// row, e.g. "12.3%". Each is empty when not available.
+func cvColumns(r *Row) []string {
+	out := make([]string, len(r.Cells))
+	for i, c := range r.Cells {
+		if c != nil {
+			if cv := c.CV(); !math.IsNaN(cv) {
+				out[i] = fmt.Sprintf("%.1f%%", cv)
+			}
+		}
+	}
+	return out
+}
+
+// statsColumns returns the p-value, the sample sizes and Cohen's d of a row
+// comparing two configurations. Each is empty when not available.
+func statsColumns(r *Row) []string {
+	p, n, d := "", "", ""
+	if r.P >= 0 {
+		p = fmt.Sprintf("%.3f", r.P)
+	}
+	if a, b := r.Cells[0], r.Cells[1]; a != nil && b != nil && a.Sample != nil && b.Sample != nil {
+		n = fmt.Sprintf("%d+%d", len(a.Sample.Values), len(b.Sample.Values))
+	}
+	if !math.IsNaN(r.EffectSize) {
+		d = fmt.Sprintf("%+.2f", r.EffectSize)
+	}
+	return []string{p, n, d}
+}
+
+// rowColor returns the ANSI color of a row: green for an improvement, red for
+// a regression and dim when the change is not significant.
+func rowColor(t *Table, r *Row) string {
+	switch {
+	case r.Change > 0:
+		return ansi.LightGreen
+	case r.Change < 0:
+		return ansi.LightRed
+	case t.Delta && r.Delta == "~":
+		return ansi.ColorCode("default+d")
+	default:
+		return ""
+	}
+}
+
+// writeColumns writes the lines as aligned columns. The first column is
+// aligned left, the last one is not padded and the others are aligned right.
+//
+// colors, when set, is the ANSI color of each line.
+func writeColumns(w io.Writer, lines [][]string, colors []string) error {
+	widths := make([]int, len(lines[0]))
+	for _, l := range lines {
+		for i, s := range l {
+			if n := utf8.RuneCountInString(s); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	for j, l := range lines {
+		var b strings.Builder
+		for i, s := range l {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(s))
+			switch {
+			case i == 0:
+				b.WriteString(s + pad)
+			case i == len(l)-1:
+				b.WriteString("  " + s)
+			default:
+				b.WriteString("  " + pad + s)
+			}
+		}
+		out := strings.TrimRight(b.String(), " ")
+		if j < len(colors) && colors[j] != "" {
+			out = colors[j] + out + ansi.Reset
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the tables as indented JSON, as a list of JSONTable.
+func WriteJSON(w io.Writer, tables []*Table) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(ToJSON(tables))
+}
+
+// ToJSON converts the tables as written by WriteJSON, to embed them in
+// another document.
+func ToJSON(tables []*Table) []*JSONTable {
+	out := make([]*JSONTable, 0, len(tables))
+	for _, t := range tables {
+		outt := &JSONTable{
+			Procs:   t.Procs,
+			Unit:    t.Unit,
+			Better:  t.Better,
+			Configs: t.Configs,
+			Rows:    make([]*JSONRow, 0, len(t.Rows)),
+		}
+		for _, r := range t.Rows {
+			jr := &JSONRow{
+				Benchmark: r.Benchmark,
+				Cells:     make([]*JSONCell, 0, len(r.Cells)),
+				PctDelta:  r.PctDelta,
+				Delta:     r.Delta,
+				Note:      r.Note,
+				Change:    r.Change,
+				Warnings:  r.Warnings,
+				Noisy:     r.Noisy,
+				Group:     r.Group,
+			}
+			if r.P >= 0 {
+				p := r.P
+				jr.PValue = &p
+			}
+			if !math.IsNaN(r.EffectSize) {
+				d := r.EffectSize
+				jr.EffectSize = &d
+			}
+			for _, c := range r.Cells {
+				if c == nil {
+					jr.Cells = append(jr.Cells, nil)
+					continue
+				}
+				jc := &JSONCell{Center: c.Center}
+				if c.Sample != nil {
+					jc.Values = c.Sample.Values
+					jc.N = len(c.Sample.Values)
+					if !math.IsInf(c.Lo, 0) && !math.IsInf(c.Hi, 0) {
+						lo, hi := c.Lo, c.Hi
+						jc.Lo = &lo
+						jc.Hi = &hi
+					}
+				}
+				jr.Cells = append(jr.Cells, jc)
+			}
+			outt.Rows = append(outt.Rows, jr)
+		}
+		out = append(out, outt)
+	}
+	return out
+}
+
+// JSONTable is a Table as written by WriteJSON.
+type JSONTable struct {
+	Procs   int `json:"GOMAXPROCS,omitempty"` // set when the benchmarks ran with multiple GOMAXPROCS
+	Unit    string
+	Better  int // 1 when higher is better, -1 when lower is better, 0 when unknown
+	Configs []string
+	Rows    []*JSONRow
+}
+
+// JSONRow is a Row as written by WriteJSON.
+type JSONRow struct {
+	Benchmark  string
+	Cells      []*JSONCell // nil when the benchmark did not run in the configuration
+	PctDelta   float64
+	Delta      string
+	Note       string
+	Change     int
+	PValue     *float64 `json:",omitempty"` // p-value of the delta test, when it was computed
+	EffectSize *float64 `json:",omitempty"` // Cohen's d, when it was computed
+	Warnings   []string `json:",omitempty"`
+	Noisy      bool     `json:",omitempty"` // too noisy to compare per Options.MaxCV; Delta is empty
+	Group      string   `json:",omitempty"` // parent benchmark with Options.Group
+}
+
+// JSONCell is a Cell as written by WriteJSON.
+type JSONCell struct {
+	Values []float64 `json:",omitempty"` // measured values, sorted
+	N      int       // number of values
+	Center float64   // median, or mean with -delta-test ttest
+	Lo     *float64  `json:",omitempty"` // 95% confidence interval, when there are enough values
+	Hi     *float64  `json:",omitempty"`
+}
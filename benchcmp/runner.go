@@ -0,0 +1,309 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package benchcmp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Side is a configuration to benchmark, e.g. a checkout of a commit.
+type Side struct {
+	// Name identifies the side in the tables, e.g. "HEAD~1".
+	Name string
+	// Dir is the directory to run go test from, e.g. a git worktree. Defaults
+	// to the current directory.
+	Dir string
+	// Go is the go command to use. Defaults to "go".
+	Go string
+	// Env is added to the environment of go test.
+	Env []string
+	// BuildFlags are passed to go test, e.g. -tags or -gcflags.
+	BuildFlags []string
+	// Output is the benchmark output of the Runner.Done series already run,
+	// when resuming an interrupted run.
+	Output string
+}
+
+// Verdict is what Runner.Check decides to do with a series.
+type Verdict int
+
+const (
+	// Keep keeps the series.
+	Keep Verdict = iota
+	// Discard drops the series, e.g. because it was disturbed.
+	Discard
+	// Rerun drops the series and runs it again.
+	Rerun
+)
+
+// Runner runs the benchmarks of each side series after series, alternating
+// between the sides to spread the noise evenly, and returns the results to
+// compare.
+//
+// The zero value matches ba's defaults. The optional hooks customize the run
+// loop; ba uses them to run its test binaries on the checkouts of the commits,
+// stop once the results are stable and discard the series disturbed by
+// thermal throttling.
+type Runner struct {
+	// Pkg is the package pattern to benchmark. Defaults to "./...".
+	Pkg string
+	// Bench is the regexp of the benchmarks to run. Defaults to ".".
+	Bench string
+	// Benchtime is the duration of each benchmark. Defaults to 100ms.
+	Benchtime time.Duration
+	// Count is the number of runs of each benchmark per series. Defaults to 2.
+	Count int
+	// Series is the number of times each side is benchmarked. Defaults to 3.
+	Series int
+	// CPU is the comma separated list of GOMAXPROCS values. Defaults to "1".
+	CPU string
+	// Benchmem adds the memory allocation statistics.
+	Benchmem bool
+	// Retries is the number of times a failed series is run again before
+	// giving up.
+	Retries int
+	// Done is the number of series already in the Output of the sides, when
+	// resuming an interrupted run.
+	Done int
+	// Logf, when set, receives the progress messages, e.g. the commands run
+	// and the retries.
+	Logf func(format string, a ...interface{})
+
+	// RunSeries runs one series on all the sides, appending their output to
+	// out in the order of the sides. i is the index of the series. It returns
+	// the number of series run, more than one when they ran concurrently.
+	// Defaults to running go test on each side in turn.
+	RunSeries func(ctx context.Context, i int, out []string) (int, error)
+	// Interleaved tells that RunSeries alternates between the sides within a
+	// series or runs them concurrently, so the output of a side is incomplete
+	// when a series fails.
+	Interleaved bool
+	// Next decides whether to run the series i, given the output so far.
+	// Defaults to running Series series.
+	Next func(i int, out []string) (bool, error)
+	// Check decides what to do with the series i that was just run.
+	// Defaults to keeping it.
+	Check func(i int, out []string) (Verdict, error)
+	// SeriesDone is called after each series with the number of series run
+	// and the output kept so far, e.g. to report progress or save a
+	// checkpoint to resume from.
+	SeriesDone func(n int, out []string) error
+	// Warmup returns the number of leading series to discard from out, e.g.
+	// the ones slowed down by cold caches. marks are the offsets in out where
+	// each series run and kept starts.
+	Warmup func(out []string, marks [][]int) (int, error)
+}
+
+// Result is the outcome of Runner.Run.
+type Result struct {
+	// Sides are the raw benchmark outputs, in the order of the sides.
+	Sides []*SideResult
+	// Series is the number of series in the outputs, excluding the discarded
+	// ones.
+	Series int
+	// Partial is true when a series failed even after the retries. Only the
+	// series completed by all the sides are kept.
+	Partial bool
+}
+
+// SideResult is the raw benchmark output of a side for all the series.
+type SideResult struct {
+	Name   string
+	Output string
+	// Completed is the number of series the side completed when Partial,
+	// including the one that failed if the side ran it entirely.
+	Completed int
+}
+
+// Compare compares the sides of the result. See Compare.
+func (r *Result) Compare(o *Options) ([]*Table, error) {
+	names := make([]string, len(r.Sides))
+	outputs := make([]string, len(r.Sides))
+	for i, s := range r.Sides {
+		names[i] = s.Name
+		outputs[i] = s.Output
+	}
+	return Compare(names, outputs, o)
+}
+
+// Run benchmarks the sides and returns their output.
+//
+// The returned result is never nil. When a series fails, it contains the
+// series completed by all the sides. Canceling ctx stops after the current
+// series without error.
+func (r *Runner) Run(ctx context.Context, sides []*Side) (*Result, error) {
+	res := &Result{Sides: make([]*SideResult, len(sides)), Series: r.Done}
+	out := make([]string, len(sides))
+	for i, s := range sides {
+		res.Sides[i] = &SideResult{Name: s.Name}
+		out[i] = s.Output
+	}
+	defer func() {
+		for i := range out {
+			res.Sides[i].Output = out[i]
+		}
+	}()
+	if len(sides) == 0 {
+		return res, errors.New("benchcmp: no side")
+	}
+	run := r.RunSeries
+	if run == nil {
+		run = func(ctx context.Context, i int, out []string) (int, error) {
+			for j, s := range sides {
+				o, err := r.runSide(ctx, s)
+				out[j] += o
+				if err != nil {
+					return 1, err
+				}
+			}
+			return 1, nil
+		}
+	}
+	next := r.Next
+	if next == nil {
+		series := r.Series
+		if series <= 0 {
+			series = 3
+		}
+		next = func(i int, out []string) (bool, error) {
+			return i < series, nil
+		}
+	}
+	// retries is the number of times the current series was retried.
+	retries := 0
+	var marks [][]int
+	for i := r.Done; ctx.Err() == nil; i++ {
+		ok, err := next(i, out)
+		if err != nil {
+			return res, err
+		}
+		if !ok {
+			break
+		}
+		lens := make([]int, len(out))
+		for j := range out {
+			lens[j] = len(out[j])
+		}
+		ran, err := run(ctx, i, out)
+		if err != nil {
+			for j := range out {
+				res.Sides[j].Completed = res.Series
+				// A series is only attributed to a side when it ran sequentially.
+				if len(out[j]) > lens[j] && !r.Interleaved {
+					res.Sides[j].Completed++
+				}
+				// Discard the partial series so the sides stay balanced.
+				out[j] = out[j][:lens[j]]
+			}
+			if retries >= r.Retries || ctx.Err() != nil {
+				res.Partial = true
+				return res, err
+			}
+			retries++
+			r.logf("series %d failed; retrying (%d/%d): %s", i+1, retries, r.Retries, err)
+			i--
+			continue
+		}
+		retries = 0
+		i += ran - 1
+		v := Keep
+		if r.Check != nil {
+			if v, err = r.Check(i, out); err != nil {
+				return res, err
+			}
+		}
+		if v == Keep {
+			marks = append(marks, lens)
+			res.Series += ran
+		} else {
+			for j := range out {
+				out[j] = out[j][:lens[j]]
+			}
+			if v == Rerun {
+				i--
+			}
+		}
+		if r.SeriesDone != nil {
+			if err = r.SeriesDone(i+1, out); err != nil {
+				return res, err
+			}
+		}
+	}
+	if r.Warmup != nil {
+		n, err := r.Warmup(out, marks)
+		if err != nil {
+			return res, err
+		}
+		if n != 0 {
+			for j := range out {
+				out[j] = out[j][:marks[0][j]] + out[j][marks[n][j]:]
+			}
+			res.Series -= n
+		}
+	}
+	return res, nil
+}
+
+func (r *Runner) logf(format string, a ...interface{}) {
+	if r.Logf != nil {
+		r.Logf(format, a...)
+	}
+}
+
+// runSide runs one series of the benchmarks of a side.
+func (r *Runner) runSide(ctx context.Context, s *Side) (string, error) {
+	goCmd := s.Go
+	if goCmd == "" {
+		goCmd = "go"
+	}
+	args := append(append([]string{"test"}, s.BuildFlags...), r.args()...)
+	r.logf("%s: %s %s", s.Name, goCmd, strings.Join(args, " "))
+	/* #nosec G204 */
+	c := exec.CommandContext(ctx, goCmd, args...)
+	c.Dir = s.Dir
+	if len(s.Env) != 0 {
+		c.Env = append(os.Environ(), s.Env...)
+	}
+	var buf bytes.Buffer
+	c.Stdout = &buf
+	c.Stderr = &buf
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("%s: go test: %w\n%s", s.Name, err, buf.String())
+	}
+	return buf.String(), nil
+}
+
+// args returns the go test arguments, after the build flags.
+func (r *Runner) args() []string {
+	pkg, bench, benchtime, count, cpu := r.Pkg, r.Bench, r.Benchtime, r.Count, r.CPU
+	if pkg == "" {
+		pkg = "./..."
+	}
+	if bench == "" {
+		bench = "."
+	}
+	if benchtime <= 0 {
+		benchtime = 100 * time.Millisecond
+	}
+	if count <= 0 {
+		count = 2
+	}
+	if cpu == "" {
+		cpu = "1"
+	}
+	args := []string{"-run", "^$", "-bench", bench, "-benchtime", benchtime.String(), "-count", strconv.Itoa(count), "-cpu", cpu}
+	if r.Benchmem {
+		args = append(args, "-benchmem")
+	}
+	return append(args, pkg)
+}
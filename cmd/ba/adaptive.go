@@ -0,0 +1,146 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+// benchPlan is how to run one benchmark with -adaptive.
+type benchPlan struct {
+	// name is the full benchmark name without the "Benchmark" prefix.
+	name string
+	// bench is the -test.bench value matching only this benchmark.
+	bench     string
+	benchtime string
+	// count is the maximum count to run the benchmark with.
+	count int
+}
+
+// probeBenchmarks runs every benchmark once with -benchtime 1x on every side
+// to measure how long an iteration takes, then plans how to run each of them.
+//
+// The slowest iteration across the sides is used so both sides of a
+// benchmark run the same number of iterations.
+func probeBenchmarks(ctx context.Context, o *benchOptions, branch string, sides []*side) error {
+	stderrLog.infof("probing benchmarks")
+	ops := map[string]float64{}
+	names := map[string][]string{}
+	for _, s := range sides {
+		if s.cmd != "" {
+			continue
+		}
+		bins, err := buildTestBinaries(ctx, o, s)
+		if err != nil {
+			return err
+		}
+		for _, b := range bins {
+			out := ""
+			err = inSide(o, branch, s, func() error {
+				var err2 error
+				out, err2 = runTestBinary(ctx, o, s, b, o.bench, "1x", 1)
+				return err2
+			})
+			if err != nil {
+				return err
+			}
+			if names[b.path], err = probeOps(out, ops); err != nil {
+				return err
+			}
+		}
+	}
+	plans := map[string]*benchPlan{}
+	for _, n := range sortedKeys(ops) {
+		p := planBench(o, n, time.Duration(ops[n]*1e9))
+		stderrLog.infof("Benchmark%s: %s/op; -benchtime %s -count %d", n, time.Duration(ops[n]*1e9), p.benchtime, p.count)
+		plans[n] = p
+	}
+	o.plans = map[string][]*benchPlan{}
+	for path, l := range names {
+		for _, n := range l {
+			o.plans[path] = append(o.plans[path], plans[n])
+		}
+	}
+	return nil
+}
+
+// probeOps parses the output of a probe. It returns the benchmarks names in
+// order and updates ops with the slowest seconds per iteration seen.
+func probeOps(out string, ops map[string]float64) ([]string, error) {
+	var names []string
+	r := benchfmt.NewReader(strings.NewReader(out), "")
+	for r.Scan() {
+		res, ok := r.Result().(*benchfmt.Result)
+		if !ok {
+			continue
+		}
+		v, ok := res.Value("sec/op")
+		if !ok {
+			continue
+		}
+		n := string(res.Name.Full())
+		if old, ok := ops[n]; !ok || v > old {
+			ops[n] = v
+		}
+		names = append(names, n)
+	}
+	return names, r.Err()
+}
+
+// planBench returns how to run the benchmark name whose iteration takes op.
+//
+// Short benchmarks keep -benchtime and -count so the testing package scales
+// their iterations up; a single cold iteration is not precise enough to do
+// better. Benchmarks where an iteration is longer than -benchtime run a single
+// iteration per run, and fewer runs so they take about -benchtime x -count.
+func planBench(o *benchOptions, name string, op time.Duration) *benchPlan {
+	p := &benchPlan{name: name, count: o.count}
+	var parts []string
+	for _, s := range strings.Split("Benchmark"+name, "/") {
+		parts = append(parts, "^"+regexp.QuoteMeta(s)+"$")
+	}
+	p.bench = strings.Join(parts, "/")
+	if op <= 0 {
+		op = 1
+	}
+	if op >= o.benchtime {
+		p.benchtime = "1x"
+		if c := int(o.benchtime * time.Duration(o.count) / op); c < p.count {
+			p.count = c
+		}
+		if p.count < 1 {
+			p.count = 1
+		}
+		return p
+	}
+	p.benchtime = o.benchtime.String()
+	return p
+}
+
+// benchSelected returns true if the benchmark name matches the top level of
+// the -bench regexp bench. It is used to honor -bench being narrowed after
+// the probe, e.g. by -timebudget.
+func benchSelected(bench, name string) bool {
+	re, err := regexp.Compile(strings.SplitN(bench, "/", 2)[0])
+	if err != nil {
+		return true
+	}
+	return re.MatchString("Benchmark" + strings.SplitN(name, "/", 2)[0])
+}
+
+func sortedKeys(m map[string]float64) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
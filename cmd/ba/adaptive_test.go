@@ -0,0 +1,43 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlanBench(t *testing.T) {
+	o := &benchOptions{benchtime: 100 * time.Millisecond, count: 4}
+	data := []struct {
+		name      string
+		op        time.Duration
+		bench     string
+		benchtime string
+		count     int
+	}{
+		{"Fast", time.Microsecond, "^BenchmarkFast$", "100ms", 4},
+		{"Slow/size=1.5", 150 * time.Millisecond, "^BenchmarkSlow$/^size=1\\.5$", "1x", 2},
+		{"Slower", 3 * time.Second, "^BenchmarkSlower$", "1x", 1},
+	}
+	for i, l := range data {
+		p := planBench(o, l.name, l.op)
+		if p.name != l.name || p.bench != l.bench || p.benchtime != l.benchtime || p.count != l.count {
+			t.Fatalf("#%d: %#v", i, p)
+		}
+	}
+}
+
+func TestBenchSelected(t *testing.T) {
+	if !benchSelected(".", "Foo/bar") {
+		t.Fatal("expected selected")
+	}
+	if !benchSelected("^Benchmark(Foo|Bar)$", "Foo/bar") {
+		t.Fatal("expected selected")
+	}
+	if benchSelected("^Benchmark(Foo|Bar)$", "Baz") {
+		t.Fatal("expected not selected")
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/maruel/pat/benchcmp"
+	"golang.org/x/perf/benchmath"
+	"golang.org/x/perf/benchunit"
+)
+
+// changePoint is a step change in the trend of a benchmark metric.
+type changePoint struct {
+	// i is the index of the first point at the new level.
+	i int
+	// p is the p-value of the Mann-Whitney U-test between the points before
+	// and after the change, in the segment it was found in.
+	p float64
+	// before and after are the medians of the points since the previous
+	// change point and until the next one.
+	before float64
+	after  float64
+}
+
+// pooled returns the values of the points.
+func pooled(points []*trendPoint) *benchmath.Sample {
+	var v []float64
+	for _, p := range points {
+		v = append(v, p.values...)
+	}
+	return benchmath.NewSample(v, &benchmath.DefaultThresholds)
+}
+
+// changePoints returns the step changes of the trend, in commit order.
+//
+// They are found by binary segmentation. The split of a segment where the
+// points before and after differ the most significantly is a change point when
+// its p-value is below alpha and the medians differ by at least minDelta
+// percent. Both sides are then searched again.
+func changePoints(t *trend, alpha, minDelta float64) []*changePoint {
+	var out []*changePoint
+	var search func(lo, hi int)
+	search = func(lo, hi int) {
+		var best *changePoint
+		for i := lo + 1; i < hi; i++ {
+			a, b := pooled(t.Points[lo:i]), pooled(t.Points[i:hi])
+			c := benchmath.AssumeNothing.Compare(a, b)
+			if best == nil || c.P < best.p {
+				best = &changePoint{
+					i:      i,
+					p:      c.P,
+					before: benchmath.AssumeNothing.Summary(a, 0.95).Center,
+					after:  benchmath.AssumeNothing.Summary(b, 0.95).Center,
+				}
+			}
+		}
+		if best == nil || best.p >= alpha || best.before == 0 || 100*math.Abs(best.after/best.before-1) < minDelta {
+			return
+		}
+		out = append(out, best)
+		search(lo, best.i)
+		search(best.i, hi)
+	}
+	search(0, len(t.Points))
+	sort.Slice(out, func(i, j int) bool { return out[i].i < out[j].i })
+	// Report the levels between the change points instead of the ones of the
+	// segments they were found in.
+	center := func(lo, hi int) float64 {
+		return benchmath.AssumeNothing.Summary(pooled(t.Points[lo:hi]), 0.95).Center
+	}
+	for j, cp := range out {
+		lo, hi := 0, len(t.Points)
+		if j > 0 {
+			lo = out[j-1].i
+		}
+		if j < len(out)-1 {
+			hi = out[j+1].i
+		}
+		cp.before = center(lo, cp.i)
+		cp.after = center(cp.i, hi)
+	}
+	return out
+}
+
+// printChangePoints prints the step changes of each trend and the commits
+// most likely responsible. It returns the number of change points found.
+func printChangePoints(w io.Writer, tr []*trend, commits []commitInfo, alpha, minDelta float64) int {
+	n := 0
+	for _, t := range tr {
+		cps := changePoints(t, alpha, minDelta)
+		if len(cps) == 0 {
+			continue
+		}
+		n += len(cps)
+		fmt.Fprintf(w, "%s %s:\n", strings.TrimSpace(t.Pkg+" "+t.Name), t.Unit)
+		cls := benchunit.ClassOf(t.Unit)
+		better := benchcmp.DefaultOptions.BetterOf(t.Unit)
+		for _, cp := range cps {
+			p := t.Points[cp.i]
+			kind := ""
+			switch {
+			case better == 0:
+			case (cp.after > cp.before) == (better > 0):
+				kind = " improvement"
+			default:
+				kind = " regression"
+			}
+			fmt.Fprintf(w, "  %s %8s -> %-8s %s  p=%.3f%s  %s\n", p.Short, benchunit.Scale(cp.before, cls), benchunit.Scale(cp.after, cls), benchcmp.PctDelta(cp.before, cp.after), cp.p, kind, p.Subject)
+			// The change may be from any commit since the previous point.
+			if prev := t.Points[cp.i-1]; p.commit-prev.commit > 1 {
+				fmt.Fprintf(w, "    or one of the %d commits not benchmarked since %s; use -bisect to find which\n", p.commit-prev.commit-1, commits[prev.commit].short)
+			}
+		}
+	}
+	return n
+}
+
+// analyzeMain implements "ba analyze", which finds the step changes in the
+// history database.
+func analyzeMain(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	db := fs.String("db", defaultHistoryPath(), "path of the history database written by -record or ba daemon")
+	rev := fs.String("rev", "HEAD", "revision whose first parent history is analyzed")
+	last := fs.Int("last", 100, "number of commits to analyze")
+	bench := fs.String("bench", "", "only analyze this benchmark")
+	alpha := fs.Float64("alpha", 0.01, "p-value cutoff to consider a step change significant")
+	var labels labelFlag
+	fs.Var(&labels, "label", "only analyze the results recorded with this key=value -label; can be specified multiple times")
+	minDelta := percent(5)
+	fs.Var(&minDelta, "min-delta", "minimum change of the median to report a step change")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: ba analyze <flags>\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "ba analyze finds the step changes in the trend of each benchmark recorded\n")
+		fmt.Fprintf(os.Stderr, "in the history database and the commits most likely responsible.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		return errors.New("unexpected argument")
+	}
+	if *last < 2 {
+		return errors.New("-last must be at least 2")
+	}
+	entries, err := readHistory(*db, *bench, machineFingerprint(), labels.labels())
+	if err != nil {
+		return err
+	}
+	commits, err := logCommits(*rev, *last)
+	if err != nil {
+		return err
+	}
+	tr := trends(entries, commits, *alpha)
+	if len(tr) == 0 {
+		return fmt.Errorf("no history in the last %d commits of %s on this machine", *last, *rev)
+	}
+	if printChangePoints(os.Stdout, tr, commits, *alpha, float64(minDelta)) == 0 {
+		names := make([]string, 0, len(tr))
+		for _, t := range tr {
+			if !contains(names, t.Name) {
+				names = append(names, t.Name)
+			}
+		}
+		stderrLog.infof("no step change found in %d benchmarks: %s", len(names), strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestChangePoints(t *testing.T) {
+	base := []float64{10, 10.1, 10.2, 9.9, 10, 10.1}
+	slow := []float64{20, 20.1, 20.2, 19.9, 20, 20.1}
+	fast := []float64{15, 15.1, 15.2, 14.9, 15, 15.1}
+	var commits []commitInfo
+	var entries []*historyEntry
+	for i, v := range [][]float64{base, base, base, nil, nil, slow, slow, slow, fast, fast, fast} {
+		sha1 := strings.Repeat(string(rune('a'+i)), 4)
+		commits = append(commits, commitInfo{sha1: sha1, short: sha1[:1], subject: "commit " + sha1[:1]})
+		if v != nil {
+			entries = append(entries, &historyEntry{SHA1: sha1, Name: "Foo", Unit: "sec/op", Values: v})
+		}
+	}
+	tr := trends(entries, commits, 0.05)
+	cps := changePoints(tr[0], 0.01, 5)
+	if len(cps) != 2 || cps[0].i != 3 || cps[1].i != 6 {
+		t.Fatalf("%+v", cps)
+	}
+	if got := changePoints(tr[0], 0.01, 200); len(got) != 0 {
+		t.Fatalf("%+v", got)
+	}
+	b := bytes.Buffer{}
+	if n := printChangePoints(&b, tr, commits, 0.01, 5); n != 2 {
+		t.Fatal(n)
+	}
+	want := "Foo sec/op:\n" +
+		"  f    10.05 -> 20.05    +99.50%  p=0.000 regression  commit f\n" +
+		"    or one of the 2 commits not benchmarked since c; use -bisect to find which\n" +
+		"  i    20.05 -> 15.05    -24.94%  p=0.000 improvement  commit i\n"
+	if got := b.String(); got != want {
+		t.Fatalf("%q", got)
+	}
+}
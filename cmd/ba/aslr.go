@@ -0,0 +1,32 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import "strings"
+
+// addrNoRandomize is ADDR_NO_RANDOMIZE from linux/personality.h.
+const addrNoRandomize = 0x0040000
+
+// envPadBlock is the size the arguments and environment are padded to a
+// multiple of.
+const envPadBlock = 4096
+
+// padEnv returns env with a BA_PAD variable so the strings copied on the
+// initial stack of the process, i.e. the path, arguments and environment, add
+// up to a multiple of envPadBlock. Without ASLR, the stack of both sides then
+// starts at the same address even when their paths or environments differ
+// slightly.
+func padEnv(path string, args, env []string) []string {
+	const key = "BA_PAD="
+	n := len(path) + 1 + len(key) + 1
+	for _, a := range args {
+		n += len(a) + 1
+	}
+	for _, e := range env {
+		n += len(e) + 1
+	}
+	pad := (envPadBlock - n%envPadBlock) % envPadBlock
+	return append(env[:len(env):len(env)], key+strings.Repeat("x", pad))
+}
@@ -0,0 +1,29 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// disableASLRThread disables address space layout randomization for the
+// processes executed by the child processes started from the calling OS
+// thread, like setarch -R. The caller must lock the goroutine to its thread.
+//
+// The returned function must be called to restore the previous personality.
+func disableASLRThread() (func(), error) {
+	// 0xffffffff queries the current personality.
+	old, _, errno := syscall.RawSyscall(syscall.SYS_PERSONALITY, 0xffffffff, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	if _, _, errno = syscall.RawSyscall(syscall.SYS_PERSONALITY, old|addrNoRandomize, 0, 0); errno != 0 {
+		return nil, fmt.Errorf("failed to disable ASLR: %w", errno)
+	}
+	return func() {
+		_, _, _ = syscall.RawSyscall(syscall.SYS_PERSONALITY, old, 0, 0)
+	}, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPadEnv(t *testing.T) {
+	for _, env := range [][]string{nil, {"A=b"}, {"HOME=/home/someone", "PATH=/usr/bin:/bin"}} {
+		got := padEnv("/tmp/x.test", []string{"/tmp/x.test", "-test.bench", "."}, env)
+		n := len("/tmp/x.test") + 1
+		for _, a := range []string{"/tmp/x.test", "-test.bench", "."} {
+			n += len(a) + 1
+		}
+		for _, e := range got {
+			n += len(e) + 1
+		}
+		if n%envPadBlock != 0 || len(got) != len(env)+1 || !strings.HasPrefix(got[len(got)-1], "BA_PAD=") {
+			t.Fatal(n, got)
+		}
+	}
+}
+
+func TestDisableASLR(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("linux only")
+	}
+	o := &benchOptions{noaslr: true}
+	c := exec.Command("cat", "/proc/self/personality")
+	var b strings.Builder
+	c.Stdout = &b
+	if err := o.start(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.ParseUint(strings.TrimSpace(b.String()), 16, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p&addrNoRandomize == 0 {
+		t.Fatalf("%x", p)
+	}
+}
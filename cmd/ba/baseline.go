@@ -0,0 +1,34 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readBaseline returns the results saved by -save-baseline in path.
+func readBaseline(path string) (string, error) {
+	/* #nosec G304 */
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(string(b), "\nBenchmark") && !strings.HasPrefix(string(b), "Benchmark") {
+		return "", fmt.Errorf("-baseline %s contains no benchmark result", path)
+	}
+	return string(b), nil
+}
+
+// saveBaseline writes the results of the only side of the session to path for
+// -baseline, in benchfmt format so benchstat can read it too.
+func saveBaseline(path string, s *session) error {
+	if len(s.Sides) != 1 || s.Sides[0].Output == "" {
+		return errors.New("-save-baseline: no result to save")
+	}
+	return os.WriteFile(path, []byte(s.Sides[0].Output), 0o644)
+}
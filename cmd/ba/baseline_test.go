@@ -0,0 +1,34 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	d := t.TempDir()
+	p := filepath.Join(d, "main.bench")
+	out := "cpu: foo\nBenchmarkFoo 1 2 ns/op\n"
+	s := &session{Sides: []*sessionSide{{Name: "HEAD", Output: out}}}
+	if err := saveBaseline(p, s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readBaseline(p)
+	if err != nil || got != out {
+		t.Fatal(got, err)
+	}
+	if err := saveBaseline(p, &session{Sides: []*sessionSide{{Name: "HEAD"}}}); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := os.WriteFile(p, []byte("cpu: foo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readBaseline(p); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// benchseriesTime is benchseries.RFC3339NanoNoZ, in UTC so the times sort
+// properly.
+const benchseriesTime = "2006-01-02T15:04:05.999999999-07:00"
+
+// printBenchseries prints the raw benchmark output of both sides in benchfmt
+// with the configuration keys expected by the default options of
+// golang.org/x/perf/benchseries:
+//
+//	runstamp: the start of the session
+//	toolchain: baseline or experiment
+//	experiment-commit: the commit of the new side
+//	experiment-commit-time: its commit time, the series key
+//	baseline-commit: the commit of the old side
+//
+// so ba runs can feed the same analysis pipeline as the Go performance
+// dashboard.
+func printBenchseries(w io.Writer, s *session) error {
+	if len(s.Sides) != 2 {
+		return errors.New("-format benchseries requires exactly two sides")
+	}
+	old, exp := s.Sides[0], s.Sides[1]
+	// Without a commit, e.g. -binary, use the time of the run.
+	t := s.Start
+	if exp.CommitTime != nil {
+		t = *exp.CommitTime
+	}
+	hdr := "runstamp: " + s.Start.UTC().Format(benchseriesTime) + "\n" +
+		"experiment-commit-time: " + t.UTC().Format(benchseriesTime) + "\n"
+	if exp.SHA1 != "" {
+		hdr += "experiment-commit: " + exp.SHA1 + "\n"
+	}
+	if old.SHA1 != "" {
+		hdr += "baseline-commit: " + old.SHA1 + "\n"
+	}
+	for i, ss := range s.Sides {
+		toolchain := "baseline"
+		if i == 1 {
+			toolchain = "experiment"
+		}
+		out := ss.Output
+		if out != "" && !strings.HasSuffix(out, "\n") {
+			out += "\n"
+		}
+		if _, err := fmt.Fprintf(w, "%stoolchain: %s\n%s", hdr, toolchain, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintBenchseries(t *testing.T) {
+	ct := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600))
+	s := &session{
+		Sides: []*sessionSide{
+			{Name: "HEAD~1", SHA1: "aaa", Output: "goos: linux\ngoarch: amd64\nBenchmarkFoo 10 100 ns/op\nBenchmarkFoo 10 102 ns/op\n"},
+			{Name: "HEAD", SHA1: "bbb", CommitTime: &ct, Output: "goos: linux\ngoarch: amd64\nBenchmarkFoo 10 90 ns/op\nBenchmarkFoo 10 91 ns/op"},
+		},
+		Start: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+	}
+	var buf bytes.Buffer
+	if err := printBenchseries(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	want := "runstamp: 2024-01-03T00:00:00+00:00\n" +
+		"experiment-commit-time: 2024-01-02T02:04:05+00:00\n" +
+		"experiment-commit: bbb\n" +
+		"baseline-commit: aaa\n" +
+		"toolchain: baseline\n"
+	got := buf.String()
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("%q", got)
+	}
+	// The output is terminated by a new line before the next side's keys.
+	if !strings.Contains(got, "102 ns/op\nrunstamp") || !strings.HasSuffix(got, "91 ns/op\n") {
+		t.Fatalf("%q", got)
+	}
+
+	s.Sides = s.Sides[:1]
+	if err := printBenchseries(&buf, s); err == nil {
+		t.Fatal("expected error")
+	}
+}
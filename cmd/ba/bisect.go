@@ -0,0 +1,78 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// bisect finds the first commit between good and HEAD where a benchmark
+// regressed by more than threshold percent compared to good.
+//
+// It returns the comparison of good against the first bad commit and the
+// commit.
+func bisect(ctx context.Context, o *benchOptions, good, cmdOld, cmdNew string, series int, nowarm bool, threshold float64, t *tableOptions) (*comparison, string, error) {
+	list, err := git("rev-list", "--first-parent", "--reverse", good+"..HEAD")
+	if err != nil {
+		return nil, "", errors.New(list)
+	}
+	commits := strings.Fields(list)
+	if len(commits) == 0 {
+		return nil, "", fmt.Errorf("no commit between %s and HEAD", good)
+	}
+	regressed := func(ref string) (*comparison, bool, error) {
+		sides := []*side{
+			{name: good, ref: good, cmd: cmdOld},
+			{name: ref[:12], ref: ref, cmd: cmdNew},
+		}
+		s, err := runSession(ctx, o, sides, series, nowarm)
+		if err != nil {
+			return nil, false, err
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		c, err := genComparisons(s, t)
+		if err != nil {
+			return nil, false, err
+		}
+		r := regressions(c, threshold, nil)
+		if len(r) != 0 {
+			stderrLog.infof("%s is bad:\n  %s", ref[:12], strings.Join(r, "\n  "))
+		} else {
+			stderrLog.infof("%s is good", ref[:12])
+		}
+		return c[0], len(r) != 0, nil
+	}
+
+	// The commits at index lo and before are good, at hi and after are bad.
+	lo, hi := -1, len(commits)-1
+	stderrLog.infof("bisecting %d commits", len(commits))
+	found, bad, err := regressed(commits[hi])
+	if err != nil {
+		return nil, "", err
+	}
+	if !bad {
+		return nil, "", fmt.Errorf("HEAD did not regress by more than %g%% against %s", threshold, good)
+	}
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		stderrLog.infof("%d commits left to test", hi-lo-1)
+		c, bad, err := regressed(commits[mid])
+		if err != nil {
+			return nil, "", err
+		}
+		if bad {
+			hi = mid
+			found = c
+		} else {
+			lo = mid
+		}
+	}
+	return found, commits[hi], nil
+}
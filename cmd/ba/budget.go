@@ -0,0 +1,101 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+// benchCosts returns the time spent measuring each top level benchmark in
+// seconds, summed over all the outputs. The names are sorted from the
+// cheapest to the most expensive.
+func benchCosts(outputs []string) ([]string, map[string]float64, error) {
+	costs := map[string]float64{}
+	for _, out := range outputs {
+		r := benchfmt.NewReader(strings.NewReader(out), "")
+		for r.Scan() {
+			res, ok := r.Result().(*benchfmt.Result)
+			if !ok {
+				continue
+			}
+			if v, ok := res.Value("sec/op"); ok {
+				costs[string(res.Name.Base())] += float64(res.Iters) * v
+			}
+		}
+		if err := r.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+	names := make([]string, 0, len(costs))
+	for n := range costs {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if costs[names[i]] != costs[names[j]] {
+			return costs[names[i]] < costs[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names, costs, nil
+}
+
+// fitBudget adjusts o.count, then o.bench if needed, so that the remaining
+// series fit in budget. The estimate is based on d, the duration of the first
+// series, and outputs, its results.
+//
+// It returns false if not even a single benchmark fits.
+func fitBudget(o *benchOptions, outputs []string, d, budget time.Duration, remaining int) (bool, error) {
+	if remaining <= 0 || d*time.Duration(remaining) <= budget {
+		return true, nil
+	}
+	// Try reducing the count first, the time is roughly proportional to it.
+	if c := int(float64(o.count) * budget.Seconds() / (d.Seconds() * float64(remaining))); c >= 1 {
+		stderrLog.infof("reducing -count from %d to %d to fit -timebudget", o.count, c)
+		o.count = c
+		return true, nil
+	}
+	// Then skip the slowest benchmarks.
+	names, costs, err := benchCosts(outputs)
+	if err != nil {
+		return false, err
+	}
+	total := 0.
+	for _, c := range costs {
+		total += c
+	}
+	if total == 0 || strings.Contains(o.bench, "/") {
+		stderrLog.infof("a series takes %s at -count 1; -timebudget will be exceeded", (d / time.Duration(o.count)).Round(time.Millisecond))
+		o.count = 1
+		return true, nil
+	}
+	// Wall time per second measured by the benchmarks, for a single series at
+	// count 1.
+	scale := d.Seconds() / total / float64(o.count)
+	used := 0.
+	var keep []string
+	for _, n := range names {
+		c := costs[n] * scale * float64(remaining)
+		if used+c > budget.Seconds() {
+			stderrLog.infof("skipping Benchmark%s to fit -timebudget", n)
+			continue
+		}
+		used += c
+		keep = append(keep, regexp.QuoteMeta(n))
+	}
+	if len(keep) == 0 {
+		return false, nil
+	}
+	if o.count != 1 {
+		stderrLog.infof("reducing -count from %d to 1 to fit -timebudget", o.count)
+		o.count = 1
+	}
+	o.bench = "^Benchmark(" + strings.Join(keep, "|") + ")$"
+	return true, nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFitBudget(t *testing.T) {
+	// Slow takes 90% of the time.
+	out := "BenchmarkFast 100 1000000 ns/op\nBenchmarkSlow/a 10 90000000 ns/op\nBenchmarkSlow/b 10 0 ns/op\n"
+	data := []struct {
+		budget time.Duration
+		ok     bool
+		count  int
+		bench  string
+	}{
+		{time.Hour, true, 4, "."},
+		{50 * time.Second, true, 2, "."},
+		{10 * time.Second, true, 1, "^Benchmark(Fast)$"},
+		{time.Second, false, 4, "."},
+	}
+	for i, l := range data {
+		o := &benchOptions{bench: ".", count: 4}
+		ok, err := fitBudget(o, []string{out, out}, 40*time.Second, l.budget, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != l.ok || o.count != l.count || o.bench != l.bench {
+			t.Fatalf("#%d: %t %d %q", i, ok, o.count, o.bench)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// timeBuild builds the packages of the side with an empty build cache and
+// returns the wall time as a benchmark result, so it is compared like the
+// others, e.g. "BenchmarkGoBuild 1 12345678 ns/op".
+//
+// It must be called with the side's ref checked out.
+func timeBuild(ctx context.Context, o *benchOptions, s *side) (string, error) {
+	d, err := os.MkdirTemp("", "ba-build-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(d)
+	// go install with GOBIN works with any number of main packages, unlike go
+	// build -o.
+	env := []string{"GOCACHE=" + filepath.Join(d, "cache"), "GOBIN=" + filepath.Join(d, "bin")}
+	start := time.Now()
+	// The modules share the build cache, like a workspace build would.
+	for _, m := range splitModules(s.dir, o.pkgPattern()) {
+		args := append(append([]string{"install"}, s.goBuildFlags(o)...), m.pattern)
+		o.logCmd("%s%s %s", s.logPrefix(m.dir, append(s.buildEnv(o), env...)), s.goCmd(), strings.Join(args, " "))
+		c := command(s.goCmd(), args...)
+		c.Dir = m.dir
+		c.Env = append(append(os.Environ(), s.buildEnv(o)...), env...)
+		if out, err := combinedOutput(ctx, c); err != nil {
+			return "", fmt.Errorf("go install %s: %w\n%s", m.pattern, err, out)
+		}
+	}
+	return fmt.Sprintf("BenchmarkGoBuild \t1\t%d ns/op\n", time.Since(start).Nanoseconds()), nil
+}
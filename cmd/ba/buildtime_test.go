@@ -0,0 +1,29 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestTimeBuild(t *testing.T) {
+	d := t.TempDir()
+	for n, c := range map[string]string{"go.mod": "module example.com/x\n", "x.go": "package x\n\nfunc X() int { return 1 }\n"} {
+		if err := os.WriteFile(filepath.Join(d, n), []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := timeBuild(context.Background(), &benchOptions{pkg: "./..."}, &side{dir: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^BenchmarkGoBuild \t1\t\d+ ns/op\n$`).MatchString(out) {
+		t.Fatal(out)
+	}
+}
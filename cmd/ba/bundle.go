@@ -0,0 +1,306 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// session describes a comparison session so it can be saved in a bundle and
+// reloaded later.
+type session struct {
+	// Sides are the configurations that were benchmarked. The first one is the
+	// baseline.
+	Sides []*sessionSide
+	// Columns shows all the sides side by side in a single table instead of
+	// comparing each one against the first one.
+	Columns bool `json:",omitempty"`
+	// Range prints a time series across the sides instead of comparisons.
+	Range bool `json:",omitempty"`
+	// Calibrate prints the noise floor between two runs of the same code
+	// instead of comparisons.
+	Calibrate bool `json:",omitempty"`
+	// Matrix is the number of sides of each -env-matrix configuration. The
+	// sides of each configuration are compared separately.
+	Matrix int `json:",omitempty"`
+	// Race is set when the test binaries were built with the race detector,
+	// which inflates the absolute numbers.
+	Race bool `json:",omitempty"`
+	// Series is the number of series completed. Once the session is done, it
+	// is the number of series in the outputs, excluding the discarded ones.
+	Series int `json:",omitempty"`
+	// Partial is set when a series failed. The outputs only contain the Series
+	// completed by all the sides.
+	Partial   bool `json:",omitempty"`
+	Args      []string
+	Commands  []string
+	GoVersion string
+	GOOS      string
+	GOARCH    string
+	NumCPU    int
+	Hostname  string
+	Start     time.Time
+	Duration  time.Duration
+	// Machine is the benchfmt configuration lines describing the machine,
+	// prepended to each side's Output. Not recorded with -remote.
+	Machine []string `json:",omitempty"`
+	// Labels are the benchfmt configuration lines specified with -label,
+	// prepended to each side's Output after Machine.
+	Labels []string `json:",omitempty"`
+	// Runtime is the GOGC, GOMEMLIMIT and GODEBUG environment variables of
+	// the benchmark processes, as set with -gogc, -gomemlimit and -godebug or
+	// inherited from ba's environment, before each side's Env.
+	Runtime []string `json:",omitempty"`
+}
+
+// config returns the configuration lines prepended to the Output of side i:
+// Machine, Labels then the runtime settings of its benchmark processes.
+func (s *session) config(i int) []string {
+	out := append(append([]string(nil), s.Machine...), s.Labels...)
+	out = append(out, runtimeConfig(append(s.Runtime[:len(s.Runtime):len(s.Runtime)], s.Sides[i].Env...))...)
+	if s.Race {
+		out = append(out, "race: on")
+	}
+	return out
+}
+
+// sessionSide is the recorded result of one side.
+type sessionSide struct {
+	Name       string
+	Ref        string   `json:",omitempty"`
+	SHA1       string   `json:",omitempty"`
+	Cmd        string   `json:",omitempty"`
+	Bin        string   `json:",omitempty"`
+	Go         string   `json:",omitempty"`
+	BuildFlags []string `json:",omitempty"`
+	Env        []string `json:",omitempty"`
+	// CommitTime is the commit time of SHA1.
+	CommitTime *time.Time `json:",omitempty"`
+	// BinarySizes is the size of each test binary in bytes, by package.
+	BinarySizes map[string]int64 `json:",omitempty"`
+	// PackageSizes is the size of the symbols of each package linked in the
+	// test binaries in bytes, summed over the binaries. Only recorded with -nm.
+	PackageSizes map[string]int64 `json:",omitempty"`
+	// Completed is the number of series this side completed when the session
+	// is Partial, including the one that failed.
+	Completed int `json:",omitempty"`
+
+	// Raw go test -bench output. It is stored as a separate file in the bundle.
+	Output string `json:"-"`
+}
+
+// newSession returns a session filled with the current environment.
+func newSession(sides []*side) *session {
+	s := &session{
+		Args:   os.Args,
+		GOOS:   runtime.GOOS,
+		GOARCH: runtime.GOARCH,
+		NumCPU: runtime.NumCPU(),
+		Start:  time.Now().Round(time.Second),
+	}
+	s.Hostname, _ = os.Hostname()
+	if out, err := exec.Command("go", "version").Output(); err == nil {
+		s.GoVersion = strings.TrimSpace(string(out))
+	}
+	for _, d := range sides {
+		ss := &sessionSide{Name: d.name, Ref: d.ref, Cmd: d.cmd, Bin: d.bin, Go: d.goTool, BuildFlags: d.buildFlags, Env: d.env}
+		if d.bin != "" {
+			// Not built from the checkout.
+			s.Sides = append(s.Sides, ss)
+			continue
+		}
+		ref := d.ref
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if sha1, err := repo.resolve(ref); err == nil {
+			ss.SHA1 = sha1
+			if t, err := repo.commitTime(sha1); err == nil {
+				ss.CommitTime = &t
+			}
+		}
+		s.Sides = append(s.Sides, ss)
+	}
+	return s
+}
+
+// sameSides returns true if the sides of both sessions are the same
+// configurations at the same commits.
+func (s *session) sameSides(o *session) bool {
+	if len(s.Sides) != len(o.Sides) {
+		return false
+	}
+	for i, ss := range s.Sides {
+		x := o.Sides[i]
+		if ss.Name != x.Name || ss.SHA1 != x.SHA1 || ss.Cmd != x.Cmd || ss.Bin != x.Bin || ss.Go != x.Go || strings.Join(ss.BuildFlags, " ") != strings.Join(x.BuildFlags, " ") || strings.Join(ss.Env, " ") != strings.Join(x.Env, " ") {
+			return false
+		}
+	}
+	return true
+}
+
+// names returns the name of each side.
+func (s *session) names() []string {
+	out := make([]string, 0, len(s.Sides))
+	for _, ss := range s.Sides {
+		out = append(out, ss.Name)
+	}
+	return out
+}
+
+// hasOutput returns true if any benchmark output was recorded.
+func (s *session) hasOutput() bool {
+	for _, ss := range s.Sides {
+		if ss.Output != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeBundle writes the session as a zip archive.
+func writeBundle(path string, s *session) error {
+	/* #nosec G304 */
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	z := zip.NewWriter(f)
+	err = writeBundleFile(z, "session.json", func(w io.Writer) error {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(s)
+	})
+	for i := 0; i < len(s.Sides) && err == nil; i++ {
+		out := s.Sides[i].Output
+		err = writeBundleFile(z, strconv.Itoa(i)+".txt", func(w io.Writer) error {
+			_, err2 := io.WriteString(w, out)
+			return err2
+		})
+	}
+	if err2 := z.Close(); err == nil {
+		err = err2
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// writeDir writes the session into a directory, as session.json and the raw
+// benchmark output of each side as <i>-<name>.txt.
+func writeDir(dir string, s *session) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	d, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(dir, "session.json"), append(d, '\n'), 0o644); err != nil {
+		return err
+	}
+	for i, ss := range s.Sides {
+		if err = os.WriteFile(filepath.Join(dir, sideFile(i, ss.Name)), []byte(ss.Output), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sideFile returns the file name of a side's raw output in a directory
+// written by writeDir.
+func sideFile(i int, name string) string {
+	return strconv.Itoa(i) + "-" + unsafeChars.ReplaceAllString(name, "_") + ".txt"
+}
+
+// readDir reads a directory written by writeDir.
+func readDir(dir string) (*session, error) {
+	/* #nosec G304 */
+	d, err := os.ReadFile(filepath.Join(dir, "session.json"))
+	if err != nil {
+		return nil, err
+	}
+	s := &session{}
+	if err = json.Unmarshal(d, s); err != nil {
+		return nil, fmt.Errorf("session.json: %w", err)
+	}
+	for i, ss := range s.Sides {
+		/* #nosec G304 */
+		if d, err = os.ReadFile(filepath.Join(dir, sideFile(i, ss.Name))); err != nil {
+			return nil, err
+		}
+		ss.Output = string(d)
+	}
+	return s, nil
+}
+
+func writeBundleFile(z *zip.Writer, name string, fn func(w io.Writer) error) error {
+	w, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	return fn(w)
+}
+
+// readBundle reads a zip archive written by writeBundle, or a directory
+// written by writeDir.
+func readBundle(path string) (*session, error) {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return readDir(path)
+	}
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	var d []byte
+	files := map[string]*zip.File{}
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+	f := files["session.json"]
+	if f == nil {
+		return nil, errors.New("invalid bundle: missing session.json")
+	}
+	if d, err = readBundleFile(f); err != nil {
+		return nil, err
+	}
+	s := &session{}
+	if err = json.Unmarshal(d, s); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	for i, ss := range s.Sides {
+		name := strconv.Itoa(i) + ".txt"
+		if f = files[name]; f == nil {
+			return nil, fmt.Errorf("invalid bundle: missing %s", name)
+		}
+		if d, err = readBundleFile(f); err != nil {
+			return nil, err
+		}
+		ss.Output = string(d)
+	}
+	return s, nil
+}
+
+func readBundleFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBundle(t *testing.T) {
+	want := testSession()
+	p := filepath.Join(t.TempDir(), "b.zip")
+	if err := writeBundle(p, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readBundle(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("%#v != %#v", want, got)
+	}
+}
+
+func TestDir(t *testing.T) {
+	want := testSession()
+	p := filepath.Join(t.TempDir(), "out")
+	if err := writeDir(p, want); err != nil {
+		t.Fatal(err)
+	}
+	// readBundle also accepts a directory.
+	got, err := readBundle(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("%#v != %#v", want, got)
+	}
+}
+
+func testSession() *session {
+	return &session{
+		Sides: []*sessionSide{
+			{Name: "HEAD~1", Ref: "HEAD~1", Output: "BenchmarkFoo 1 10 ns/op\n"},
+			{Name: "HEAD", Env: []string{"GOGC=off"}, Output: "BenchmarkFoo 1 9 ns/op\n"},
+		},
+		Args:      []string{"ba", "-against", "HEAD~1"},
+		Commands:  []string{"go test", "git checkout HEAD~1"},
+		GoVersion: "go version go1.20 linux/amd64",
+		GOOS:      "linux",
+		GOARCH:    "amd64",
+		NumCPU:    4,
+		Start:     time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration:  time.Second,
+	}
+}
+
+func TestSameSides(t *testing.T) {
+	a := testSession()
+	b := testSession()
+	if !a.sameSides(b) {
+		t.Fatal("expected same")
+	}
+	b.Sides[1].Env = []string{"GOGC=100"}
+	if a.sameSides(b) {
+		t.Fatal("expected different")
+	}
+	b.Sides = b.Sides[:1]
+	if a.sameSides(b) {
+		t.Fatal("expected different")
+	}
+}
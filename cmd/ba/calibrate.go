@@ -0,0 +1,195 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/perf/benchmath"
+)
+
+// calibratePower is the probability to detect a change of -threshold that
+// -calibrate sizes its suggestion for.
+const calibratePower = 0.8
+
+// calibrateSides returns two identical sides of the current checkout. They
+// share the same test binaries.
+func calibrateSides() []*side {
+	return []*side{{name: "HEAD#1"}, {name: "HEAD#2"}}
+}
+
+// noise is the apparent change of one benchmark between two runs of the same
+// code.
+type noise struct {
+	pkg, name string
+	// delta is the relative difference between the medians.
+	delta float64
+	// cv is the coefficient of variation of both sides combined.
+	cv float64
+	p  float64
+	// samples is the number of samples per side needed to detect the effect.
+	samples int
+}
+
+// genNoise measures the apparent change in sec/op of each benchmark between
+// both sides of a -calibrate session.
+func genNoise(s *session, effect, alpha float64) ([]noise, error) {
+	if len(s.Sides) != 2 {
+		return nil, errors.New("-calibrate requires exactly two sides")
+	}
+	a, err := parseSamples(s.Sides[0].Output)
+	if err != nil {
+		return nil, err
+	}
+	b, err := parseSamples(s.Sides[1].Output)
+	if err != nil {
+		return nil, err
+	}
+	var out []noise
+	for _, k := range a.keys {
+		if !strings.HasSuffix(k, " sec/op") || len(b.values[k]) == 0 {
+			continue
+		}
+		sa := benchmath.NewSample(a.values[k], &benchmath.DefaultThresholds)
+		sb := benchmath.NewSample(b.values[k], &benchmath.DefaultThresholds)
+		ma := median(sa)
+		if ma == 0 {
+			continue
+		}
+		f := strings.SplitN(k, " ", 3)
+		n := noise{
+			pkg:   f[0],
+			name:  f[1],
+			delta: (median(sb) - ma) / ma,
+			cv:    coefVar(append(append([]float64{}, a.values[k]...), b.values[k]...)),
+			p:     benchmath.AssumeNothing.Compare(sa, sb).P,
+		}
+		n.samples = samplesNeeded(n.cv, effect, alpha)
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// coefVar returns the standard deviation of values relative to their mean.
+func coefVar(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := 0.
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if mean == 0 {
+		return 0
+	}
+	ss := 0.
+	for _, v := range values {
+		ss += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(ss/float64(len(values)-1)) / mean
+}
+
+// samplesNeeded returns the number of samples per side for a two sided test
+// at alpha to detect a relative change of effect with calibratePower, given
+// the coefficient of variation cv.
+//
+// It uses the normal approximation, corrected by the asymptotic relative
+// efficiency of the Mann-Whitney U test, and never returns less than the
+// smallest sample size for which the U test can reach alpha at all.
+func samplesNeeded(cv, effect, alpha float64) int {
+	z := func(p float64) float64 { return math.Sqrt2 * math.Erfinv(2*p-1) }
+	k := (z(1-alpha/2) + z(calibratePower)) * cv / effect
+	n := int(math.Ceil(2 * k * k * math.Pi / 3))
+	if m := minSamples(alpha); n < m {
+		n = m
+	}
+	return n
+}
+
+// minSamples returns the smallest number of samples per side for which the
+// U test can report a p-value below alpha.
+func minSamples(alpha float64) int {
+	for n := 2; n < 100; n++ {
+		a := make([]float64, n)
+		b := make([]float64, n)
+		for i := range a {
+			a[i] = float64(i)
+			b[i] = float64(n + i)
+		}
+		sa := benchmath.NewSample(a, &benchmath.DefaultThresholds)
+		sb := benchmath.NewSample(b, &benchmath.DefaultThresholds)
+		if benchmath.AssumeNothing.Compare(sa, sb).P < alpha {
+			return n
+		}
+	}
+	return 100
+}
+
+// printCalibration prints the apparent change of each benchmark between two
+// runs of the same code, the resulting noise floor and the smallest -series
+// at -count that detects a change of effect.
+func printCalibration(w io.Writer, s *session, effect percent, alpha float64, count int) error {
+	ns, err := genNoise(s, float64(effect)/100, alpha)
+	if err != nil {
+		return err
+	}
+	if len(ns) == 0 {
+		return errors.New("-calibrate: no sec/op results")
+	}
+	var tw *tabwriter.Writer
+	pkg := ""
+	fp := 0
+	abs := make([]float64, 0, len(ns))
+	worst := ns[0]
+	needed := 0
+	for i, n := range ns {
+		if i == 0 || n.pkg != pkg {
+			if tw != nil {
+				if err := tw.Flush(); err != nil {
+					return err
+				}
+				fmt.Fprintln(w)
+			}
+			pkg = n.pkg
+			fmt.Fprintf(w, "pkg: %s\n", pkg)
+			tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "\tdelta\tcv\tp\tsamples for ±%s\n", effect.String())
+		}
+		fmt.Fprintf(tw, "%s\t%+.2f%%\t%.2f%%\tp=%.3f\t%d\n", n.name, 100*n.delta, 100*n.cv, n.p, n.samples)
+		if n.p < alpha {
+			fp++
+		}
+		abs = append(abs, math.Abs(n.delta))
+		if math.Abs(n.delta) > math.Abs(worst.delta) {
+			worst = n
+		}
+		if n.samples > needed {
+			needed = n.samples
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	sort.Float64s(abs)
+	med := abs[len(abs)/2]
+	if len(abs)%2 == 0 {
+		med = (med + abs[len(abs)/2-1]) / 2
+	}
+	fmt.Fprintf(w, "\nnoise floor: median |delta| %.2f%%, max %.2f%% (%s)\n", 100*med, 100*abs[len(abs)-1], worst.name)
+	fmt.Fprintf(w, "%d of %d benchmarks falsely reported as changed at -alpha %g\n", fp, len(ns), alpha)
+	if count < 1 {
+		count = 1
+	}
+	series := (needed + count - 1) / count
+	_, err = fmt.Fprintf(w, "to detect a %s change with %.0f%% power: -count %d -series %d\n", effect.String(), 100*calibratePower, count, series)
+	return err
+}
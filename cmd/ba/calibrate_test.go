@@ -0,0 +1,55 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSamplesNeeded(t *testing.T) {
+	m := minSamples(0.05)
+	if m != 4 {
+		t.Fatal(m)
+	}
+	if n := samplesNeeded(0, 0.02, 0.05); n != m {
+		t.Fatal(n)
+	}
+	// 2*(1.96+0.84)^2*(0.05/0.02)^2*pi/3 = 102.6
+	if n := samplesNeeded(0.05, 0.02, 0.05); n != 103 {
+		t.Fatal(n)
+	}
+	// Halving the effect size quadruples the samples.
+	if a, b := samplesNeeded(0.05, 0.04, 0.05), samplesNeeded(0.05, 0.02, 0.05); b < 4*a-4 || b > 4*a {
+		t.Fatal(a, b)
+	}
+}
+
+func TestPrintCalibration(t *testing.T) {
+	a := "pkg: example.com/a\nBenchmarkFoo 10 100 ns/op\nBenchmarkFoo 10 102 ns/op\nBenchmarkFoo 10 98 ns/op\nBenchmarkFoo 10 100 ns/op\n"
+	b := "pkg: example.com/a\nBenchmarkFoo 10 101 ns/op\nBenchmarkFoo 10 103 ns/op\nBenchmarkFoo 10 99 ns/op\nBenchmarkFoo 10 101 ns/op\n"
+	s := &session{Sides: []*sessionSide{{Name: "HEAD#1", Output: a}, {Name: "HEAD#2", Output: b}}, Calibrate: true}
+	var buf bytes.Buffer
+	if err := printCalibration(&buf, s, 2, 0.05, 2); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"pkg: example.com/a\n",
+		"Foo  +1.00%  1.60%  p=0.429  11\n",
+		"noise floor: median |delta| 1.00%, max 1.00% (Foo)\n",
+		"0 of 1 benchmarks falsely reported as changed at -alpha 0.05\n",
+		"to detect a 2% change with 80% power: -count 2 -series 6\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("missing %q in:\n%s", want, got)
+		}
+	}
+	s.Sides = s.Sides[:1]
+	if err := printCalibration(&buf, s, 2, 0.05, 2); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// cgroupWrap returns the systemd-run command prefix to run each benchmark
+// process in its own transient scope, i.e. a cgroup v2, restricted to cpus
+// with at most memory and the CPU weight.
+//
+// cpus and memory are not restricted when empty. As a regular user, the scope
+// is created in the user's systemd instance, which only applies the limits of
+// the controllers delegated to it.
+func cgroupWrap(cpus []int, memory string, weight int) ([]string, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("-cgroup is only supported on linux")
+	}
+	if weight < 1 || weight > 10000 {
+		return nil, errors.New("-cgroup-weight must be between 1 and 10000")
+	}
+	if _, err := exec.LookPath("systemd-run"); err != nil {
+		return nil, errors.New("-cgroup requires systemd-run")
+	}
+	args := []string{"systemd-run", "--scope", "--quiet", "--collect"}
+	if os.Getuid() != 0 {
+		args = append(args, "--user")
+	}
+	args = append(args, "-p", "CPUWeight="+strconv.Itoa(weight))
+	if len(cpus) != 0 {
+		l := make([]string, 0, len(cpus))
+		for _, c := range cpus {
+			l = append(l, strconv.Itoa(c))
+		}
+		args = append(args, "-p", "AllowedCPUs="+strings.Join(l, ","))
+	}
+	if memory != "" {
+		// Do not let the kernel swap the benchmark out instead.
+		args = append(args, "-p", "MemoryMax="+memory, "-p", "MemorySwapMax=0")
+	}
+	args = append(args, "--")
+	// Fail early, e.g. without a systemd session or with an invalid limit.
+	/* #nosec G204 */
+	if out, err := exec.Command(args[0], append(args[1:], "true")...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("-cgroup: %s: %w\n%s", strings.Join(args, " "), err, out)
+	}
+	return args, nil
+}
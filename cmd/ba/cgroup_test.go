@@ -0,0 +1,28 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrapperCgroup(t *testing.T) {
+	o := &benchOptions{wrap: []string{"numactl", "--membind=0"}, cgroup: []string{"systemd-run", "--scope", "--"}}
+	if got, want := (&side{}).wrapper(o), []string{"systemd-run", "--scope", "--", "numactl", "--membind=0"}; !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+	s := &side{wrap: []string{"numactl", "--membind=1"}}
+	if got, want := s.wrapper(o), []string{"systemd-run", "--scope", "--", "numactl", "--membind=1"}; !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+	// The prefix is not modified.
+	if len(o.cgroup) != 3 {
+		t.Fatal(o.cgroup)
+	}
+	if _, err := cgroupWrap(nil, "", 0); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// changedFiles returns the files changed in the working tree since the merge
+// base with each ref, relative to the root of the repository.
+func changedFiles(refs []string) ([]string, error) {
+	seen := map[string]bool{}
+	var out []string
+	for _, ref := range refs {
+		mb, err := git("merge-base", ref, "HEAD")
+		if err != nil {
+			return nil, errors.New(mb)
+		}
+		files, err := git("diff", "--name-only", mb)
+		if err != nil {
+			return nil, errors.New(files)
+		}
+		for _, f := range strings.Split(files, "\n") {
+			if f != "" && !seen[f] {
+				seen[f] = true
+				out = append(out, f)
+			}
+		}
+	}
+	return out, nil
+}
+
+// listedPackage is a package as printed by go list -test.
+type listedPackage struct {
+	importPath, dir string
+	deps            []string
+}
+
+// listPackages returns the packages matching pattern in the current checkout,
+// including their test variants.
+func listPackages(pattern string) ([]listedPackage, error) {
+	var out []listedPackage
+	for _, m := range splitModules("", pattern) {
+		c := command("go", "list", "-test", "-f", "{{.ImportPath}}\t{{.Dir}}\t{{join .Deps \" \"}}", m.pattern)
+		c.Dir = m.dir
+		b, err := c.Output()
+		if err != nil {
+			return nil, fmt.Errorf("go list %s: %w", m.pattern, err)
+		}
+		for _, l := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			f := strings.SplitN(l, "\t", 3)
+			if len(f) != 3 {
+				continue
+			}
+			out = append(out, listedPackage{importPath: f[0], dir: f[1], deps: strings.Fields(f[2])})
+		}
+	}
+	return out, nil
+}
+
+// testedPackage returns the package whose tests an entry of go list -test
+// belongs to, e.g. "p" for "p", "p_test [p.test]" and "p.test".
+func testedPackage(importPath string) string {
+	if i := strings.Index(importPath, " ["); i != -1 {
+		return strings.TrimSuffix(strings.TrimSuffix(importPath[i+2:], "]"), ".test")
+	}
+	return strings.TrimSuffix(importPath, ".test")
+}
+
+// affectedPackages returns the import paths of the packages whose benchmarks
+// may be affected by the changed files: the packages containing a changed
+// file, e.g. in their testdata, and the ones depending on them, including via
+// their tests.
+//
+// root is the directory the files are relative to. It returns nil when all the
+// packages are affected, e.g. when go.mod changed.
+func affectedPackages(root string, files []string, pkgs []listedPackage) map[string]bool {
+	dirs := map[string]string{}
+	for _, p := range pkgs {
+		if !strings.Contains(p.importPath, " [") && !strings.HasSuffix(p.importPath, ".test") {
+			dirs[p.dir] = p.importPath
+		}
+	}
+	changed := map[string]bool{}
+	for _, f := range files {
+		switch filepath.Base(f) {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+			return nil
+		}
+		// Walk up to the package containing the file.
+		for d := filepath.Dir(filepath.Join(root, filepath.FromSlash(f))); ; d = filepath.Dir(d) {
+			if p, ok := dirs[d]; ok {
+				changed[p] = true
+				break
+			}
+			if d == root || filepath.Dir(d) == d {
+				break
+			}
+		}
+	}
+	out := map[string]bool{}
+	for _, p := range pkgs {
+		t := testedPackage(p.importPath)
+		if changed[t] {
+			out[t] = true
+			continue
+		}
+		for _, d := range p.deps {
+			// Strip the test variant, e.g. "q [p.test]".
+			if i := strings.Index(d, " ["); i != -1 {
+				d = d[:i]
+			}
+			if changed[d] {
+				out[t] = true
+				break
+			}
+		}
+	}
+	return out
+}
+
+// changedPackages returns the packages matching pattern affected by the
+// changes since the merge base with each ref, or nil when all of them are.
+func changedPackages(refs []string, pattern string) (map[string]bool, error) {
+	root, err := repo.root("")
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, err
+	}
+	files, err := changedFiles(refs)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := listPackages(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return affectedPackages(root, files, pkgs), nil
+}
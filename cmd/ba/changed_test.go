@@ -0,0 +1,47 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAffectedPackages(t *testing.T) {
+	root := filepath.FromSlash("/src")
+	dir := func(p string) string { return filepath.Join(root, filepath.FromSlash(p)) }
+	pkgs := []listedPackage{
+		{importPath: "m/a", dir: dir("a")},
+		{importPath: "m/b", dir: dir("b"), deps: []string{"m/a"}},
+		{importPath: "m/c", dir: dir("c")},
+		// c's external tests use d.
+		{importPath: "m/c_test [m/c.test]", dir: dir("c"), deps: []string{"m/c [m/c.test]", "m/d"}},
+		{importPath: "m/c.test", dir: dir("c"), deps: []string{"m/c [m/c.test]", "m/c_test [m/c.test]", "m/d"}},
+		{importPath: "m/d", dir: dir("d")},
+		{importPath: "m/e", dir: dir("e")},
+	}
+	for _, tc := range []struct {
+		files []string
+		want  map[string]bool
+	}{
+		{[]string{"a/a.go"}, map[string]bool{"m/a": true, "m/b": true}},
+		{[]string{"d/testdata/x.txt"}, map[string]bool{"m/c": true, "m/d": true}},
+		{[]string{"README.md", "e2/x.go"}, map[string]bool{}},
+		{[]string{"a/a.go", "go.sum"}, nil},
+	} {
+		if got := affectedPackages(root, tc.files, pkgs); !reflect.DeepEqual(tc.want, got) {
+			t.Fatalf("%v: %v != %v", tc.files, tc.want, got)
+		}
+	}
+}
+
+func TestTestedPackage(t *testing.T) {
+	for in, want := range map[string]string{"m/a": "m/a", "m/a [m/a.test]": "m/a", "m/a_test [m/a.test]": "m/a", "m/a.test": "m/a"} {
+		if got := testedPackage(in); got != want {
+			t.Fatalf("%s: %s != %s", in, want, got)
+		}
+	}
+}
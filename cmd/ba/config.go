@@ -0,0 +1,158 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configFile is the file at the root of the repository with the project's
+// default flags.
+const configFile = ".ba.yml"
+
+// configEntry is one flag set by the config file.
+type configEntry struct {
+	name, value string
+	line        int
+}
+
+// parseConfig parses a flat YAML mapping of flag names to scalar values, e.g.
+//
+//	pkg: ./...
+//	benchtime: 200ms
+//	fail-on-regression: 10
+//	fail-on-regression-for:
+//	  ^BenchmarkParse: 2
+//	  Cold: 10
+//
+// A nested mapping sets the flag once per entry as "key=value", in order.
+// Only this subset of YAML is supported.
+func parseConfig(r io.Reader) ([]configEntry, error) {
+	var out []configEntry
+	// parent is the flag of the nested mapping being parsed, if any.
+	parent := ""
+	parentLine := 0
+	s := bufio.NewScanner(r)
+	for i := 1; s.Scan(); i++ {
+		l := s.Text()
+		if t := strings.TrimSpace(l); t == "" || t[0] == '#' || t == "---" {
+			continue
+		}
+		nested := l[0] == ' ' || l[0] == '\t'
+		if nested && parent == "" {
+			return nil, fmt.Errorf("line %d: nested values are not supported", i)
+		}
+		if !nested && parent != "" && parentLine != 0 {
+			return nil, fmt.Errorf("line %d: only scalar values are supported", parentLine)
+		}
+		k, v, mapping, err := splitEntry(strings.TrimSpace(l))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i, err)
+		}
+		if nested {
+			if mapping {
+				return nil, fmt.Errorf("line %d: only scalar values are supported", i)
+			}
+			out = append(out, configEntry{name: parent, value: k + "=" + v, line: i})
+			parentLine = 0
+			continue
+		}
+		parent = ""
+		if mapping {
+			// Must be followed by a nested mapping.
+			parent, parentLine = k, i
+			continue
+		}
+		out = append(out, configEntry{name: k, value: v, line: i})
+	}
+	if parentLine != 0 {
+		return nil, fmt.Errorf("line %d: only scalar values are supported", parentLine)
+	}
+	return out, s.Err()
+}
+
+// splitEntry splits a "key: value" line, unquoting both. mapping is true when
+// there is no value, i.e. a nested mapping follows.
+func splitEntry(l string) (k, v string, mapping bool, err error) {
+	if l[0] == '"' || l[0] == '\'' {
+		j := strings.IndexByte(l[1:], l[0])
+		if j == -1 || !strings.HasPrefix(l[j+2:], ":") {
+			return "", "", false, errors.New("expected \"name: value\"")
+		}
+		k, l = l[1:j+1], l[j+3:]
+	} else {
+		f := strings.SplitN(l, ":", 2)
+		if len(f) != 2 {
+			return "", "", false, errors.New("expected \"name: value\"")
+		}
+		k, l = strings.TrimSpace(f[0]), f[1]
+	}
+	v = strings.TrimSpace(l)
+	switch {
+	case len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0]:
+		v = v[1 : len(v)-1]
+	case v == "" || v[0] == '#':
+		return k, "", true, nil
+	case v[0] == '[' || v[0] == '{' || v[0] == '|' || v[0] == '>':
+		return "", "", false, errors.New("only scalar values are supported")
+	default:
+		if j := strings.Index(v, " #"); j != -1 {
+			v = strings.TrimSpace(v[:j])
+		}
+	}
+	return k, v, false, nil
+}
+
+// loadConfig sets the flags that were not specified on the command line from
+// the config file at path.
+//
+// When path is empty, .ba.yml at the root of the repository is used if
+// present.
+func loadConfig(fs *flag.FlagSet, path string) error {
+	if path == "" {
+		root, err := repo.root("")
+		if err != nil {
+			// Not in a checkout.
+			return nil
+		}
+		path = filepath.Join(root, configFile)
+		if _, err = os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+	/* #nosec G304 */
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entries, err := parseConfig(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, e := range entries {
+		if fs.Lookup(e.name) == nil || e.name == "config" {
+			return fmt.Errorf("%s:%d: unknown flag %q", path, e.line, e.name)
+		}
+		if set[e.name] {
+			continue
+		}
+		if err = fs.Set(e.name, e.value); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", path, e.line, e.name, err)
+		}
+	}
+	return nil
+}
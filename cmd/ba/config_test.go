@@ -0,0 +1,74 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	got, err := parseConfig(strings.NewReader("# Project defaults.\n---\npkg: ./...\nbench: \"^BenchmarkFoo$\"\n\nbenchtime: 200ms # Longer.\nstrict-env: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []configEntry{
+		{"pkg", "./...", 3},
+		{"bench", "^BenchmarkFoo$", 4},
+		{"benchtime", "200ms", 6},
+		{"strict-env", "true", 7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%v != %v", got, want)
+	}
+	got, err = parseConfig(strings.NewReader("fail-on-regression-for:\n  ^BenchmarkParse: 2 # Hot.\n  \"a:b\": 10%\ncount: 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []configEntry{
+		{"fail-on-regression-for", "^BenchmarkParse=2", 2},
+		{"fail-on-regression-for", "a:b=10%", 3},
+		{"count", "3", 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%v != %v", got, want)
+	}
+	for _, c := range []string{"pkg", "pkg:", "a:\nb: c\n", "  b: c\n", "a:\n  b:\n    c: d\n", "against: [a, b]\n"} {
+		if _, err = parseConfig(strings.NewReader(c)); err == nil {
+			t.Fatal(c)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "ba.yml")
+	if err := os.WriteFile(p, []byte("count: 5\nbenchtime: 250ms\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("ba", flag.ContinueOnError)
+	count := fs.Int("count", 2, "")
+	benchtime := fs.Duration("benchtime", time.Second, "")
+	if err := fs.Parse([]string{"-count", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(fs, p); err != nil {
+		t.Fatal(err)
+	}
+	// The command line wins.
+	if *count != 3 || *benchtime != 250*time.Millisecond {
+		t.Fatal(*count, *benchtime)
+	}
+	if err := os.WriteFile(p, []byte("foo: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig(fs, p); err == nil || !strings.Contains(err.Error(), "unknown flag") {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// container runs the test binaries in a Docker or Podman container with fixed
+// resources, as specified by -container.
+type container struct {
+	// tool is the container engine, docker or podman.
+	tool  string
+	image string
+	// cpus and memory are the --cpus and --memory limits. Not set when empty.
+	cpus   string
+	memory string
+}
+
+// newContainer returns a container running image with the docker or podman
+// engine found in PATH.
+func newContainer(image, cpus, memory string) (*container, error) {
+	for _, t := range []string{"docker", "podman"} {
+		if _, err := exec.LookPath(t); err == nil {
+			return &container{tool: t, image: image, cpus: cpus, memory: memory}, nil
+		}
+	}
+	return nil, errors.New("-container requires docker or podman")
+}
+
+// wrap returns the command prefix to run the test binary b in the container.
+//
+// The checkout containing the package and the binary are mounted read-only at
+// the same path, and writable is mounted read-write, e.g. for profiles.
+func (c *container) wrap(o *benchOptions, s *side, b *testBinary, writable []string) []string {
+	dir := b.dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	args := []string{c.tool, "run", "--rm", "--network", "none", "-w", dir}
+	if c.cpus != "" {
+		args = append(args, "--cpus", c.cpus)
+	}
+	if c.memory != "" {
+		args = append(args, "--memory", c.memory)
+	}
+	if len(o.pin) != 0 {
+		var l []string
+		for _, p := range o.pin {
+			l = append(l, strconv.Itoa(p))
+		}
+		args = append(args, "--cpuset-cpus", strings.Join(l, ","))
+	}
+	if uid := os.Getuid(); uid >= 0 {
+		// So the profiles are not owned by root.
+		args = append(args, "--user", strconv.Itoa(uid)+":"+strconv.Itoa(os.Getgid()))
+	}
+	for _, e := range s.runEnv(o) {
+		args = append(args, "-e", e)
+	}
+	mounted := map[string]bool{}
+	mount := func(d, mode string) {
+		if !mounted[d] {
+			mounted[d] = true
+			args = append(args, "-v", d+":"+d+":"+mode)
+		}
+	}
+	for _, d := range writable {
+		mount(d, "rw")
+	}
+	if b.root != "" {
+		mount(b.root, "ro")
+	} else {
+		mount(dir, "ro")
+	}
+	mount(filepath.Dir(b.path), "ro")
+	return append(args, c.image)
+}
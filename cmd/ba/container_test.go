@@ -0,0 +1,32 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestContainerWrap(t *testing.T) {
+	dir := t.TempDir()
+	prof := t.TempDir()
+	c := &container{tool: "podman", image: "alpine", cpus: "1"}
+	o := &benchOptions{pin: []int{2, 3}}
+	s := &side{env: []string{"GOGC=off"}}
+	b := &testBinary{dir: dir, path: filepath.Join(dir, "0.test"), root: dir}
+	got := strings.Join(c.wrap(o, s, b, []string{prof}), " ")
+	user := ""
+	if uid := os.Getuid(); uid >= 0 {
+		user = "--user " + strconv.Itoa(uid) + ":" + strconv.Itoa(os.Getgid()) + " "
+	}
+	want := "podman run --rm --network none -w " + dir + " --cpus 1 --cpuset-cpus 2,3 " + user + "-e GOGC=off " +
+		"-v " + prof + ":" + prof + ":rw -v " + dir + ":" + dir + ":ro alpine"
+	if got != want {
+		t.Fatalf("%s\n!=\n%s", got, want)
+	}
+}
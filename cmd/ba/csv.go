@@ -0,0 +1,107 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+// csvSample is one benchmark run parsed from a side's output.
+type csvSample struct {
+	pkg, name string
+	// run is the index of the run of this benchmark in the side.
+	run int
+	// series is the 0-based series of the run, -1 when unknown.
+	series int
+	values map[string]float64
+}
+
+// printCSV writes one row per benchmark run of each side, with one column per
+// unit as reported by go test, e.g. ns/op, B/op and allocs/op.
+//
+// The series column is derived from the number of runs of each benchmark and
+// the number of series in the session. It is empty when they do not divide
+// evenly.
+func printCSV(w io.Writer, s *session) error {
+	var units []string
+	samples := make([][]*csvSample, len(s.Sides))
+	for i, ss := range s.Sides {
+		runs := map[string]int{}
+		r := benchfmt.NewReader(strings.NewReader(ss.Output), ss.Name)
+		for r.Scan() {
+			res, ok := r.Result().(*benchfmt.Result)
+			if !ok {
+				continue
+			}
+			c := &csvSample{pkg: res.GetConfig("pkg"), name: string(res.Name), values: map[string]float64{}}
+			k := c.pkg + " " + c.name
+			c.run = runs[k]
+			runs[k]++
+			for _, v := range res.Values {
+				// Only the tidied units, like ns/op, have an original unit.
+				u, f := v.OrigUnit, v.OrigValue
+				if u == "" {
+					u, f = v.Unit, v.Value
+				}
+				c.values[u] = f
+				if !contains(units, u) {
+					units = append(units, u)
+				}
+			}
+			samples[i] = append(samples[i], c)
+		}
+		if err := r.Err(); err != nil {
+			return err
+		}
+		for _, c := range samples[i] {
+			c.series = seriesOf(c.run, runs[c.pkg+" "+c.name], s.Series)
+		}
+	}
+	c := csv.NewWriter(w)
+	_ = c.Write(append([]string{"pkg", "benchmark", "side", "series"}, units...))
+	for i, ss := range s.Sides {
+		for _, smp := range samples[i] {
+			series := ""
+			if smp.series >= 0 {
+				series = strconv.Itoa(smp.series)
+			}
+			l := []string{smp.pkg, smp.name, ss.Name, series}
+			for _, u := range units {
+				v, ok := smp.values[u]
+				if !ok {
+					l = append(l, "")
+					continue
+				}
+				l = append(l, strconv.FormatFloat(v, 'g', -1, 64))
+			}
+			_ = c.Write(l)
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// seriesOf returns the 0-based series of the run-th run of a benchmark that
+// ran total times over series series, or -1 when it cannot be determined.
+func seriesOf(run, total, series int) int {
+	if series <= 0 || total%series != 0 {
+		return -1
+	}
+	return run / (total / series)
+}
+
+func contains(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
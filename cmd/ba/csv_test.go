@@ -0,0 +1,33 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintCSV(t *testing.T) {
+	s := &session{
+		Series: 2,
+		Sides: []*sessionSide{
+			{Name: "old", Output: "pkg: example.com/x\nBenchmarkA \t10\t100 ns/op\t16 B/op\t1 allocs/op\nBenchmarkA \t10\t110 ns/op\t16 B/op\t1 allocs/op\n"},
+			{Name: "new", Output: "pkg: example.com/x\nBenchmarkA \t10\t90 ns/op\t0 B/op\t0 allocs/op\nBenchmarkB-4 \t10\t5 ns/op\nBenchmarkA \t10\t95 ns/op\t0 B/op\t0 allocs/op\n"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := printCSV(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	want := "pkg,benchmark,side,series,ns/op,B/op,allocs/op\n" +
+		"example.com/x,A,old,0,100,16,1\n" +
+		"example.com/x,A,old,1,110,16,1\n" +
+		"example.com/x,A,new,0,90,0,0\n" +
+		"example.com/x,B-4,new,,5,,\n" +
+		"example.com/x,A,new,1,95,0,0\n"
+	if got := buf.String(); got != want {
+		t.Fatal(got)
+	}
+}
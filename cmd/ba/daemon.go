@@ -0,0 +1,184 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// daemonOptions describes what "ba daemon" benchmarks and records.
+type daemonOptions struct {
+	// interval is the time between two checks for new commits.
+	interval time.Duration
+	// branch is the branch whose first parent history is benchmarked.
+	branch string
+	// maxCommits is the maximum number of new commits benchmarked per check,
+	// the newest ones.
+	maxCommits int
+	// db is the history database the results are appended to.
+	db string
+	// serve is the address to serve the history on, if any.
+	serve string
+	// last is the number of commits shown by the dashboard.
+	last int
+	// labels are the experiment labels of the results.
+	labels map[string]string
+}
+
+// daemonRev returns the revision of branch to benchmark: its remote-tracking
+// branch when the repository has an origin remote.
+func daemonRev(branch string) string {
+	if _, err := git("remote", "get-url", "origin"); err != nil {
+		return branch
+	}
+	return "origin/" + branch
+}
+
+// recordedCommits returns the commits with results recorded on this machine
+// with the labels in the history database.
+func recordedCommits(db string, labels map[string]string) (map[string]bool, error) {
+	out := map[string]bool{}
+	if _, err := os.Stat(db); errors.Is(err, fs.ErrNotExist) {
+		return out, nil
+	}
+	entries, err := readHistory(db, "", machineFingerprint(), labels)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range entries {
+		out[h.SHA1] = true
+	}
+	return out, nil
+}
+
+// newCommits returns the commits of the first parent history of tip more
+// recent than the last recorded one, oldest first, at most n, and the commit
+// preceding the oldest one to compare them against. See pendingCommits.
+func newCommits(tip string, n int, recorded map[string]bool) (string, []string, error) {
+	out, err := git("rev-list", "--first-parent", "-n", strconv.Itoa(n+1), tip)
+	if err != nil {
+		return "", nil, errors.New(out)
+	}
+	base, c := pendingCommits(strings.Split(out, "\n"), n, recorded)
+	return base, c, nil
+}
+
+// pendingCommits returns the commits of revs, a first parent history newest
+// first, more recent than the last recorded one, oldest first, at most n.
+//
+// base is the commit preceding the oldest one, usually the last recorded one,
+// since a session needs a baseline to compare against. It is "" when there is
+// no new commit or when the oldest one is the root commit.
+func pendingCommits(revs []string, n int, recorded map[string]bool) (base string, c []string) {
+	for _, l := range revs {
+		if l == "" {
+			break
+		}
+		if recorded[l] || len(c) == n {
+			base = l
+			break
+		}
+		c = append([]string{l}, c...)
+	}
+	if len(c) == 0 {
+		base = ""
+	}
+	return base, c
+}
+
+// daemonCheck benchmarks the new commits of the branch and appends their
+// results to the history database.
+func daemonCheck(ctx context.Context, o *benchOptions, d *daemonOptions, mu *sync.Mutex, series int, nowarm bool) error {
+	tip := daemonRev(d.branch)
+	if tip != d.branch {
+		if out, err := git("fetch", "--quiet", "origin", d.branch); err != nil {
+			return errors.New(out)
+		}
+	}
+	mu.Lock()
+	recorded, err := recordedCommits(d.db, d.labels)
+	mu.Unlock()
+	if err != nil {
+		return err
+	}
+	base, commits, err := newCommits(tip, d.maxCommits, recorded)
+	if err != nil || len(commits) == 0 {
+		return err
+	}
+	if base == "" {
+		// The root commit has no parent to be compared against, so it is used
+		// as the baseline and not recorded.
+		if len(commits) == 1 {
+			stderrLog.infof("nothing to compare the root commit of %s against", tip)
+			return nil
+		}
+		base, commits = commits[0], commits[1:]
+	}
+	sides := make([]*side, 0, len(commits)+1)
+	for _, c := range append([]string{base}, commits...) {
+		short, err := git("rev-parse", "--short", c)
+		if err != nil {
+			return errors.New(short)
+		}
+		sides = append(sides, &side{name: short, ref: c})
+	}
+	stderrLog.infof("benchmarking %d new commits of %s", len(commits), tip)
+	s, err := runSession(ctx, o, sides, series, nowarm)
+	if err != nil {
+		return err
+	}
+	// The baseline is either already recorded or older than the commits
+	// benchmarked this time.
+	s.Sides = s.Sides[1:]
+	mu.Lock()
+	n, err := recordHistory(d.db, s)
+	mu.Unlock()
+	if err == nil {
+		stderrLog.infof("recorded %d results in %s", n, d.db)
+	}
+	return err
+}
+
+// daemon benchmarks the new commits of the branch every interval and
+// appends their results to the history database, serving it if requested,
+// until ctx is canceled.
+//
+// A failed check, e.g. because a commit does not compile, is reported and
+// the daemon continues.
+func daemon(ctx context.Context, o *benchOptions, d *daemonOptions, series int, nowarm bool) error {
+	// The benchmarks added by the new commits are recorded from then on.
+	o.allowMissing = true
+	mu := &sync.Mutex{}
+	if d.serve != "" {
+		db := &dashboard{db: d.db, rev: daemonRev(d.branch), last: d.last, alpha: 0.05, labels: d.labels, mu: mu}
+		stop, err := listenAndServe(d.serve, db.handler())
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	for {
+		err := daemonCheck(ctx, o, d, mu, series, nowarm)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			stderrLog.errorf("%s", err)
+		}
+		stderrLog.infof("next check at %s; press Ctrl-C to stop", time.Now().Add(d.interval).Format("15:04:05"))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(d.interval):
+		}
+	}
+}
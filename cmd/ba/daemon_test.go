@@ -0,0 +1,55 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecordedCommits(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "history.db")
+	if got, err := recordedCommits(db, nil); err != nil || len(got) != 0 {
+		t.Fatal(got, err)
+	}
+	s := &session{Sides: []*sessionSide{
+		{Name: "aaaa", SHA1: "aaaa", Output: "BenchmarkFoo 1 10 ns/op\nBenchmarkBar 1 1 ns/op\n"},
+		{Name: "bbbb", SHA1: "bbbb", Output: "BenchmarkFoo 1 9 ns/op\n"},
+	}}
+	if _, err := recordHistory(db, s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := recordedCommits(db, nil)
+	if err != nil || len(got) != 2 || !got["aaaa"] || !got["bbbb"] {
+		t.Fatal(got, err)
+	}
+}
+
+func TestPendingCommits(t *testing.T) {
+	revs := []string{"dddd", "cccc", "bbbb", "aaaa", ""}
+	data := []struct {
+		n        int
+		recorded map[string]bool
+		base     string
+		commits  []string
+	}{
+		// The usual check finds one new commit, compared against the last
+		// recorded one.
+		{5, map[string]bool{"cccc": true, "bbbb": true}, "cccc", []string{"dddd"}},
+		{5, map[string]bool{"bbbb": true}, "bbbb", []string{"cccc", "dddd"}},
+		// The baseline of the newest n commits is not recorded.
+		{2, nil, "bbbb", []string{"cccc", "dddd"}},
+		// The root commit has no baseline.
+		{5, nil, "", []string{"aaaa", "bbbb", "cccc", "dddd"}},
+		{5, map[string]bool{"dddd": true}, "", nil},
+	}
+	for i, l := range data {
+		base, commits := pendingCommits(revs, l.n, l.recorded)
+		if base != l.base || !reflect.DeepEqual(commits, l.commits) {
+			t.Fatalf("#%d: %q %q", i, base, commits)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// printPlan prints the commands runSession would run, without running any,
+// and an estimate of the time spent in each benchmark.
+//
+// Temporary paths are shown as placeholders, e.g. <worktree:HEAD~1>.
+func printPlan(w io.Writer, o *benchOptions, sides []*side, series int, nowarm bool) error {
+	branch := "<current branch>"
+	if b, err := repo.current(); err == nil {
+		branch = b
+	}
+	pkg := o.pkgPattern()
+	if m := splitModules("", pkg); len(m) > 1 {
+		fmt.Fprintf(w, "# %s spans %d modules; the go commands run in each module's directory\n", pkg, len(m))
+	}
+	// The directory each side runs in.
+	dirs := make([]string, len(sides))
+	added := map[string]bool{}
+	for i, s := range sides {
+		if s.ref == "" && s.dir != "" {
+			dirs[i] = s.dir
+			fmt.Fprintf(w, "cp -a <working tree> %s\n", s.dir)
+			continue
+		}
+		if s.ref != "" && !o.inplace {
+			dirs[i] = "<worktree:" + s.ref + ">"
+			if !added[s.ref] {
+				added[s.ref] = true
+				fmt.Fprintf(w, "%s\n", repo.addWorktreeCmd(dirs[i], s.ref))
+			}
+		}
+	}
+	// inSide prints the checkouts around fn, like the real inSide.
+	inSide := func(i int, fn func(prefix string)) {
+		s := sides[i]
+		prefix := ""
+		if dirs[i] != "" {
+			prefix = "cd " + dirs[i] + " && "
+		}
+		if s.ref == "" || dirs[i] != "" {
+			fn(prefix)
+			return
+		}
+		fmt.Fprintf(w, "%s\n", repo.checkoutCmd(s.ref))
+		fn(prefix)
+		fmt.Fprintf(w, "%s\n", repo.checkoutCmd(branch))
+	}
+	if o.verify != "" {
+		fmt.Fprintf(w, "# unit tests\n")
+		tested := map[string]bool{}
+		for i, s := range sides {
+			key := s.ref + "\x00" + dirs[i] + "\x00" + s.goTool + "\x00" + strings.Join(s.buildFlags, " ")
+			if s.cmd != "" || s.bin != "" || tested[key] {
+				continue
+			}
+			tested[key] = true
+			inSide(i, func(prefix string) {
+				fmt.Fprintf(w, "%s%s %s\n", prefix, s.goCmd(), strings.Join(verifyArgs(o, s, pkg), " "))
+			})
+		}
+	}
+	// The test binaries are built once per buildKey().
+	bins := make([]int, len(sides))
+	built := map[string]int{}
+	for i, s := range sides {
+		if s.cmd != "" || s.bin != "" {
+			continue
+		}
+		key := s.ref + "\x00" + dirs[i] + "\x00" + s.goTool + "\x00" + strings.Join(s.buildFlags, " ")
+		if j, ok := built[key]; ok {
+			bins[i] = j
+			continue
+		}
+		built[key] = i
+		bins[i] = i
+		flags := ""
+		for _, f := range s.goBuildFlags(o) {
+			flags += f + " "
+		}
+		inSide(i, func(prefix string) {
+			each := "each package"
+			if o.only != nil {
+				each = "each affected package"
+			}
+			if o.isolateCache {
+				prefix += "GOCACHE=<bin:" + strconv.Itoa(i) + ">/gocache "
+			}
+			fmt.Fprintf(w, "%s%s test -c %s-o <bin:%d>/<n>.test <%s with tests in %s>\n", prefix, s.goCmd(), flags, i, each, pkg)
+		})
+	}
+	run := func(i, count int, extra ...string) {
+		s := sides[i]
+		inSide(i, func(string) {
+			prefix := s.logPrefix(dirs[i], s.runEnv(o))
+			if s.cmd != "" {
+				fmt.Fprintf(w, "%s%s\n", prefix, strings.Join(append(s.wrapper(o), "sh", "-c", s.cmd), " "))
+				return
+			}
+			bin := "<bin:" + strconv.Itoa(bins[i]) + ">/<n>.test"
+			if s.bin != "" {
+				bin = s.bin
+			}
+			cmd := append(append(s.wrapper(o), bin), testArgs(o, o.bench, o.benchtime.String(), count, extra...)...)
+			if o.container != nil {
+				cmd = append(o.container.wrap(o, s, &testBinary{dir: dirs[i], path: bin}, nil), cmd...)
+			}
+			l := prefix + strings.Join(cmd, " ")
+			if o.remote != nil {
+				l += "  # on " + o.remote.host
+			}
+			fmt.Fprintf(w, "%s\n", l)
+		})
+	}
+	if o.adaptive {
+		fmt.Fprintf(w, "# each benchmark is probed with -test.benchtime 1x, then run with its own -test.benchtime and -test.count\n")
+	}
+	if o.shuffle != nil {
+		fmt.Fprintf(w, "# the sides run in a random order in each series\n")
+	}
+	if !nowarm {
+		fmt.Fprintf(w, "# warmup\n")
+		for i := range sides {
+			run(i, 1)
+		}
+	}
+	count, loops := o.count, 1
+	if o.interleave {
+		count, loops = 1, o.count
+	}
+	for n := 0; n < series; n++ {
+		fmt.Fprintf(w, "# series %d\n", n+1)
+		for k := 0; k < loops; k++ {
+			for i, s := range sides {
+				run(i, count)
+				if o.buildTime && s.cmd == "" && s.bin == "" {
+					inSide(i, func(string) {
+						fmt.Fprintf(w, "%sGOCACHE=<empty> GOBIN=<tmp> %s install %s\n", s.logPrefix(dirs[i], s.buildEnv(o)), s.goCmd(), strings.Join(append(s.goBuildFlags(o), pkg), " "))
+					})
+				}
+			}
+		}
+	}
+	if o.stable > 0 {
+		fmt.Fprintf(w, "# more series until every benchmark is within ±%.1f%%, for up to %s\n", o.stable, o.maxtime)
+	}
+	if len(o.profiles) != 0 {
+		fmt.Fprintf(w, "# profiles\n")
+		for i := range sides {
+			var extra []string
+			for _, p := range o.profiles {
+				extra = append(append(extra, p.flag, p.dir+"/"+strconv.Itoa(i)+"-<n>."+p.name+".pprof"), p.extra...)
+			}
+			run(i, o.count, extra...)
+			for _, p := range o.profiles {
+				fmt.Fprintf(w, "go tool pprof -proto -output %s/%d-%s.%s.pprof %s/%d-<n>.%s.pprof...\n", p.dir, i, unsafeChars.ReplaceAllString(sides[i].name, "_"), p.name, p.dir, i, p.name)
+			}
+		}
+	}
+	for i, s := range sides {
+		if dirs[i] != "" && added[s.ref] {
+			added[s.ref] = false
+			fmt.Fprintf(w, "%s\n", repo.removeWorktreeCmd(dirs[i]))
+		}
+	}
+	runs := series * o.count
+	if !nowarm {
+		runs++
+	}
+	if len(o.profiles) != 0 {
+		runs += o.count
+	}
+	d := time.Duration(runs*len(sides)) * o.benchtime
+	l := fmt.Sprintf("# estimated: at least %s per benchmark, excluding compilation", d)
+	if o.timebudget > 0 && o.timebudget < d {
+		l += fmt.Sprintf("; capped by -timebudget %s", o.timebudget)
+	}
+	fmt.Fprintf(w, "%s\n", l)
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintPlan(t *testing.T) {
+	o := &benchOptions{pkg: "./...", bench: ".", benchtime: 100 * time.Millisecond, count: 2}
+	sides := []*side{
+		{name: "HEAD~1", ref: "HEAD~1"},
+		{name: "HEAD", cmd: "./bench.sh"},
+	}
+	var b bytes.Buffer
+	if err := printPlan(&b, o, sides, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	want := "git worktree add <worktree:HEAD~1> HEAD~1\n" +
+		"cd <worktree:HEAD~1> && go test -c -o <bin:0>/<n>.test <each package with tests in ./...>\n" +
+		"# series 1\n" +
+		"cd <worktree:HEAD~1> && <bin:0>/<n>.test -test.v -test.run ^$ -test.bench . -test.benchtime 100ms -test.count 2 -test.cpu 1\n" +
+		"sh -c ./bench.sh\n" +
+		"# series 2\n" +
+		"cd <worktree:HEAD~1> && <bin:0>/<n>.test -test.v -test.run ^$ -test.bench . -test.benchtime 100ms -test.count 2 -test.cpu 1\n" +
+		"sh -c ./bench.sh\n" +
+		"git worktree remove <worktree:HEAD~1>\n" +
+		"# estimated: at least 800ms per benchmark, excluding compilation\n"
+	if got := b.String(); got != want {
+		t.Fatalf("%s\n!=\n%s", got, strings.TrimSpace(want))
+	}
+}
+
+func TestPrintPlanVerify(t *testing.T) {
+	o := &benchOptions{pkg: "./...", bench: ".", benchtime: 100 * time.Millisecond, count: 1, verify: "."}
+	sides := []*side{
+		{name: "HEAD~1", ref: "HEAD~1"},
+		{name: "HEAD"},
+		{name: "HEAD", cmd: "./bench.sh"},
+	}
+	var b bytes.Buffer
+	if err := printPlan(&b, o, sides, 1, true); err != nil {
+		t.Fatal(err)
+	}
+	want := "git worktree add <worktree:HEAD~1> HEAD~1\n" +
+		"# unit tests\n" +
+		"cd <worktree:HEAD~1> && go test -run . -count=1 ./...\n" +
+		"go test -run . -count=1 ./...\n" +
+		"cd <worktree:HEAD~1> && go test -c -o <bin:0>/<n>.test <each package with tests in ./...>\n"
+	if got := b.String(); !strings.HasPrefix(got, want) {
+		t.Fatalf("%s\n!=\n%s", got, strings.TrimSpace(want))
+	}
+}
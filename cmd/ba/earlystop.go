@@ -0,0 +1,79 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"strings"
+
+	"golang.org/x/perf/benchmath"
+)
+
+// earlyStop decides, as specified by -early-stop, whether running more
+// series could change the outcome of -fail-on-regression.
+type earlyStop struct {
+	// threshold and per are the regression thresholds in percent of
+	// -fail-on-regression and -fail-on-regression-for.
+	threshold float64
+	per       thresholds
+	// opts tells whether higher or lower values of a unit are better.
+	opts *tableOptions
+}
+
+// undecided returns the first metric whose confidence interval of the change
+// between the first side and another one still includes its regression
+// threshold, or "" when the outcome of every metric is decided.
+//
+// The metrics without a known better direction or whose threshold is negative
+// are ignored, as by the gate.
+func (e *earlyStop) undecided(stats []string) (string, error) {
+	if len(stats) < 2 {
+		return "", nil
+	}
+	old, err := parseSamples(stats[0])
+	if err != nil {
+		return "", err
+	}
+	for _, out := range stats[1:] {
+		s, err := parseSamples(out)
+		if err != nil {
+			return "", err
+		}
+		for _, k := range s.keys {
+			ov, ok := old.values[k]
+			if !ok {
+				continue
+			}
+			f := strings.SplitN(k, " ", 3)
+			better := e.opts.BetterOf(f[2])
+			limit := e.per.limit(f[1], e.threshold)
+			if better == 0 || limit < 0 {
+				continue
+			}
+			lo, hi := deltaCI(ov, s.values[k])
+			if better > 0 {
+				// A decrease is a regression.
+				lo, hi = -hi, -lo
+			}
+			if lo <= limit && hi > limit {
+				return strings.TrimSpace(k), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// deltaCI returns a conservative 95% confidence interval of the change in
+// percent from old to new: from the lowest new over the highest old to the
+// highest new over the lowest old median within their own 95% confidence
+// intervals. It is infinite when either has too few samples.
+func deltaCI(old, new []float64) (lo, hi float64) {
+	a := benchmath.AssumeNothing.Summary(benchmath.NewSample(old, &benchmath.DefaultThresholds), 0.95)
+	b := benchmath.AssumeNothing.Summary(benchmath.NewSample(new, &benchmath.DefaultThresholds), 0.95)
+	if a.Lo <= 0 || math.IsInf(a.Hi, 0) || math.IsInf(b.Lo, 0) || math.IsInf(b.Hi, 0) {
+		return math.Inf(-1), math.Inf(1)
+	}
+	return 100 * (b.Lo/a.Hi - 1), 100 * (b.Hi/a.Lo - 1)
+}
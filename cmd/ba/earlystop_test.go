@@ -0,0 +1,74 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/maruel/pat/benchcmp"
+)
+
+// benchOutput returns n results of the benchmark name, spread by ±1%.
+func benchOutput(name string, n int, v float64, unit string) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "Benchmark%s-8 100 %g %s\n", name, v*(0.99+0.02*float64(i)/float64(n-1)), unit)
+	}
+	return b.String()
+}
+
+func TestEarlyStop(t *testing.T) {
+	e := &earlyStop{threshold: 5, opts: benchcmp.DefaultOptions}
+	data := []struct {
+		old, new string
+		want     string
+	}{
+		// Too few samples.
+		{benchOutput("A", 3, 100, "ns/op"), benchOutput("A", 3, 100, "ns/op"), "A-8 sec/op"},
+		// Clearly within the threshold.
+		{benchOutput("A", 10, 100, "ns/op"), benchOutput("A", 10, 101, "ns/op"), ""},
+		// Clearly a regression.
+		{benchOutput("A", 10, 100, "ns/op"), benchOutput("A", 10, 120, "ns/op"), ""},
+		// Too close to the threshold.
+		{benchOutput("A", 10, 100, "ns/op"), benchOutput("A", 10, 105, "ns/op"), "A-8 sec/op"},
+		// Higher is better: a 5% increase is fine.
+		{benchOutput("A", 10, 100, "MB/s"), benchOutput("A", 10, 105, "MB/s"), ""},
+		// But not a 5% decrease.
+		{benchOutput("A", 10, 100, "MB/s"), benchOutput("A", 10, 95, "MB/s"), "A-8 B/s"},
+		// Unknown direction.
+		{benchOutput("A", 3, 100, "widgets"), benchOutput("A", 3, 200, "widgets"), ""},
+		// Only on one side.
+		{benchOutput("A", 3, 100, "ns/op"), benchOutput("B", 3, 100, "ns/op"), ""},
+	}
+	for i, l := range data {
+		got, err := e.undecided([]string{l.old, l.new})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != l.want {
+			t.Fatalf("#%d: %q", i, got)
+		}
+	}
+	// A negative threshold disables the benchmark.
+	e.per = thresholds{{re: regexp.MustCompile("^A$"), pct: -1}}
+	if got, err := e.undecided([]string{benchOutput("A", 3, 100, "ns/op"), benchOutput("A", 3, 100, "ns/op")}); err != nil || got != "" {
+		t.Fatal(got, err)
+	}
+}
+
+func TestDeltaCI(t *testing.T) {
+	lo, hi := deltaCI([]float64{1, 2}, []float64{1, 2})
+	if !math.IsInf(lo, -1) || !math.IsInf(hi, 1) {
+		t.Fatal(lo, hi)
+	}
+	v := []float64{100, 100, 100, 100, 100, 100}
+	if lo, hi = deltaCI(v, v); lo != 0 || hi != 0 {
+		t.Fatal(lo, hi)
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// checkEnv warns about host settings that make benchmark results unreliable,
+// like CPU frequency scaling. cpus limits the check to these CPUs when set.
+//
+// When strict is true, an error is returned instead.
+func checkEnv(cpus []int, strict bool) error {
+	issues := envIssues(cpus)
+	if len(issues) == 0 {
+		return nil
+	}
+	if strict {
+		return errors.New("refusing to run; the environment is not suitable for benchmarking:\n  " + strings.Join(issues, "\n  "))
+	}
+	for _, i := range issues {
+		stderrLog.warnf("%s", i)
+	}
+	stderrLog.warnf("results may be unreliable; use -strict-env to refuse to run")
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// envIssues returns the power settings that are not suitable for
+// benchmarking.
+func envIssues(cpus []int) []string {
+	var out []string
+	if b, err := exec.Command("pmset", "-g").Output(); err == nil {
+		for _, l := range strings.Split(string(b), "\n") {
+			if f := strings.Fields(l); len(f) == 2 && f[0] == "lowpowermode" && f[1] == "1" {
+				out = append(out, "low power mode is enabled")
+			}
+		}
+	}
+	if b, err := exec.Command("pmset", "-g", "therm").Output(); err == nil {
+		for _, l := range strings.Split(string(b), "\n") {
+			f := strings.Fields(l)
+			if len(f) == 3 && f[0] == "CPU_Speed_Limit" {
+				if v, err := strconv.Atoi(f[2]); err == nil && v < 100 {
+					out = append(out, fmt.Sprintf("CPU speed is thermally limited to %d%%", v))
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// envIssues returns the CPU frequency scaling settings that are not suitable
+// for benchmarking.
+func envIssues(cpus []int) []string {
+	var paths []string
+	if len(cpus) != 0 {
+		for _, c := range cpus {
+			paths = append(paths, "/sys/devices/system/cpu/cpu"+strconv.Itoa(c)+"/cpufreq/scaling_governor")
+		}
+	} else {
+		paths, _ = filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_governor")
+	}
+	// Group the CPUs per governor to keep the warning short.
+	governors := map[string][]string{}
+	for _, p := range paths {
+		if g := readSys(p); g != "" && g != "performance" {
+			cpu := strings.TrimPrefix(filepath.Base(filepath.Dir(filepath.Dir(p))), "cpu")
+			governors[g] = append(governors[g], cpu)
+		}
+	}
+	var out []string
+	for g, l := range governors {
+		out = append(out, fmt.Sprintf("CPU frequency governor is %q instead of \"performance\" on CPU %s", g, strings.Join(l, ",")))
+	}
+	sort.Strings(out)
+	if readSys("/sys/devices/system/cpu/intel_pstate/no_turbo") == "0" {
+		out = append(out, "turbo boost is enabled; disable with: echo 1 > /sys/devices/system/cpu/intel_pstate/no_turbo")
+	}
+	if readSys("/sys/devices/system/cpu/cpufreq/boost") == "1" {
+		out = append(out, "CPU boost is enabled; disable with: echo 0 > /sys/devices/system/cpu/cpufreq/boost")
+	}
+	return out
+}
+
+// readSys returns the trimmed content of a sysfs file, or "" if it can't be
+// read.
+func readSys(p string) string {
+	/* #nosec G304 */
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,28 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// envIssues returns the power settings that are not suitable for
+// benchmarking.
+func envIssues(cpus []int) []string {
+	b, err := exec.Command("powercfg", "/getactivescheme").Output()
+	if err != nil {
+		return nil
+	}
+	s := strings.TrimSpace(string(b))
+	// High performance and Ultimate Performance power schemes.
+	for _, g := range []string{"8c5e7fda-e8bf-4a96-9a85-a6e23a8c635c", "e9a42b02-d5df-448d-aa00-03f14749eb61"} {
+		if strings.Contains(s, g) {
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("the power plan is not High performance: %s", s)}
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseEnvMatrix parses the -env-matrix flag.
+//
+// Configurations are separated with ';' and each configuration is a space
+// separated list of environment variables, e.g. "GOGC=100;GOGC=off
+// GOMEMLIMIT=1GiB".
+func parseEnvMatrix(v string) ([][]string, error) {
+	var out [][]string
+	for _, c := range strings.Split(v, ";") {
+		f := strings.Fields(c)
+		if len(f) == 0 {
+			continue
+		}
+		for _, e := range f {
+			if strings.IndexByte(e, '=') <= 0 {
+				return nil, fmt.Errorf("invalid -env-matrix setting %q; expected KEY=VALUE", e)
+			}
+		}
+		out = append(out, f)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("-env-matrix %q has no configuration", v)
+	}
+	return out, nil
+}
+
+// matrixSides returns the sides repeated for each configuration, with the
+// configuration added to their environment and name.
+//
+// The sides of a configuration are contiguous, in the same order as sides.
+func matrixSides(sides []*side, matrix [][]string) []*side {
+	var out []*side
+	for _, env := range matrix {
+		for _, s := range sides {
+			d := *s
+			d.name = s.name + " " + strings.Join(env, " ")
+			d.env = append(append([]string(nil), s.env...), env...)
+			out = append(out, &d)
+		}
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEnvMatrix(t *testing.T) {
+	got, err := parseEnvMatrix("GOGC=100; GOGC=off GOMEMLIMIT=1GiB;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"GOGC=100"}, {"GOGC=off", "GOMEMLIMIT=1GiB"}}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("%#v != %#v", want, got)
+	}
+	for _, v := range []string{"", ";", "GOGC"} {
+		if _, err := parseEnvMatrix(v); err == nil {
+			t.Fatalf("%q: expected error", v)
+		}
+	}
+}
+
+func TestMatrixSides(t *testing.T) {
+	sides := []*side{{name: "HEAD~1", ref: "HEAD~1"}, {name: "HEAD", env: []string{"A=1"}}}
+	got := matrixSides(sides, [][]string{{"GOGC=100"}, {"GOGC=off"}})
+	want := []*side{
+		{name: "HEAD~1 GOGC=100", ref: "HEAD~1", env: []string{"GOGC=100"}},
+		{name: "HEAD GOGC=100", env: []string{"A=1", "GOGC=100"}},
+		{name: "HEAD~1 GOGC=off", ref: "HEAD~1", env: []string{"GOGC=off"}},
+		{name: "HEAD GOGC=off", env: []string{"A=1", "GOGC=off"}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("%#v != %#v", want, got)
+	}
+}
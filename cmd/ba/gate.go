@@ -0,0 +1,122 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// regressions returns the statistically significant regressions larger than
+// threshold percent, or the benchmark's own threshold in per, formatted for
+// the user.
+//
+// Benchmarks whose threshold is negative are ignored.
+func regressions(c []*comparison, threshold float64, per thresholds) []string {
+	var out []string
+	for _, cmp := range c {
+		for _, t := range cmp.tables {
+			for _, row := range t.Rows {
+				limit := per.limit(row.Benchmark, threshold)
+				if row.Change >= 0 || limit < 0 || math.Abs(row.PctDelta) <= limit {
+					continue
+				}
+				l := t.Name(row) + " " + t.Unit + " " + row.Delta
+				if len(c) > 1 {
+					l = cmp.new + ": " + l
+				}
+				out = append(out, l)
+			}
+		}
+	}
+	return out
+}
+
+// checkRegressions returns an error listing the regressions larger than
+// threshold percent, or the benchmark's own threshold in per, if any.
+func checkRegressions(c []*comparison, threshold float64, per thresholds) error {
+	r := regressions(c, threshold, per)
+	if len(r) == 0 {
+		return nil
+	}
+	if len(per) != 0 {
+		return fmt.Errorf("%d benchmark(s) regressed by more than their threshold:\n  %s", len(r), strings.Join(r, "\n  "))
+	}
+	return fmt.Errorf("%d benchmark(s) regressed by more than %g%%:\n  %s", len(r), threshold, strings.Join(r, "\n  "))
+}
+
+// thresholds is a flag mapping benchmark name regexps to the maximum allowed
+// regression in percent, as specified by -fail-on-regression-for, e.g.
+// 'Parse=2'. It can be specified multiple times; the first match wins.
+type thresholds []benchThreshold
+
+type benchThreshold struct {
+	re  *regexp.Regexp
+	pct float64
+}
+
+func (t *thresholds) Set(v string) error {
+	i := strings.LastIndexByte(v, '=')
+	if i == -1 {
+		return fmt.Errorf("expected regexp=percent, got %q", v)
+	}
+	re, err := regexp.Compile(v[:i])
+	if err != nil {
+		return err
+	}
+	var p percent
+	if err = p.Set(v[i+1:]); err != nil {
+		return err
+	}
+	*t = append(*t, benchThreshold{re: re, pct: float64(p)})
+	return nil
+}
+
+func (t *thresholds) String() string {
+	var l []string
+	for _, b := range *t {
+		l = append(l, b.re.String()+"="+strconv.FormatFloat(b.pct, 'g', -1, 64))
+	}
+	return strings.Join(l, ",")
+}
+
+// reProcs matches the GOMAXPROCS suffix of a benchmark name, e.g. "-8".
+var reProcs = regexp.MustCompile(`-[0-9]+$`)
+
+// limit returns the threshold of the first regexp matching the benchmark name
+// or def if none does.
+//
+// The GOMAXPROCS suffix of name, if any, is ignored so the regexps match the
+// same names in the raw results, as used by -early-stop, and in the tables,
+// where it is only present when the benchmarks ran with a single GOMAXPROCS
+// other than 1.
+func (t thresholds) limit(name string, def float64) float64 {
+	name = reProcs.ReplaceAllString(name, "")
+	for _, b := range t {
+		if b.re.MatchString(name) {
+			return b.pct
+		}
+	}
+	return def
+}
+
+// percent is a flag accepting a percentage, with or without the trailing '%'.
+type percent float64
+
+func (p *percent) Set(v string) error {
+	f, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	if err != nil {
+		return err
+	}
+	*p = percent(f)
+	return nil
+}
+
+func (p *percent) String() string {
+	return strconv.FormatFloat(float64(*p), 'g', -1, 64) + "%"
+}
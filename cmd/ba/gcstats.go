@@ -0,0 +1,79 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// gcTraceRe matches the line printed for each garbage collection with
+// GODEBUG=gctrace=1, e.g. "gc 1 @0.012s 2%: 0.016+1.2+0.023 ms clock,
+// 0.13+0.20/1.1/0.47+0.19 ms cpu, 4->4->0 MB, 4 MB goal, ...". The first and
+// last clock times are the stop-the-world phases and the heap sizes are at the
+// start and the end of the marking, then live.
+var gcTraceRe = regexp.MustCompile(`^gc \d+ @\S+ \d+%: ([\d.]+)\+[\d.]+\+([\d.]+) ms clock, .* (\d+)->(\d+)->\d+ MB`)
+
+// withGCTrace returns env with gctrace=1 added to GODEBUG, keeping its other
+// settings.
+func withGCTrace(env []string) []string {
+	out := make([]string, 0, len(env)+1)
+	v := "gctrace=1"
+	for _, e := range env {
+		if strings.HasPrefix(e, "GODEBUG=") {
+			if old := e[len("GODEBUG="):]; old != "" {
+				v = old + "," + v
+			}
+			continue
+		}
+		out = append(out, e)
+	}
+	return append(out, "GODEBUG="+v)
+}
+
+// splitGCTrace separates the GODEBUG=gctrace=1 lines from the rest of the
+// stderr output of a test binary and returns them summarized as a benchfmt
+// line.
+//
+// Like with -perf, the garbage collections cover the whole process, including
+// the benchmarks calibration runs, so they are reported as a single run of the
+// pseudo benchmark "GC". The collections forced by the testing package
+// between the benchmarks are ignored.
+func splitGCTrace(stderr string) (line, rest string) {
+	cycles := 0
+	pause := 0.
+	peak := 0
+	var other []string
+	for _, l := range strings.SplitAfter(stderr, "\n") {
+		if !strings.HasPrefix(l, "gc ") {
+			other = append(other, l)
+			continue
+		}
+		m := gcTraceRe.FindStringSubmatch(l)
+		if m == nil {
+			other = append(other, l)
+			continue
+		}
+		if strings.HasSuffix(strings.TrimSpace(l), "(forced)") {
+			continue
+		}
+		cycles++
+		a, _ := strconv.ParseFloat(m[1], 64)
+		b, _ := strconv.ParseFloat(m[2], 64)
+		pause += a + b
+		for _, s := range m[3:5] {
+			if h, _ := strconv.Atoi(s); h > peak {
+				peak = h
+			}
+		}
+	}
+	// The pauses are in ms and the heap sizes in MiB. The units are already
+	// tidied since benchfmt does not tidy a zero value.
+	line = "BenchmarkGC 1 " + strconv.Itoa(cycles) + " gcs " +
+		strconv.FormatFloat(pause/1e3, 'g', -1, 64) + " gc-pause-sec " +
+		strconv.Itoa(peak<<20) + " peak-heap-B\n"
+	return line, strings.Join(other, "")
+}
@@ -0,0 +1,36 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitGCTrace(t *testing.T) {
+	stderr := "gc 1 @0.024s 4%: 0.015+3.3+0.005 ms clock, 0.015+0.29/0.89/0+0.006 ms cpu, 3->4->1 MB, 4 MB goal, 0 MB stacks, 0 MB globals, 1 P\n" +
+		"panic: boom\n" +
+		"gc 2 @0.035s 7%: 0.010+3.2+0.020 ms clock, 0.013+0.68/0.92/0+0.003 ms cpu, 6->5->2 MB, 4 MB goal, 0 MB stacks, 0 MB globals, 1 P\n" +
+		"gc 3 @0.040s 7%: 1+3.2+1 ms clock, 0.013+0.68/0.92/0+0.003 ms cpu, 9->9->2 MB, 4 MB goal, 0 MB stacks, 0 MB globals, 1 P (forced)\n" +
+		"gc 4 is not a trace"
+	line, rest := splitGCTrace(stderr)
+	if want := "BenchmarkGC 1 2 gcs 5e-05 gc-pause-sec 6291456 peak-heap-B\n"; line != want {
+		t.Fatal(line)
+	}
+	if want := "panic: boom\ngc 4 is not a trace"; rest != want {
+		t.Fatal(rest)
+	}
+}
+
+func TestWithGCTrace(t *testing.T) {
+	got := withGCTrace([]string{"A=1", "GODEBUG=madvdontneed=1"})
+	if want := []string{"A=1", "GODEBUG=madvdontneed=1,gctrace=1"}; !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+	got = withGCTrace([]string{"A=1"})
+	if want := []string{"A=1", "GODEBUG=gctrace=1"}; !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
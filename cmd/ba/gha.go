@@ -0,0 +1,61 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// printGHA writes the comparisons for a GitHub Actions workflow: the text
+// tables on w for the job log, the markdown tables appended to the job
+// summary, and an annotation per statistically significant regression.
+//
+// Regressions larger than errorThreshold percent, or the benchmark's own
+// threshold in per, are reported as errors and the others as warnings.
+// errorThreshold is disabled when negative.
+func printGHA(w io.Writer, c []*comparison, errorThreshold float64, per thresholds) error {
+	if err := printComparisons(w, "text", c, false); err != nil {
+		return err
+	}
+	if p := os.Getenv("GITHUB_STEP_SUMMARY"); p != "" {
+		/* #nosec G304 */
+		f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(f, "## Benchmarks\n\n")
+		err = printComparisons(f, "markdown", c, false)
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		stderrLog.infof("GITHUB_STEP_SUMMARY is not set; skipping the job summary")
+	}
+	errs := map[string]bool{}
+	if errorThreshold >= 0 || len(per) != 0 {
+		for _, r := range regressions(c, errorThreshold, per) {
+			errs[r] = true
+		}
+	}
+	for _, r := range regressions(c, 0, nil) {
+		level := "warning"
+		if errs[r] {
+			level = "error"
+		}
+		fmt.Fprintf(w, "::%s title=Benchmark regression::%s\n", level, ghaEscape(r))
+	}
+	return nil
+}
+
+// ghaEscape escapes a workflow command message.
+func ghaEscape(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
@@ -0,0 +1,192 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// githubCommentMarker identifies the comment posted by ba so it is updated
+// on re-runs instead of posting a new one.
+const githubCommentMarker = "<!-- ba benchmark results -->"
+
+// githubClient is a minimal GitHub REST API client.
+type githubClient struct {
+	api   string
+	token string
+	// repo is "owner/name".
+	repo string
+	c    *http.Client
+}
+
+// newGitHubClient returns a client using the token in $GITHUB_TOKEN for the
+// repository in $GITHUB_REPOSITORY, or else the one of the origin remote.
+func newGitHubClient() (*githubClient, error) {
+	g := &githubClient{
+		api:   os.Getenv("GITHUB_API_URL"),
+		token: os.Getenv("GITHUB_TOKEN"),
+		repo:  os.Getenv("GITHUB_REPOSITORY"),
+		c:     http.DefaultClient,
+	}
+	if g.token == "" {
+		return nil, errors.New("-github-comment requires $GITHUB_TOKEN")
+	}
+	if g.api == "" {
+		g.api = "https://api.github.com"
+	}
+	if g.repo == "" {
+		out, err := git("remote", "get-url", "origin")
+		if err != nil {
+			return nil, errors.New(out)
+		}
+		if g.repo = githubRepo(out); g.repo == "" {
+			return nil, fmt.Errorf("origin %q is not a GitHub repository; set $GITHUB_REPOSITORY", out)
+		}
+	}
+	return g, nil
+}
+
+// reGitHubRemote matches the owner/name of https and ssh GitHub remotes.
+var reGitHubRemote = regexp.MustCompile(`github\.com[:/]([^/]+/[^/]+?)(?:\.git)?/?$`)
+
+// githubRepo returns the "owner/name" of a GitHub git remote URL, or "".
+func githubRepo(remote string) string {
+	if m := reGitHubRemote.FindStringSubmatch(remote); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// do sends a request with the JSON encoded in and decodes the response into
+// out, when not nil.
+func (g *githubClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.api+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := g.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s\n%s", method, path, resp.Status, b)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
+
+// findPR returns the number of the pull request to comment on: the one
+// that triggered the workflow if any, else the open one from branch.
+func (g *githubClient) findPR(ctx context.Context, branch string) (int, error) {
+	// refs/pull/<n>/merge in a pull_request workflow.
+	if f := strings.Split(os.Getenv("GITHUB_REF"), "/"); len(f) == 4 && f[1] == "pull" {
+		if n, err := strconv.Atoi(f[2]); err == nil {
+			return n, nil
+		}
+	}
+	if b := os.Getenv("GITHUB_HEAD_REF"); b != "" {
+		branch = b
+	}
+	owner := strings.SplitN(g.repo, "/", 2)[0]
+	var prs []struct {
+		Number int
+	}
+	q := url.Values{"head": {owner + ":" + branch}, "state": {"open"}}
+	if err := g.do(ctx, "GET", "/repos/"+g.repo+"/pulls?"+q.Encode(), nil, &prs); err != nil {
+		return 0, err
+	}
+	if len(prs) == 0 {
+		return 0, fmt.Errorf("no open pull request found for branch %s", branch)
+	}
+	return prs[0].Number, nil
+}
+
+// githubComment is an issue comment.
+type githubComment struct {
+	ID      int64  `json:"id"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+}
+
+// upsertComment updates the comment previously posted by ba on the pull
+// request, or creates it. It returns the comment URL.
+func (g *githubClient) upsertComment(ctx context.Context, pr int, body string) (string, error) {
+	body = githubCommentMarker + "\n" + body
+	for page := 1; ; page++ {
+		var comments []*githubComment
+		p := "/repos/" + g.repo + "/issues/" + strconv.Itoa(pr) + "/comments?per_page=100&page=" + strconv.Itoa(page)
+		if err := g.do(ctx, "GET", p, nil, &comments); err != nil {
+			return "", err
+		}
+		for _, c := range comments {
+			if strings.HasPrefix(c.Body, githubCommentMarker) {
+				out := &githubComment{}
+				err := g.do(ctx, "PATCH", "/repos/"+g.repo+"/issues/comments/"+strconv.FormatInt(c.ID, 10), map[string]string{"body": body}, out)
+				return out.HTMLURL, err
+			}
+		}
+		if len(comments) < 100 {
+			break
+		}
+	}
+	out := &githubComment{}
+	err := g.do(ctx, "POST", "/repos/"+g.repo+"/issues/"+strconv.Itoa(pr)+"/comments", map[string]string{"body": body}, out)
+	return out.HTMLURL, err
+}
+
+// postGitHubComment posts the comparisons as a comment on the pull request
+// associated with branch.
+func postGitHubComment(ctx context.Context, c []*comparison, branch string) error {
+	g, err := newGitHubClient()
+	if err != nil {
+		return err
+	}
+	pr, err := g.findPR(ctx, branch)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("## Benchmarks\n\n")
+	if err = printComparisons(&b, "markdown", c, false); err != nil {
+		return err
+	}
+	u, err := g.upsertComment(ctx, pr, b.String())
+	if err != nil {
+		return err
+	}
+	stderrLog.infof("posted %s", u)
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubRepo(t *testing.T) {
+	for _, v := range []string{
+		"https://github.com/maruel/pat",
+		"https://github.com/maruel/pat.git",
+		"git@github.com:maruel/pat.git",
+		"ssh://git@github.com/maruel/pat/",
+	} {
+		if got := githubRepo(v); got != "maruel/pat" {
+			t.Fatalf("%q: %q", v, got)
+		}
+	}
+	if got := githubRepo("https://example.com/maruel/pat"); got != "" {
+		t.Fatal(got)
+	}
+}
+
+func TestUpsertComment(t *testing.T) {
+	var comments []*githubComment
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/issues/3/comments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			_ = json.NewEncoder(w).Encode(comments)
+		case "POST":
+			c := &githubComment{ID: int64(len(comments) + 1), HTMLURL: "new"}
+			_ = json.NewDecoder(r.Body).Decode(c)
+			comments = append(comments, c)
+			_ = json.NewEncoder(w).Encode(c)
+		}
+	})
+	mux.HandleFunc("/repos/o/r/issues/comments/2", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(comments[1])
+		comments[1].HTMLURL = "updated"
+		_ = json.NewEncoder(w).Encode(comments[1])
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	g := &githubClient{api: s.URL, token: "tok", repo: "o/r", c: s.Client()}
+	ctx := context.Background()
+
+	comments = []*githubComment{{ID: 1, Body: "LGTM"}}
+	u, err := g.upsertComment(ctx, 3, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "new" || len(comments) != 2 || comments[1].Body != githubCommentMarker+"\nv1" {
+		t.Fatal(u, comments)
+	}
+	// Re-run updates the same comment.
+	if u, err = g.upsertComment(ctx, 3, "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if u != "updated" || len(comments) != 2 || comments[1].Body != githubCommentMarker+"\nv2" {
+		t.Fatal(u, comments)
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/perf/benchmath"
+	"golang.org/x/perf/benchunit"
+
+	// A pure Go SQLite driver, so ba stays free of cgo.
+	_ "modernc.org/sqlite"
+)
+
+// historySchema is the schema of the history database, a SQLite database with
+// one row per historyEntry keyed by commit, benchmark and machine. Recording
+// the same commit again adds rows, whose samples are pooled.
+const historySchema = `
+CREATE TABLE IF NOT EXISTS results (
+	sha1 TEXT NOT NULL,
+	name TEXT NOT NULL,
+	machine TEXT NOT NULL,
+	pkg TEXT NOT NULL,
+	unit TEXT NOT NULL,
+	time TEXT NOT NULL,
+	vals TEXT NOT NULL,
+	labels TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS results_key ON results (machine, name, sha1);
+`
+
+// historyEntry is the samples of one benchmark metric measured at a commit on
+// a machine.
+type historyEntry struct {
+	SHA1    string
+	Machine string
+	Time    time.Time
+	Pkg     string `json:",omitempty"`
+	Name    string
+	Unit    string
+	Values  []float64
+	// Labels are the experiment labels specified with -label.
+	Labels map[string]string `json:",omitempty"`
+}
+
+// defaultHistoryPath returns the default path of the history database.
+func defaultHistoryPath() string {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		d = os.TempDir()
+	}
+	return filepath.Join(d, "ba", "history.db")
+}
+
+// machineFingerprint identifies the current machine, so results from
+// different hardware are not mixed.
+func machineFingerprint() string {
+	h, _ := os.Hostname()
+	v := strings.Join([]string{h, runtime.GOOS, runtime.GOARCH, strconv.Itoa(runtime.NumCPU()), cpuModel()}, "\x00")
+	d := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(d[:6])
+}
+
+// cpuModel returns the CPU model name, if known.
+func cpuModel() string {
+	b, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(l, "model name") {
+			if i := strings.IndexByte(l, ':'); i != -1 {
+				return strings.TrimSpace(l[i+1:])
+			}
+		}
+	}
+	return ""
+}
+
+// openHistory opens the history database at path, creating it if needed.
+func openHistory(path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = db.Exec(historySchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
+
+// recordHistory appends the results of the session's sides measured at a
+// commit with the default configuration to the history database. It returns
+// the number of entries added.
+func recordHistory(path string, s *session) (int, error) {
+	machine := machineFingerprint()
+	var labels map[string]string
+	for _, l := range s.Labels {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		f := strings.SplitN(l, ": ", 2)
+		labels[f[0]] = f[1]
+	}
+	var entries []*historyEntry
+	for _, ss := range s.Sides {
+		if ss.SHA1 == "" || ss.Bin != "" || len(ss.Env) != 0 {
+			continue
+		}
+		smp, err := parseSamples(ss.Output)
+		if err != nil {
+			return 0, err
+		}
+		for _, k := range smp.keys {
+			f := strings.SplitN(k, " ", 3)
+			entries = append(entries, &historyEntry{
+				SHA1:    ss.SHA1,
+				Machine: machine,
+				Time:    s.Start,
+				Pkg:     f[0],
+				Name:    f[1],
+				Unit:    f[2],
+				Values:  smp.values[k],
+				Labels:  labels,
+			})
+		}
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	db, err := openHistory(path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	for _, h := range entries {
+		vals, _ := json.Marshal(h.Values)
+		l, _ := json.Marshal(h.Labels)
+		if _, err = tx.Exec("INSERT INTO results (sha1, name, machine, pkg, unit, time, vals, labels) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			h.SHA1, h.Name, h.Machine, h.Pkg, h.Unit, h.Time.Format(time.RFC3339Nano), string(vals), string(l)); err != nil {
+			_ = tx.Rollback()
+			return 0, err
+		}
+	}
+	return len(entries), tx.Commit()
+}
+
+// readHistory reads the entries of the history database matching the
+// benchmark name, with or without the "Benchmark" prefix, measured on
+// machine with all the labels, in the order they were recorded. An empty name
+// matches all the benchmarks and an empty machine all the machines.
+func readHistory(path, name, machine string, labels map[string]string) ([]*historyEntry, error) {
+	name = strings.TrimPrefix(name, "Benchmark")
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no history recorded in %s; use -record first", path)
+	}
+	db, err := openHistory(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT sha1, name, machine, pkg, unit, time, vals, labels FROM results WHERE (? = '' OR machine = ?) AND (? = '' OR name = ?) ORDER BY rowid", machine, machine, name, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer rows.Close()
+	var out []*historyEntry
+	for rows.Next() {
+		h := &historyEntry{}
+		var t, vals, l string
+		if err = rows.Scan(&h.SHA1, &h.Name, &h.Machine, &h.Pkg, &h.Unit, &t, &vals, &l); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if h.Time, err = time.Parse(time.RFC3339Nano, t); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err = json.Unmarshal([]byte(vals), &h.Values); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err = json.Unmarshal([]byte(l), &h.Labels); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if h.hasLabels(labels) {
+			out = append(out, h)
+		}
+	}
+	return out, rows.Err()
+}
+
+// commitInfo describes a commit of the history.
+type commitInfo struct {
+	sha1    string
+	short   string
+	subject string
+}
+
+// logCommits returns the last commits of the first parent history of rev,
+// oldest first.
+func logCommits(rev string, last int) ([]commitInfo, error) {
+	out, err := git("log", "--first-parent", "-n", strconv.Itoa(last), "--format=%H %h %s", rev)
+	if err != nil {
+		return nil, errors.New(out)
+	}
+	lines := strings.Split(out, "\n")
+	commits := make([]commitInfo, 0, len(lines))
+	for i := len(lines) - 1; i >= 0; i-- {
+		if f := strings.SplitN(lines[i], " ", 3); len(f) == 3 {
+			commits = append(commits, commitInfo{sha1: f[0], short: f[1], subject: f[2]})
+		}
+	}
+	return commits, nil
+}
+
+// printHistory prints the metrics of the benchmark name over the last
+// commits of the first parent history of rev, oldest first. Only the commits
+// with recorded results with all the labels are printed.
+func printHistory(w io.Writer, path, name, rev string, last int, labels map[string]string) error {
+	entries, err := readHistory(path, name, machineFingerprint(), labels)
+	if err != nil {
+		return err
+	}
+	commits, err := logCommits(rev, last)
+	if err != nil {
+		return err
+	}
+	if !writeHistory(w, name, entries, commits) {
+		return fmt.Errorf("no history for %s in the last %d commits on this machine", name, last)
+	}
+	return nil
+}
+
+// writeHistory writes the metrics of the entries of the benchmark name over
+// the commits, per package and unit. It returns false if none of the commits
+// has results.
+func writeHistory(w io.Writer, name string, entries []*historyEntry, commits []commitInfo) bool {
+	// Group the samples per package and unit, since benchmarks of the same
+	// name in different packages are unrelated, then per commit.
+	type key struct{ pkg, unit string }
+	var keys []key
+	values := map[key]map[string][]float64{}
+	for _, h := range entries {
+		k := key{h.Pkg, h.Unit}
+		m := values[k]
+		if m == nil {
+			m = map[string][]float64{}
+			values[k] = m
+			keys = append(keys, k)
+		}
+		m[h.SHA1] = append(m[h.SHA1], h.Values...)
+	}
+	found := false
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s %s:\n", strings.TrimSpace(k.pkg+" "+strings.TrimPrefix(name, "Benchmark")), k.unit)
+		cls := benchunit.ClassOf(k.unit)
+		for _, c := range commits {
+			v := values[k][c.sha1]
+			if len(v) == 0 {
+				continue
+			}
+			found = true
+			sum := benchmath.AssumeNothing.Summary(benchmath.NewSample(v, &benchmath.DefaultThresholds), 0.95)
+			fmt.Fprintf(w, "  %s %10s %-6s n=%-3d %s\n", c.short, benchunit.Scale(sum.Center, cls), sum.PctRangeString(), len(v), c.subject)
+		}
+	}
+	return found
+}
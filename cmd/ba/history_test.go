@@ -0,0 +1,77 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "ba", "history.db")
+	start := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := &session{
+		Sides: []*sessionSide{
+			{Name: "HEAD~1", SHA1: "aaaa", Output: "pkg: foo\nBenchmarkFoo 1 10 ns/op\nBenchmarkFoo 1 12 ns/op\nBenchmarkBar 1 1 ns/op\n"},
+			// Not the default configuration, skipped.
+			{Name: "GOGC=off", SHA1: "bbbb", Env: []string{"GOGC=off"}, Output: "BenchmarkFoo 1 5 ns/op\n"},
+			{Name: "HEAD", SHA1: "bbbb", Output: "pkg: foo\nBenchmarkFoo 1 9 ns/op\n"},
+		},
+		Start: start,
+	}
+	for i := 0; i < 2; i++ {
+		n, err := recordHistory(p, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 3 {
+			t.Fatal(n)
+		}
+	}
+	got, err := readHistory(p, "BenchmarkFoo", machineFingerprint(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Computed at runtime to match benchfmt's rounding.
+	ns := 1e-9
+	a := &historyEntry{SHA1: "aaaa", Machine: machineFingerprint(), Time: start, Pkg: "foo", Name: "Foo", Unit: "sec/op", Values: []float64{10 * ns, 12 * ns}}
+	b := &historyEntry{SHA1: "bbbb", Machine: machineFingerprint(), Time: start, Pkg: "foo", Name: "Foo", Unit: "sec/op", Values: []float64{9 * ns}}
+	if want := []*historyEntry{a, b, a, b}; !reflect.DeepEqual(want, got) {
+		t.Fatalf("%#v", got)
+	}
+	if got, err = readHistory(p, "Foo", "other", nil); err != nil || len(got) != 0 {
+		t.Fatal(got, err)
+	}
+	if got, err = readHistory(p, "", "", nil); err != nil || len(got) != 6 {
+		t.Fatal(got, err)
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	commits := []commitInfo{{"aaaa", "a", "first"}, {"bbbb", "b", "second"}}
+	entries := []*historyEntry{
+		{SHA1: "aaaa", Pkg: "x", Name: "Foo", Unit: "sec/op", Values: []float64{1e-8}},
+		{SHA1: "aaaa", Pkg: "y", Name: "Foo", Unit: "sec/op", Values: []float64{1e-6}},
+		{SHA1: "bbbb", Pkg: "x", Name: "Foo", Unit: "sec/op", Values: []float64{2e-8}},
+	}
+	var buf bytes.Buffer
+	if !writeHistory(&buf, "BenchmarkFoo", entries, commits) {
+		t.Fatal("expected history")
+	}
+	want := "x Foo sec/op:\n" +
+		"  a     10.00n ∞      n=1   first\n" +
+		"  b     20.00n ∞      n=1   second\n" +
+		"y Foo sec/op:\n" +
+		"  a     1.000µ ∞      n=1   first\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("%q", got)
+	}
+	if writeHistory(&buf, "BenchmarkFoo", entries, []commitInfo{{"cccc", "c", "third"}}) {
+		t.Fatal("expected no history")
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/maruel/pat/benchcmp"
+	"golang.org/x/perf/benchunit"
+)
+
+// Box plot geometry, in pixels.
+const (
+	plotWidth  = 320
+	plotLabel  = 80
+	plotBoxH   = 14
+	plotMargin = 4
+)
+
+// htmlComparison is a comparison as rendered by htmlReport.
+type htmlComparison struct {
+	Title  string
+	Tables []*htmlTable
+}
+
+type htmlTable struct {
+	Title  string
+	Header []string
+	Rows   []*htmlRow
+	Notes  []string
+}
+
+type htmlRow struct {
+	// Class is better, worse or same, to color the row.
+	Class string
+	Cells []string
+	Plot  template.HTML
+}
+
+var htmlTmpl = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ba report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 0.5em; }
+th, td { padding: 2px 8px; text-align: right; white-space: nowrap; }
+th:first-child, td:first-child, td:last-child { text-align: left; }
+tr:nth-child(even) { background: #f4f4f4; }
+tr.better { color: #080; }
+tr.worse { color: #c00; }
+tr.same { color: #888; }
+.notes { font-size: smaller; color: #555; }
+svg text { font-size: 10px; fill: #333; }
+</style>
+</head>
+<body>
+{{- range .}}
+{{- if .Title}}
+<h2>{{.Title}}</h2>
+{{- end}}
+{{- range .Tables}}
+<h3>{{.Title}}</h3>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}<th>distribution</th></tr>
+{{- range .Rows}}
+<tr class="{{.Class}}">{{range .Cells}}<td>{{.}}</td>{{end}}<td>{{.Plot}}</td></tr>
+{{- end}}
+</table>
+{{- if .Notes}}
+<div class="notes">{{range .Notes}}{{.}}<br>{{end}}</div>
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlReport writes the comparisons as a self-contained HTML page: the
+// benchstat tables with a box plot of the values of each side next to each
+// benchmark.
+func htmlReport(w io.Writer, c []*comparison) error {
+	var data []*htmlComparison
+	for _, cmp := range c {
+		hc := &htmlComparison{}
+		if len(c) > 1 {
+			hc.Title = cmp.old + " vs " + cmp.new
+		}
+		for _, t := range cmp.tables {
+			hc.Tables = append(hc.Tables, newHTMLTable(t))
+		}
+		data = append(data, hc)
+	}
+	return htmlTmpl.Execute(w, data)
+}
+
+func newHTMLTable(t *table) *htmlTable {
+	ht := &htmlTable{Title: t.Unit, Header: []string{"name"}}
+	if t.Procs != 0 {
+		ht.Title += fmt.Sprintf(" (GOMAXPROCS=%d)", t.Procs)
+	}
+	for _, c := range t.Configs {
+		ht.Header = append(ht.Header, c)
+	}
+	if t.Delta {
+		ht.Header = append(ht.Header, "delta")
+	}
+	ht.Header = append(ht.Header, "")
+	markers, notes := benchcmp.Footnotes(t)
+	ht.Notes = notes
+	for j, r := range t.Rows {
+		hr := &htmlRow{Cells: []string{r.Benchmark}, Plot: boxPlot(t, r)}
+		switch {
+		case r.Change > 0:
+			hr.Class = "better"
+		case r.Change < 0:
+			hr.Class = "worse"
+		case t.Delta && r.Delta == "~":
+			hr.Class = "same"
+		}
+		for _, c := range r.Cells {
+			if c == nil {
+				hr.Cells = append(hr.Cells, "")
+			} else {
+				hr.Cells = append(hr.Cells, c.Format(t.Unit))
+			}
+		}
+		if t.Delta {
+			hr.Cells = append(hr.Cells, r.Delta)
+		}
+		note := r.Note
+		if note != "" {
+			note = "(" + note + ")"
+		}
+		hr.Cells = append(hr.Cells, strings.TrimSpace(note+" "+markers[j]))
+		ht.Rows = append(ht.Rows, hr)
+	}
+	return ht
+}
+
+// boxPlot returns an SVG box plot of the values of each side of the row, on a
+// shared scale. It is empty for rows without values, like geomean.
+func boxPlot(t *table, r *row) template.HTML {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, c := range r.Cells {
+		if c == nil || c.Sample == nil {
+			continue
+		}
+		for _, v := range c.Sample.Values {
+			lo = math.Min(lo, v)
+			hi = math.Max(hi, v)
+		}
+	}
+	if math.IsInf(lo, 0) {
+		return ""
+	}
+	if hi == lo {
+		hi, lo = hi+math.Abs(hi)*0.01+1e-12, lo-math.Abs(lo)*0.01-1e-12
+	}
+	span := float64(plotWidth - plotLabel - 2*plotMargin)
+	x := func(v float64) float64 {
+		return float64(plotLabel+plotMargin) + (v-lo)/(hi-lo)*span
+	}
+	cls := benchunit.ClassOf(t.Unit)
+	rowH := plotBoxH + plotMargin
+	height := len(r.Cells)*rowH + 12
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`, plotWidth, height)
+	for i, c := range r.Cells {
+		if c == nil || c.Sample == nil {
+			continue
+		}
+		v := c.Sample.Values
+		y := i*rowH + plotMargin/2
+		mid := float64(y) + plotBoxH/2
+		q1, med, q3 := quantile(v, 0.25), quantile(v, 0.5), quantile(v, 0.75)
+		fill := "#9cf"
+		if i == 0 {
+			fill = "#ccc"
+		}
+		fmt.Fprintf(&b, `<text x="0" y="%.1f">%s</text>`, mid+3, html.EscapeString(ellipsis(t.Configs[i], 12)))
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#555"/>`, x(v[0]), mid, x(v[len(v)-1]), mid)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" stroke="#555"/>`, x(q1), y, math.Max(x(q3)-x(q1), 1), plotBoxH, fill)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#000" stroke-width="2"/>`, x(med), y, x(med), y+plotBoxH)
+		for _, p := range v {
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="1.5" fill="#333"/>`, x(p), mid)
+		}
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`, plotLabel+plotMargin, height-1, html.EscapeString(benchunit.Scale(lo, cls)))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`, plotWidth-plotMargin, height-1, html.EscapeString(benchunit.Scale(hi, cls)))
+	b.WriteString(`</svg>`)
+	/* #nosec G203 */
+	return template.HTML(b.String())
+}
+
+// quantile returns the q quantile of the sorted values, interpolating
+// linearly between the closest ranks.
+func quantile(v []float64, q float64) float64 {
+	p := q * float64(len(v)-1)
+	i := int(p)
+	if i+1 >= len(v) {
+		return v[len(v)-1]
+	}
+	return v[i] + (v[i+1]-v[i])*(p-float64(i))
+}
+
+// ellipsis shortens s to n runes.
+func ellipsis(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
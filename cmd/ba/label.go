@@ -0,0 +1,77 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// labelFlag is the experiment labels specified with -label as key=value, in
+// order.
+type labelFlag []string
+
+func (l *labelFlag) Set(v string) error {
+	i := strings.IndexByte(v, '=')
+	if i <= 0 || i == len(v)-1 {
+		return fmt.Errorf("expected key=value, got %q", v)
+	}
+	k := v[:i]
+	// benchfmt configuration keys start with a lower case letter and contain
+	// no space.
+	if r, _ := utf8.DecodeRuneInString(k); !unicode.IsLower(r) || strings.IndexFunc(k, unicode.IsSpace) != -1 || strings.Contains(k, ":") {
+		return fmt.Errorf("invalid label key %q: it must start with a lower case letter and contain no space or colon", k)
+	}
+	// go test writes goos, goarch, pkg and cpu, and ba the machine and runtime
+	// settings, which would override the label.
+	if contains(machineKeys, k) || contains(runtimeVars, strings.ToUpper(k)) || k == "race" || k == "goos" || k == "goarch" || k == "pkg" {
+		return fmt.Errorf("label key %q is reserved for the configuration written by ba and go test", k)
+	}
+	if _, ok := l.labels()[k]; ok {
+		return fmt.Errorf("label %q specified twice", k)
+	}
+	*l = append(*l, v)
+	return nil
+}
+
+func (l *labelFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+// labels returns the labels by key, nil if there are none.
+func (l labelFlag) labels() map[string]string {
+	if len(l) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(l))
+	for _, v := range l {
+		i := strings.IndexByte(v, '=')
+		m[v[:i]] = v[i+1:]
+	}
+	return m
+}
+
+// config returns the labels as benchfmt configuration lines, e.g.
+// "patchset: 3".
+func (l labelFlag) config() []string {
+	out := make([]string, 0, len(l))
+	for _, v := range l {
+		i := strings.IndexByte(v, '=')
+		out = append(out, v[:i]+": "+strings.TrimSpace(v[i+1:]))
+	}
+	return out
+}
+
+// hasLabels returns true if the entry was recorded with all the labels.
+func (h *historyEntry) hasLabels(labels map[string]string) bool {
+	for k, v := range labels {
+		if h.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLabelFlag(t *testing.T) {
+	var l labelFlag
+	for _, v := range []string{"patchset=3", "host=ci 5950x", "url=a=b"} {
+		if err := l.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"patchset: 3", "host: ci 5950x", "url: a=b"}; !reflect.DeepEqual(want, l.config()) {
+		t.Fatalf("%q", l.config())
+	}
+	if want := map[string]string{"patchset": "3", "host": "ci 5950x", "url": "a=b"}; !reflect.DeepEqual(want, l.labels()) {
+		t.Fatalf("%q", l.labels())
+	}
+	for _, v := range []string{"", "patchset", "=3", "patchset=", "Patchset=3", "patch set=3", "a:b=3", "cpu=5950x", "goos=linux", "gogc=off", "race=on", "patchset=4"} {
+		if err := l.Set(v); err == nil {
+			t.Fatal(v)
+		}
+	}
+	if (labelFlag{}).labels() != nil {
+		t.Fatal("expected nil")
+	}
+}
+
+func TestHistoryLabels(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "history.db")
+	for _, l := range [][]string{nil, {"patchset: 3"}, {"patchset: 4", "host: ci"}} {
+		s := &session{Sides: []*sessionSide{{Name: "HEAD", SHA1: "aaaa", Output: "BenchmarkFoo 1 10 ns/op\n"}}, Labels: l}
+		if _, err := recordHistory(p, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := []struct {
+		labels map[string]string
+		want   int
+	}{
+		{nil, 3},
+		{map[string]string{"patchset": "3"}, 1},
+		{map[string]string{"patchset": "4", "host": "ci"}, 1},
+		{map[string]string{"host": "other"}, 0},
+	}
+	for i, l := range data {
+		got, err := readHistory(p, "Foo", machineFingerprint(), l.labels)
+		if err != nil || len(got) != l.want {
+			t.Fatalf("#%d: %d %v", i, len(got), err)
+		}
+	}
+}
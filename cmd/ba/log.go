@@ -0,0 +1,91 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// logLevel is how much is printed on stderr while running.
+type logLevel int
+
+const (
+	// levelQuiet only prints the errors, as specified by -q.
+	levelQuiet logLevel = iota
+	// levelInfo also prints the status line, the progress messages and the
+	// warnings. It is the default.
+	levelInfo
+	// levelVerbose also prints each command run and the output of the
+	// benchmark processes, as specified by -v.
+	levelVerbose
+)
+
+// logger prints the diagnostics on stderr filtered by level, so that stdout
+// only contains the results.
+//
+// The status line, when shown, is erased while printing.
+type logger struct {
+	mu       sync.Mutex
+	w        io.Writer
+	level    logLevel
+	progress *progress
+}
+
+// stderrLog is the logger used throughout ba. Its level is set by -q and -v.
+var stderrLog = &logger{w: os.Stderr, level: levelInfo}
+
+// enabled returns true if messages of level l are printed.
+func (l *logger) enabled(level logLevel) bool {
+	return l.level >= level
+}
+
+// setProgress sets the status line to erase while printing. p may be nil.
+func (l *logger) setProgress(p *progress) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.progress = p
+}
+
+func (l *logger) printf(level logLevel, prefix, format string, a ...interface{}) {
+	if !l.enabled(level) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.progress.hide()
+	fmt.Fprintf(l.w, "%s%s\n", prefix, fmt.Sprintf(format, a...))
+	l.progress.show()
+}
+
+// errorf prints an error that does not stop ba, at every level.
+func (l *logger) errorf(format string, a ...interface{}) {
+	l.printf(levelQuiet, "ba: ", format, a...)
+}
+
+// warnf prints something that may affect the results.
+func (l *logger) warnf(format string, a ...interface{}) {
+	l.printf(levelInfo, "WARNING: ", format, a...)
+}
+
+// infof prints what ba is doing.
+func (l *logger) infof(format string, a ...interface{}) {
+	l.printf(levelInfo, "", format, a...)
+}
+
+// verbosef prints details only useful to debug a run, e.g. the commands.
+func (l *logger) verbosef(format string, a ...interface{}) {
+	l.printf(levelVerbose, "", format, a...)
+}
+
+// Write implements io.Writer to pass through the output of a process with
+// -v.
+func (l *logger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
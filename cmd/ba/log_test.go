@@ -0,0 +1,32 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoggerLevels(t *testing.T) {
+	data := []struct {
+		level logLevel
+		want  string
+	}{
+		{levelQuiet, "ba: e\n"},
+		{levelInfo, "ba: e\nWARNING: w\ni\n"},
+		{levelVerbose, "ba: e\nWARNING: w\ni\nv\n"},
+	}
+	for i, l := range data {
+		var b strings.Builder
+		lg := &logger{w: &b, level: l.level}
+		lg.errorf("%s", "e")
+		lg.warnf("w")
+		lg.infof("i")
+		lg.verbosef("v")
+		if got := b.String(); got != l.want {
+			t.Fatalf("#%d: %q", i, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// machineConfig returns the benchfmt configuration lines describing the
+// machine and toolchain the benchmarks run on, e.g. "cores: 8", so results
+// stored or shared later can be interpreted.
+func machineConfig() []string {
+	var out []string
+	add := func(k, v string) {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, k+": "+v)
+		}
+	}
+	add("cpu", cpuModel())
+	add("cores", strconv.Itoa(runtime.NumCPU()))
+	// cpu0 is representative, envIssues warns about the others.
+	if b, err := os.ReadFile("/sys/devices/system/cpu/cpu0/cpufreq/scaling_governor"); err == nil {
+		add("governor", string(b))
+	}
+	add("kernel", kernelVersion())
+	if b, err := exec.Command("go", "env", "GOVERSION", "GOAMD64").Output(); err == nil {
+		l := strings.Split(string(b), "\n")
+		add("go", l[0])
+		if runtime.GOARCH == "amd64" && len(l) > 1 {
+			add("goamd64", l[1])
+		}
+	}
+	return out
+}
+
+// kernelVersion returns the OS and kernel version, e.g. "Linux 6.8.0".
+func kernelVersion() string {
+	if b, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		return "Linux " + strings.TrimSpace(string(b))
+	}
+	if runtime.GOOS == "windows" {
+		if b, err := exec.Command("cmd", "/c", "ver").Output(); err == nil {
+			return string(b)
+		}
+	} else if b, err := exec.Command("uname", "-sr").Output(); err == nil {
+		return string(b)
+	}
+	return runtime.GOOS
+}
+
+// withMachine prepends the configuration lines to the benchmark output, unless
+// already present, e.g. when resuming.
+func withMachine(config []string, out string) string {
+	if len(config) == 0 || out == "" {
+		return out
+	}
+	h := strings.Join(config, "\n") + "\n"
+	if strings.Contains(out, h) {
+		return out
+	}
+	return h + out
+}
+
+// machineKeys are the keys of the configuration lines written by
+// machineConfig.
+var machineKeys = []string{"cpu", "cores", "governor", "kernel", "go", "goamd64"}
+
+// outputMachine returns the configuration lines describing the machine at the
+// top of a benchmark output written by withMachine.
+func outputMachine(out string) []string {
+	var m []string
+	for _, l := range strings.Split(out, "\n") {
+		if i := strings.IndexByte(l, ':'); i <= 0 || !contains(machineKeys, l[:i]) {
+			break
+		}
+		m = append(m, l)
+	}
+	return m
+}
+
+// machineDiff returns the configuration lines that differ between a and b,
+// formatted as "key: a != b".
+func machineDiff(a, b []string) []string {
+	m := map[string]string{}
+	var keys []string
+	for _, l := range a {
+		k := strings.SplitN(l, ":", 2)[0]
+		m[k] = l
+		keys = append(keys, k)
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, l := range b {
+		k := strings.SplitN(l, ":", 2)[0]
+		seen[k] = true
+		if x, ok := m[k]; !ok {
+			out = append(out, fmt.Sprintf("%s: <none> !=%s", k, l[len(k)+1:]))
+		} else if x != l {
+			out = append(out, fmt.Sprintf("%s !=%s", x, l[len(k)+1:]))
+		}
+	}
+	for _, k := range keys {
+		if !seen[k] {
+			out = append(out, m[k]+" != <none>")
+		}
+	}
+	return out
+}
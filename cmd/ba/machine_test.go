@@ -0,0 +1,58 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMachineConfig(t *testing.T) {
+	for _, l := range machineConfig() {
+		if f := strings.SplitN(l, ": ", 2); len(f) != 2 || f[0] == "" || strings.ContainsAny(f[0], " \t") || f[1] == "" {
+			t.Fatalf("invalid configuration line %q", l)
+		}
+	}
+}
+
+func TestWithMachine(t *testing.T) {
+	config := []string{"cores: 8", "go: go1.22.0"}
+	want := "cores: 8\ngo: go1.22.0\nBenchmarkA 1 1 ns/op\n"
+	if got := withMachine(config, "BenchmarkA 1 1 ns/op\n"); got != want {
+		t.Fatalf("%q != %q", want, got)
+	}
+	if got := withMachine(config, want); got != want {
+		t.Fatalf("%q != %q", want, got)
+	}
+	if got := withMachine(config, ""); got != "" {
+		t.Fatalf("%q", got)
+	}
+	if got := withMachine(nil, "x\n"); got != "x\n" {
+		t.Fatalf("%q", got)
+	}
+}
+
+func TestOutputMachine(t *testing.T) {
+	out := "cpu: foo\ncores: 8\ngo: go1.22.0\ngoos: linux\npkg: example.com/x\ncpu: foo\nBenchmarkFoo 1 2 ns/op\n"
+	want := []string{"cpu: foo", "cores: 8", "go: go1.22.0"}
+	if got := outputMachine(out); !reflect.DeepEqual(want, got) {
+		t.Fatalf("%q != %q", want, got)
+	}
+	if got := outputMachine("goos: linux\nBenchmarkFoo 1 2 ns/op\n"); len(got) != 0 {
+		t.Fatal(got)
+	}
+}
+
+func TestMachineDiff(t *testing.T) {
+	if d := machineDiff([]string{"cores: 8"}, []string{"cores: 8"}); len(d) != 0 {
+		t.Fatal(d)
+	}
+	got := machineDiff([]string{"cores: 8", "governor: powersave"}, []string{"cores: 16", "go: go1.22.0"})
+	want := []string{"cores: 8 != 16", "go: <none> != go1.22.0", "governor: powersave != <none>"}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("%q != %q", want, got)
+	}
+}
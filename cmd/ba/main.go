@@ -6,325 +6,2180 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"math"
+	"math/rand"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	// TODO(maruel): Figure this out.
-	"golang.org/x/perf/benchstat"
+	"github.com/maruel/pat/benchcmp"
+	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+	"golang.org/x/perf/benchunit"
 )
 
 func git(args ...string) (string, error) {
-	out, err := exec.Command("git", args...).CombinedOutput()
+	out, err := command("git", args...).CombinedOutput()
 	return strings.TrimSpace(string(out)), err
 }
 
-func runBench(ctx context.Context, pkg, bench string, benchtime time.Duration, count int) (string, error) {
+// benchOptions describes what benchmark to run on each side.
+type benchOptions struct {
+	pkg       string
+	bench     string
+	benchtime time.Duration
+	count     int
+	// benchmem reports memory allocation statistics.
+	benchmem bool
+	// cpu is the comma separated list of GOMAXPROCS values to run each
+	// benchmark with. Defaults to 1.
+	cpu string
+	// inplace checks out the refs in the current checkout instead of using
+	// temporary git worktrees.
+	inplace bool
+	// autostash allows uncommitted changes. They are stashed while another
+	// ref is checked out in the current checkout.
+	autostash bool
+	// interleave alternates the sides at every iteration instead of at every
+	// series.
+	interleave bool
+	// autowarm discards the leading series that are significantly slower than
+	// the following ones.
+	autowarm bool
+	// shuffle, when set, randomizes the order of the sides in each series and
+	// of the benchmarks in each run.
+	shuffle *rand.Rand
+	// stable, when positive, runs more series until the 95% confidence
+	// interval of every benchmark is within ±stable percent, up to maxtime.
+	stable  float64
+	maxtime time.Duration
+	// throttle is what to do with a series collected while the machine was
+	// thermally throttled: warn, discard or rerun.
+	throttle string
+	// retries is the number of times to retry building the test binaries or a
+	// series that failed, e.g. due to a crash or an OOM.
+	retries int
+	// pin is the CPUs to run the benchmark processes on.
+	pin []int
+	// noaslr runs the benchmark processes without address space layout
+	// randomization and with their environment padded to a fixed size.
+	noaslr bool
+	// nice is the niceness of the benchmark processes and fifo runs them with
+	// the SCHED_FIFO real-time policy.
+	nice int
+	fifo bool
+	// adaptive probes each benchmark first to choose its own benchtime and
+	// count. plans is the result for each test binary path.
+	adaptive bool
+	plans    map[string][]*benchPlan
+	// timebudget, when set, is the total time to finish the series in. count
+	// is reduced and the slowest benchmarks are skipped to fit in.
+	timebudget time.Duration
+	// wrap is the default command prefix for sides that do not specify one.
+	wrap []string
+	// cgroup is the command prefix to run each benchmark process in its own
+	// cgroup, in front of the side's wrap.
+	cgroup []string
+	// qemu is the command prefix to run the cross compiled test binaries under
+	// qemu-user, after the side's wrap.
+	qemu []string
+	// binDir is where the test binaries are compiled. binaries is the
+	// compiled test binaries for each side's buildKey().
+	binDir   string
+	binaries map[string][]*testBinary
+	// buildEnv is added to the environment of go test -c, e.g. to cross
+	// compile.
+	buildEnv []string
+	// resume is the directory to save the progress into after each series,
+	// and to resume from if it already contains one. checkpoint does the
+	// saving and the streaming.
+	resume     string
+	checkpoint func(stats []string, series int) error
+	// stream, when set, is called with the session after each series, as
+	// specified by -stream.
+	stream func(s *session) error
+	// allowMissing continues when the sides did not run the same benchmarks
+	// after the first series, instead of failing.
+	allowMissing bool
+	// labels are the experiment labels added to the results.
+	labels labelFlag
+	// earlyStop, when set, stops running series once their outcome is
+	// decided, as specified by -early-stop.
+	earlyStop *earlyStop
+	// race is set when the test binaries are built with the race detector.
+	race bool
+	// runtime is the GOGC, GOMEMLIMIT and GODEBUG environment variables of
+	// the benchmark processes, added before each side's env.
+	runtime []string
+	// allowMixed allows -resume to continue a run recorded on a different
+	// machine.
+	allowMixed bool
+	// profiles are the profiles to record on each side after the series.
+	profiles []*profileKind
+	// trace is the benchmark to record an execution trace of on each side
+	// after the series.
+	trace string
+	// nm records the size of each package linked in the test binaries.
+	nm bool
+	// buildTime also measures the time to build the packages with an empty
+	// build cache, as the GoBuild benchmark.
+	buildTime bool
+	// perf records hardware counters of each benchmark process via perf stat.
+	perf bool
+	// spin keeps the CPUs busy before each series until their frequency is
+	// stable.
+	spin bool
+	// gcStats records the garbage collections of each test binary via
+	// GODEBUG=gctrace=1.
+	gcStats bool
+	// resctrl enables memory bandwidth and LLC occupancy monitoring.
+	resctrl bool
+	// container, when set, runs the test binaries in a container.
+	container *container
+	// remote, when set, runs the test binaries on another machine. When
+	// there are multiple remotes, the series are sharded across them.
+	remote  *remote
+	remotes []*remote
+
+	// buildFlags are added to go test -c for all sides, e.g. -tags, before
+	// the side's own.
+	buildFlags []string
+	// testFlags are added to the test binary arguments, e.g. -test.short.
+	testFlags []string
+	// isolateCache builds the test binaries of each side with its own empty
+	// GOCACHE.
+	isolateCache bool
+	// only, when set, restricts the packages benchmarked to these import
+	// paths, e.g. with -changed-only.
+	only map[string]bool
+	// verify is the regexp of the unit tests to run on each side before
+	// benchmarking.
+	verify string
+	// progress is the status line, while the series run.
+	progress *progress
+
+	// commands is the list of commands that were run, in order.
+	commands []string
+}
+
+// pkgPattern returns the package pattern to benchmark.
+func (o *benchOptions) pkgPattern() string {
+	if o.pkg == "" {
+		return "."
+	}
+	return o.pkg
+}
+
+// logCmd prints the command about to be run with -v and records it.
+func (o *benchOptions) logCmd(format string, a ...interface{}) {
+	c := fmt.Sprintf(format, a...)
+	stderrLog.verbosef("%s", c)
+	o.commands = append(o.commands, c)
+}
+
+// side is one of the configurations being benchmarked.
+type side struct {
+	// name is the label used in the results.
+	name string
+	// ref is the git commit to check out to run this side. When empty, the
+	// current checkout is used as is.
+	ref string
+	// dir is the directory to run the benchmark in, e.g. a git worktree of
+	// ref. When set, ref is not checked out.
+	dir string
+	// cmd, when set, is run instead of go test. It must print benchmark results
+	// in the benchfmt format on stdout.
+	cmd string
+	// bin, when set, is a prebuilt test binary to run instead of building the
+	// package.
+	bin string
+	// goTool, when set, is the go command to build the test binaries with
+	// instead of the one in PATH.
+	goTool string
+	// buildFlags are added to go test -c, e.g. -pgo=off.
+	buildFlags []string
+	// env is added to the environment of the benchmark process.
+	env []string
+	// thp, when "on" or "off", enables or disables transparent huge pages for
+	// the benchmark process instead of inheriting ba's setting.
+	thp string
+	// wrap is a command prefix to run the benchmark process under, e.g.
+	// numactl. For go test, only the test binary is wrapped. Defaults to
+	// benchOptions.wrap.
+	wrap []string
+}
+
+// wrapper returns the command prefix to run the side's benchmark process under.
+func (s *side) wrapper(o *benchOptions) []string {
+	w := o.wrap
+	if len(s.wrap) != 0 {
+		w = s.wrap
+	}
+	if len(o.cgroup) != 0 {
+		return append(o.cgroup[:len(o.cgroup):len(o.cgroup)], w...)
+	}
+	return w
+}
+
+// runBench runs the benchmark for one side on the current checkout.
+func runBench(ctx context.Context, o *benchOptions, s *side, count int) (string, error) {
+	if s.thp != "" {
+		restore, err := setTHP(s.thp == "on")
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+	if s.cmd != "" {
+		return runCustomBench(ctx, o, s, count)
+	}
+	bins, err := buildTestBinaries(ctx, o, s)
+	if err != nil {
+		return "", err
+	}
+	if o.shuffle != nil {
+		bins = append([]*testBinary(nil), bins...)
+		o.shuffle.Shuffle(len(bins), func(i, j int) { bins[i], bins[j] = bins[j], bins[i] })
+	}
+	out := ""
+	for _, b := range bins {
+		if o.plans != nil {
+			plans := o.plans[b.path]
+			if o.shuffle != nil {
+				plans = append([]*benchPlan(nil), plans...)
+				o.shuffle.Shuffle(len(plans), func(i, j int) { plans[i], plans[j] = plans[j], plans[i] })
+			}
+			for _, p := range plans {
+				if !benchSelected(o.bench, p.name) {
+					continue
+				}
+				c := count
+				if p.count < c {
+					c = p.count
+				}
+				raw, err := runTestBinary(ctx, o, s, b, p.bench, p.benchtime, c)
+				out += raw
+				if err != nil {
+					return out, err
+				}
+			}
+			continue
+		}
+		raw, err := runTestBinary(ctx, o, s, b, o.bench, o.benchtime.String(), count)
+		out += raw
+		if err != nil {
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+// runTestBinary runs the benchmarks matching bench in a compiled test binary.
+//
+// extra is appended to the test binary arguments.
+func runTestBinary(ctx context.Context, o *benchOptions, s *side, b *testBinary, bench, benchtime string, count int, extra ...string) (string, error) {
+	args := testArgs(o, bench, benchtime, count, extra...)
+	wrap := s.wrapper(o)
+	if len(o.qemu) != 0 {
+		wrap = append(wrap[:len(wrap):len(wrap)], o.qemu...)
+	}
+	if o.container != nil {
+		var writable []string
+		for _, p := range o.profiles {
+			writable = append(writable, p.dir)
+		}
+		wrap = append(o.container.wrap(o, s, b, writable), wrap...)
+	}
+	cmd := append(append(wrap[:len(wrap):len(wrap)], b.path), args...)
+	if o.remote != nil {
+		var err error
+		if cmd, err = o.remote.command(ctx, o, s, b, wrap, args); err != nil {
+			return "", err
+		}
+	}
+	o.logCmd("%s%s", s.logPrefix(b.dir, s.runEnv(o)), strings.Join(cmd, " "))
+	c := command(cmd[0], cmd[1:]...)
+	c.Dir = b.dir
+	if env := s.runEnv(o); len(env) != 0 {
+		c.Env = append(os.Environ(), env...)
+	}
+	raw, err := o.run(ctx, c, true)
+	return parseTestOutput(ctx, b.pkg, raw, err)
+}
+
+// testArgs returns the arguments of a test binary to run the benchmarks
+// matching bench.
+func testArgs(o *benchOptions, bench, benchtime string, count int, extra ...string) []string {
+	// -test.v so failures and skips can be parsed by test2json.
 	args := []string{
-		"test",
-		"-bench", bench,
-		"-benchtime", benchtime.String(),
-		"-count", strconv.Itoa(count),
-		"-run", "^$",
-		"-cpu", "1",
+		"-test.v",
+		"-test.run", "^$",
+		"-test.bench", bench,
+		"-test.benchtime", benchtime,
+		"-test.count", strconv.Itoa(count),
+		"-test.cpu", "1",
+	}
+	if o.cpu != "" {
+		args[len(args)-1] = o.cpu
 	}
-	if pkg != "" {
-		args = append(args, pkg)
+	if o.benchmem {
+		args = append(args, "-test.benchmem")
 	}
-	fmt.Fprintf(os.Stderr, "go %s\n", strings.Join(args, " "))
-	/* #nosec G204 */
-	out, err := exec.CommandContext(ctx, "go", args...).CombinedOutput()
-	return string(out), err
+	if o.shuffle != nil {
+		args = append(args, "-test.shuffle", strconv.FormatInt(o.shuffle.Int63(), 10))
+	}
+	args = append(args, o.testFlags...)
+	return append(args, extra...)
+}
+
+// runCustomBench runs a user provided command via the shell.
+//
+// The benchmark parameters are passed via environment variables so the
+// command can honor them. Only stdout is parsed, stderr is passed through.
+func runCustomBench(ctx context.Context, o *benchOptions, s *side, count int) (string, error) {
+	args := []string{"sh", "-c", s.cmd}
+	if runtime.GOOS == "windows" {
+		args = []string{"cmd.exe", "/C", s.cmd}
+	}
+	wrap := s.wrapper(o)
+	args = append(wrap[:len(wrap):len(wrap)], args...)
+	o.logCmd("%s%s", s.logPrefix(s.dir, s.runEnv(o)), strings.Join(args, " "))
+	c := command(args[0], args[1:]...)
+	c.Dir = s.dir
+	c.Env = append(os.Environ(),
+		"BA_PKG="+o.pkg,
+		"BA_BENCH="+o.bench,
+		"BA_BENCHTIME="+o.benchtime.String(),
+		"BA_COUNT="+strconv.Itoa(count),
+		"BA_BENCHMEM="+strconv.FormatBool(o.benchmem),
+	)
+	c.Env = append(c.Env, s.runEnv(o)...)
+	out, err := o.run(ctx, c, false)
+	if err != nil && ctx.Err() == nil {
+		// stderr was passed through but stdout may explain the failure too.
+		err = fmt.Errorf("%s: %w\n%s", s.cmd, err, lastLines(out, 20))
+	}
+	return out, err
+}
+
+// run runs the benchmark process and returns its output. When combined is
+// false, only stdout is returned and stderr is passed through.
+//
+// The process tree is killed when ctx is canceled.
+func (o *benchOptions) run(ctx context.Context, c *exec.Cmd, combined bool) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	var out io.Writer = &buf
+	if stderrLog.enabled(levelVerbose) {
+		out = io.MultiWriter(&buf, stderrLog)
+	}
+	c.Stdout = out
+	c.Stderr = os.Stderr
+	if combined {
+		c.Stderr = out
+	}
+	// The GC trace is written to stderr while the benchmark results are
+	// printed, so it is kept apart not to garble them.
+	var gcTrace bytes.Buffer
+	if o.gcStats && combined {
+		env := c.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		c.Env = withGCTrace(env)
+		c.Stderr = &gcTrace
+	}
+	perfOut := ""
+	if o.perf {
+		f, err := os.CreateTemp("", "ba-perf-")
+		if err != nil {
+			return "", err
+		}
+		perfOut = f.Name()
+		_ = f.Close()
+		defer os.Remove(perfOut)
+		if err = perfWrap(c, perfOut); err != nil {
+			return "", err
+		}
+	}
+	var r *resctrlGroup
+	if o.resctrl {
+		var err error
+		if r, err = newResctrlGroup(); err != nil {
+			return "", err
+		}
+	}
+	if err := o.start(c); err != nil {
+		return "", err
+	}
+	stop := killOnCancel(ctx, c)
+	if r != nil {
+		if err := r.start(c.Process.Pid); err != nil {
+			_ = killTree(c.Process)
+			_ = c.Wait()
+			stop()
+			_, _ = r.stop()
+			return "", err
+		}
+	}
+	err := c.Wait()
+	stop()
+	if r != nil {
+		// Always stop the monitoring to release the group.
+		line, err2 := r.stop()
+		if err == nil {
+			err = err2
+		}
+		buf.WriteString(line)
+	}
+	if perfOut != "" && err == nil {
+		var line string
+		line, err = readPerfStat(perfOut)
+		buf.WriteString(line)
+	}
+	if o.gcStats && combined {
+		line, rest := splitGCTrace(gcTrace.String())
+		buf.WriteString(rest)
+		if err == nil {
+			buf.WriteString(line)
+		}
+	}
+	return buf.String(), err
+}
+
+// start starts the process, pinned to o.pin if set and without ASLR with
+// o.noaslr.
+func (o *benchOptions) start(c *exec.Cmd) error {
+	if len(o.pin) == 0 && !o.noaslr && o.nice == 0 && !o.fifo {
+		return c.Start()
+	}
+	// The affinity, the personality and the priority are inherited from the
+	// thread that forks the child.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if len(o.pin) != 0 {
+		restore, err := pinThread(o.pin)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+	if o.nice != 0 || o.fifo {
+		restore, err := priorityThread(o.nice, o.fifo)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+	if o.noaslr {
+		restore, err := disableASLRThread()
+		if err != nil {
+			return err
+		}
+		defer restore()
+		env := c.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		c.Env = padEnv(c.Path, c.Args, env)
+	}
+	return c.Start()
+}
+
+// logPrefix returns the side's settings formatted as a shell like prefix, to
+// run a command in dir with the environment variables env.
+func (s *side) logPrefix(dir string, env []string) string {
+	out := ""
+	if dir != "" {
+		out = "cd " + dir + " && "
+	}
+	if s.thp != "" {
+		out += "thp=" + s.thp + " "
+	}
+	for _, e := range env {
+		out += e + " "
+	}
+	return out
 }
 
 // isPristine makes sure the tree is checked out and pristine, otherwise we
 // could loose the checkout.
 func isPristine() error {
-	diff, err := git("status", "--porcelain")
+	diff, err := repo.modified()
 	if err != nil {
 		return err
 	}
 	if diff != "" {
-		return errors.New("the tree is modified, make sure to commit all your changes before running this script")
+		return errors.New("the tree is modified, make sure to commit all your changes before running this script or use -autostash")
 	}
 	return nil
 }
 
-func getInfos(against string) (string, int, error) {
-	// Verify current and against are different commits.
-	sha1Cur, err := git("rev-parse", "HEAD")
+// getInfos returns the current branch to check back out and the number of
+// commits between against and head.
+func getInfos(against, head string) (string, int, error) {
+	// Verify head and against are different commits.
+	sha1Head, err := repo.resolve(head)
 	if err != nil {
 		return "", 0, err
 	}
-	sha1Ag, err := git("rev-parse", against)
+	sha1Ag, err := repo.resolve(against)
 	if err != nil {
 		return "", 0, err
 	}
-	if sha1Cur == sha1Ag {
+	if sha1Head == sha1Ag {
 		return "", 0, errors.New("specify -against to state against why commit to test, e.g. -against HEAD~1")
 	}
 
 	// Make sure we'll be able to check the commit back.
-	branch, err := git("rev-parse", "--abbrev-ref", "HEAD")
+	branch, err := repo.current()
 	if err != nil {
 		return "", 0, err
 	}
-	if branch == "HEAD" {
-		// We're in detached head. It's fine, just save the head.
-		branch = sha1Cur[:16]
-	}
-
-	commitsHashes, err := git("log", "--format='%h'", sha1Cur+"..."+sha1Ag)
+	commits, err := repo.count(sha1Head, sha1Ag)
 	if err != nil {
 		return "", 0, err
 	}
-	commits := strings.Count(commitsHashes, "\n") + 1
 	return branch, commits, nil
 }
 
-func warmBench(ctx context.Context, branch, against, pkg, bench string, benchtime time.Duration) error {
-	fmt.Fprintf(os.Stderr, "warming up\n")
-	if err := ctx.Err(); err != nil {
-		return err
+// inSide calls fn with the side's ref checked out if needed, then checks out
+// branch back.
+func inSide(o *benchOptions, branch string, s *side, fn func() error) error {
+	if s.ref == "" || s.dir != "" {
+		return fn()
 	}
-	if _, err := runBench(ctx, pkg, bench, benchtime, 1); err != nil {
+	stashed := false
+	if o.autostash {
+		var err error
+		if stashed, err = stash(o); err != nil {
+			return err
+		}
+	}
+	o.logCmd("%s", repo.checkoutCmd(s.ref))
+	if err := repo.checkout(s.ref); err != nil {
+		if stashed {
+			if err2 := unstash(o); err2 != nil {
+				return fmt.Errorf("%s\n%w", err, err2)
+			}
+		}
 		return err
 	}
-	fmt.Fprintf(os.Stderr, "git checkout %s\n", against)
-	out, err := git("checkout", "-q", against)
-	if err == nil {
-		_, err = runBench(ctx, pkg, bench, benchtime, 1)
-	} else {
-		err = errors.New(out)
+	err := fn()
+	o.logCmd("%s", repo.checkoutCmd(branch))
+	if err2 := repo.checkout(branch); err2 != nil {
+		if stashed {
+			return fmt.Errorf("%s\nyour uncommitted changes are in the stash; run 'git checkout %s && git stash pop --index' to restore them", err2, branch)
+		}
+		return err2
 	}
-	fmt.Fprintf(os.Stderr, "git checkout %s\n", branch)
-	if out2, err2 := git("checkout", "-q", branch); err2 != nil {
-		return errors.New(out2)
+	if stashed {
+		if err2 := unstash(o); err == nil {
+			err = err2
+		}
 	}
 	return err
 }
 
-// runBenchmarks runs benchmarks and return the go test -bench=. result for
-// (old, new) where old is `against` and new is HEAD.
-func runBenchmarks(ctx context.Context, against, pkg, bench string, benchtime time.Duration, count, series int, nowarm bool) (string, string, error) {
-	if err := isPristine(); err != nil {
-		return "", "", err
+// stash stashes the uncommitted changes, including the untracked files, if
+// there are any. It returns true if it did.
+func stash(o *benchOptions) (bool, error) {
+	if err := isPristine(); err == nil {
+		return false, nil
 	}
-	branch, commits, err := getInfos(against)
-	if err != nil {
-		return "", "", err
+	o.logCmd("git stash push --include-untracked")
+	if out, err := git("stash", "push", "-q", "--include-untracked", "-m", "ba -autostash"); err != nil {
+		return false, errors.New(out)
 	}
+	return true, nil
+}
 
-	// TODO(maruel): Make it smart, where it does series until the numbers
-	// becomes stable, and actively ignores the higher values.
-	// TODO(maruel): When a benchmark takes more than benchtime*count, reduce its
-	// count to 1. We could do this by running -benchtime=1x -json.
-	// This is particularly problematic with benchmarks lasting less than 100ns
-	// per operation as they fail to be numerically stable and deviate by ~3%.
-	if !nowarm {
-		if err = warmBench(ctx, branch, against, pkg, bench, benchtime); err != nil {
-			return "", "", err
-		}
+// unstash restores the changes stashed by stash.
+func unstash(o *benchOptions) error {
+	o.logCmd("git stash pop --index")
+	if out, err := git("stash", "pop", "-q", "--index"); err != nil {
+		return fmt.Errorf("%s\nrestoring the uncommitted changes failed; they are in the stash, run 'git stash pop --index' to restore them", out)
 	}
+	return nil
+}
 
-	// Run the benchmarks.
-	oldStats := ""
-	newStats := ""
-	needRevert := false
-	fmt.Fprintf(os.Stderr, "%s...%s (%d commits), %s x %d times/batch, batch repeated %d times.\n", branch, against, commits, benchtime, count, series)
-	for i := 0; i < series; i++ {
-		if ctx.Err() != nil {
-			// Don't error out, just quit.
-			break
-		}
-		out := ""
-		out, err = runBench(ctx, pkg, bench, benchtime, count)
+// runSide runs the benchmark of a side.
+func runSide(ctx context.Context, o *benchOptions, branch string, s *side, count int) (string, error) {
+	out := ""
+	err := inSide(o, branch, s, func() error {
+		var err2 error
+		out, err2 = runBench(ctx, o, s, count)
+		return err2
+	})
+	return out, err
+}
+
+// buildSides builds the test binaries for all sides upfront so the compiler
+// doesn't run between measurements.
+func buildSides(ctx context.Context, o *benchOptions, branch string, sides []*side) error {
+	for _, s := range sides {
+		if s.cmd != "" {
+			continue
+		}
+		err := inSide(o, branch, s, func() error {
+			_, err2 := buildTestBinaries(ctx, o, s)
+			return err2
+		})
 		if err != nil {
-			break
+			return err
+		}
+	}
+	return nil
+}
+
+func warmBench(ctx context.Context, o *benchOptions, branch string, sides []*side) error {
+	stderrLog.infof("warming up")
+	for _, s := range sides {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-		newStats += out
+		if _, err := runSide(ctx, o, branch, s, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		fmt.Fprintf(os.Stderr, "git checkout %s\n", against)
-		needRevert = true
-		if out, err = git("checkout", "-q", against); err != nil {
-			err = errors.New(out)
-			break
+// runBenchmarks runs the series on all sides with benchcmp.Runner and returns
+// the benchmark output of each side, in the same order, and the number of
+// series in it, excluding the discarded ones.
+//
+// stats is the output of each side from the done series already run, when
+// resuming. More series than series are run with -stable, fewer with
+// -timebudget or -early-stop. branch is checked out back after running a side
+// that has a ref.
+func runBenchmarks(ctx context.Context, o *benchOptions, branch string, sides []*side, stats []string, done, series int, nowarm bool) (*benchcmp.Result, error) {
+	// Benchmarks lasting less than 100ns per operation fail to be numerically
+	// stable and deviate by ~3%.
+	begin := time.Now()
+	bs := make([]*benchcmp.Side, len(sides))
+	for i, s := range sides {
+		bs[i] = &benchcmp.Side{Name: s.name, Output: stats[i]}
+	}
+	fail := func(err error) (*benchcmp.Result, error) {
+		res := &benchcmp.Result{Series: done}
+		for _, s := range bs {
+			res.Sides = append(res.Sides, &benchcmp.SideResult{Name: s.Name, Output: s.Output})
 		}
-		out, err = runBench(ctx, pkg, bench, benchtime, count)
-		if err != nil {
-			break
+		return res, err
+	}
+	if o.verify != "" && done == 0 {
+		if err := verifySides(ctx, o, branch, sides); err != nil {
+			return fail(err)
 		}
-		oldStats += out
-		fmt.Fprintf(os.Stderr, "git checkout %s\n", branch)
-		if out, err = git("checkout", "-q", branch); err != nil {
-			err = errors.New(out)
-			break
+	}
+	err := buildSides(ctx, o, branch, sides)
+	for n := 1; err != nil && n <= o.retries && ctx.Err() == nil; n++ {
+		stderrLog.infof("building failed; retrying (%d/%d): %s", n, o.retries, err)
+		err = buildSides(ctx, o, branch, sides)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if o.adaptive && o.plans == nil {
+		if err := probeBenchmarks(ctx, o, branch, sides); err != nil {
+			return fail(err)
 		}
-		needRevert = false
 	}
-	if needRevert {
-		fmt.Fprintf(os.Stderr, "Checking out %s\n", branch)
-		out := ""
-		if out, err = git("checkout", "-q", branch); err != nil {
-			err = errors.New(out)
+	if !nowarm && done == 0 {
+		if err := warmBench(ctx, o, branch, sides); err != nil {
+			return fail(err)
 		}
 	}
-	return oldStats, newStats, err
+
+	// Run the benchmarks.
+	start := time.Now()
+	// With -v, the output of the benchmarks is shown instead.
+	if stderrLog.level == levelInfo {
+		o.progress = newProgress(os.Stderr, done, series)
+		stderrLog.setProgress(o.progress)
+	}
+	defer func() {
+		stderrLog.setProgress(nil)
+		o.progress.close()
+		o.progress = nil
+	}()
+	base := readThermal()
+	var before thermalSample
+	throttledSeries, reruns := 0, 0
+	r := &benchcmp.Runner{
+		Retries:     o.retries,
+		Done:        done,
+		Logf:        stderrLog.infof,
+		Interleaved: len(o.remotes) > 1 || o.interleave,
+		Next: func(i int, stats []string) (bool, error) {
+			if o.earlyStop != nil && i > 0 && i < series {
+				k, err := o.earlyStop.undecided(stats)
+				if err != nil {
+					return false, err
+				}
+				if k == "" {
+					stderrLog.infof("-early-stop: the outcome of every benchmark is decided after %d series; skipping the other %d", i, series-i)
+					return false, nil
+				}
+				stderrLog.verbosef("-early-stop: %s is undecided after %d series", k, i)
+			}
+			if i >= series {
+				if o.stable <= 0 {
+					return false, nil
+				}
+				k, w, err := widestCI(stats)
+				if err != nil {
+					return false, err
+				}
+				if w <= o.stable {
+					stderrLog.infof("all benchmarks are within ±%.1f%% after %d series", o.stable, i)
+					return false, nil
+				}
+				if d := time.Since(start); d >= o.maxtime {
+					stderrLog.infof("%s is still at ±%.1f%% after %d series and %s; giving up", k, w, i, d.Round(time.Second))
+					return false, nil
+				}
+				if math.IsInf(w, 1) {
+					stderrLog.infof("%s has too few samples; running another series", k)
+				} else {
+					stderrLog.infof("%s is at ±%.1f%%; running another series", k, w)
+				}
+			}
+			if o.timebudget > 0 && i != done {
+				left := o.timebudget - time.Since(begin)
+				if left <= 0 {
+					stderrLog.infof("-timebudget reached after %d series", i)
+					return false, nil
+				}
+				if i == done+1 {
+					ok, err := fitBudget(o, stats, time.Since(start), left, series-i)
+					if err != nil {
+						return false, err
+					}
+					if !ok {
+						stderrLog.infof("no benchmark fits in -timebudget; stopping after 1 series")
+						return false, nil
+					}
+				}
+			}
+			return true, nil
+		},
+		RunSeries: func(ctx context.Context, i int, stats []string) (int, error) {
+			if o.spin {
+				spinCPU(ctx)
+			}
+			before = readThermal()
+			if len(o.remotes) > 1 {
+				// Run one series per machine concurrently.
+				n := len(o.remotes)
+				if i < series && series-i < n {
+					n = series - i
+				}
+				return n, runShards(ctx, o, branch, sides, stats, n)
+			}
+			return 1, runSeries(ctx, o, branch, sides, stats)
+		},
+		Check: func(i int, stats []string) (benchcmp.Verdict, error) {
+			// Fail fast instead of running all the series to compare nothing.
+			if i == done && len(sides) > 1 {
+				m, err := missingBenchmarks(sides, stats)
+				if err != nil {
+					return benchcmp.Keep, err
+				}
+				if len(m) != 0 {
+					msg := strings.Join(m, "\n  ")
+					if !o.allowMissing {
+						return benchcmp.Keep, fmt.Errorf("the sides did not run the same benchmarks:\n  %s\nuse -allow-missing to continue and report them on one side only", msg)
+					}
+					stderrLog.warnf("the sides did not run the same benchmarks:\n  %s", msg)
+				}
+			}
+			// The local thermal state is irrelevant with -remote.
+			why := throttled(base, before, readThermal())
+			if why == "" || o.remote != nil {
+				return benchcmp.Keep, nil
+			}
+			throttledSeries++
+			switch o.throttle {
+			case "discard", "rerun":
+				if o.throttle == "rerun" && reruns < series {
+					reruns++
+					stderrLog.infof("series %d was thermally throttled (%s); running it again", i+1, why)
+					return benchcmp.Rerun, nil
+				}
+				stderrLog.infof("series %d was thermally throttled (%s); discarded", i+1, why)
+				return benchcmp.Discard, nil
+			default:
+				stderrLog.warnf("series %d was thermally throttled (%s)", i+1, why)
+				return benchcmp.Keep, nil
+			}
+		},
+		SeriesDone: func(n int, stats []string) error {
+			o.progress.seriesDone(n)
+			if o.checkpoint != nil {
+				return o.checkpoint(stats, n)
+			}
+			return nil
+		},
+	}
+	if o.autowarm {
+		r.Warmup = func(stats []string, marks [][]int) (int, error) {
+			n, err := warmupSeries(stats, marks)
+			if n != 0 {
+				stderrLog.infof("discarded %d leading series that were significantly slower than the following ones", n)
+			}
+			return n, err
+		}
+	}
+	res, err := r.Run(ctx, bs)
+	if throttledSeries != 0 && o.throttle == "warn" {
+		stderrLog.warnf("%d series were collected while thermally throttled; consider -throttle rerun", throttledSeries)
+	}
+	return res, err
 }
 
-func genBenchTables(against, head, o, n string) ([]*benchstat.Table, error) {
-	c := &benchstat.Collection{
-		Alpha:     0.05,
-		DeltaTest: benchstat.UTest,
+// runSeries runs one series on all sides, appending the results to stats.
+//
+// When interleaving, the sides are alternated for each iteration instead of
+// running the full count on one side then the other.
+func runSeries(ctx context.Context, o *benchOptions, branch string, sides []*side, stats []string) error {
+	count, loops := o.count, 1
+	if o.interleave {
+		count, loops = 1, o.count
+	}
+	order := make([]int, len(sides))
+	for j := range order {
+		order[j] = j
 	}
-	// benchstat assumes that old must be first!
-	if err := c.AddFile(against, strings.NewReader(o)); err != nil {
-		return nil, err
+	for k := 0; k < loops; k++ {
+		if o.shuffle != nil {
+			o.shuffle.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		}
+		for _, j := range order {
+			o.progress.setSide(sides[j].name)
+			out, err := runSide(ctx, o, branch, sides[j], count)
+			if err != nil {
+				return fmt.Errorf("%s: %w", sides[j].name, err)
+			}
+			stats[j] += out
+			if s := sides[j]; o.buildTime && s.cmd == "" && s.bin == "" {
+				err = inSide(o, branch, s, func() error {
+					out, err = timeBuild(ctx, o, s)
+					return err
+				})
+				if err != nil {
+					return err
+				}
+				stats[j] += out
+			}
+		}
 	}
-	if err := c.AddFile(head, strings.NewReader(n)); err != nil {
-		return nil, err
+	return nil
+}
+
+// deltaTestFlag is a flag selecting the significance test: utest, ttest or
+// none.
+type deltaTestFlag string
+
+func (d *deltaTestFlag) Set(v string) error {
+	switch v {
+	case "utest", "ttest", "none":
+		*d = deltaTestFlag(v)
+		return nil
+	default:
+		return errors.New("must be one of utest, ttest or none")
 	}
-	return c.Tables(), nil
 }
 
-func printBenchstat(w io.Writer, tables []*benchstat.Table) error {
-	benchstat.FormatText(w, tables)
+func (d *deltaTestFlag) String() string {
+	return string(*d)
+}
+
+// renamesFlag maps the old name of benchmarks to their new name, as specified
+// by -rename, e.g. 'Parse=ParseJSON'. It can be specified multiple times.
+type renamesFlag map[string]string
+
+func (r renamesFlag) Set(v string) error {
+	i := strings.IndexByte(v, '=')
+	if i <= 0 || i == len(v)-1 {
+		return fmt.Errorf("expected old=new, got %q", v)
+	}
+	old, n := strings.TrimPrefix(v[:i], "Benchmark"), strings.TrimPrefix(v[i+1:], "Benchmark")
+	if _, ok := r[old]; ok {
+		return fmt.Errorf("%s is renamed twice", old)
+	}
+	r[old] = n
 	return nil
 }
 
-func jsonBenchstat(w io.Writer, tables []*benchstat.Table) error {
-	out := make([]*jsonTable, 0, len(tables))
-	for _, t := range tables {
-		outt := &jsonTable{
-			Metric:  t.Metric,
-			Unit:    t.Rows[0].Metrics[0].Unit,
-			Configs: t.Configs,
-			Rows:    make([]*jsonRow, 0, len(t.Rows)),
-		}
-		for _, row := range t.Rows {
-			r := &jsonRow{
-				Benchmark: row.Benchmark,
-				Metrics:   make([]*jsonMetrics, 0, len(row.Metrics)),
-				PctDelta:  row.PctDelta,
-				Delta:     row.Delta,
-				Note:      row.Note,
-				Change:    row.Change,
-			}
-			for _, m := range row.Metrics {
-				r.Metrics = append(r.Metrics, &jsonMetrics{
-					Values:  m.Values,
-					RValues: m.RValues,
-					Min:     m.Min,
-					Mean:    m.Mean,
-					Max:     m.Max,
-				})
+func (r renamesFlag) String() string {
+	l := make([]string, 0, len(r))
+	for old, n := range r {
+		l = append(l, old+"="+n)
+	}
+	sort.Strings(l)
+	return strings.Join(l, ",")
+}
+
+// betterFlag maps a unit to whether higher (1) or lower (-1) values are
+// better, as specified by -better, e.g. 'hit-ratio=higher'. It can be
+// specified multiple times.
+type betterFlag map[string]int
+
+func (b betterFlag) Set(v string) error {
+	i := strings.LastIndexByte(v, '=')
+	if i <= 0 {
+		return fmt.Errorf("expected unit=higher or unit=lower, got %q", v)
+	}
+	// The units are compared tidied, e.g. p99-ns becomes p99-sec.
+	_, u := benchunit.Tidy(1, v[:i])
+	switch v[i+1:] {
+	case "higher":
+		b[u] = 1
+	case "lower":
+		b[u] = -1
+	default:
+		return fmt.Errorf("expected unit=higher or unit=lower, got %q", v)
+	}
+	return nil
+}
+
+func (b betterFlag) String() string {
+	l := make([]string, 0, len(b))
+	for u, d := range b {
+		if d > 0 {
+			l = append(l, u+"=higher")
+		} else {
+			l = append(l, u+"=lower")
+		}
+	}
+	sort.Strings(l)
+	return strings.Join(l, ",")
+}
+
+// comparison is the tables comparing one side against the baseline.
+type comparison struct {
+	old, new string
+	tables   []*table
+}
+
+// genComparisons compares each side of the session against the first one, or
+// all of them at once when the session is in columns mode.
+func genComparisons(s *session, t *tableOptions) ([]*comparison, error) {
+	var out []*comparison
+	if s.Matrix > 0 {
+		for i := 0; i+s.Matrix <= len(s.Sides); i += s.Matrix {
+			old := s.Sides[i]
+			for _, ss := range s.Sides[i+1 : i+s.Matrix] {
+				tables, err := genBenchTables(old.Name, ss.Name, old.Output, ss.Output, t)
+				if err != nil {
+					return out, err
+				}
+				tables = append(tables, sizeTables([]*sessionSide{old, ss})...)
+				out = append(out, &comparison{old: old.Name, new: ss.Name, tables: tables})
 			}
-			outt.Rows = append(outt.Rows, r)
 		}
-		out = append(out, outt)
+		return out, nil
 	}
-	e := json.NewEncoder(w)
-	e.SetIndent("", "  ")
-	return e.Encode(out)
+	if s.Columns {
+		var outputs []string
+		for _, ss := range s.Sides {
+			outputs = append(outputs, ss.Output)
+		}
+		names := s.names()
+		tables, err := benchcmp.Compare(names, outputs, t)
+		if err != nil {
+			return out, err
+		}
+		tables = append(tables, sizeTables(s.Sides)...)
+		return append(out, &comparison{old: names[0], new: strings.Join(names[1:], ", "), tables: tables}), nil
+	}
+	for _, ss := range s.Sides[1:] {
+		tables, err := genBenchTables(s.Sides[0].Name, ss.Name, s.Sides[0].Output, ss.Output, t)
+		if err != nil {
+			return out, err
+		}
+		tables = append(tables, sizeTables([]*sessionSide{s.Sides[0], ss})...)
+		out = append(out, &comparison{old: s.Sides[0].Name, new: ss.Name, tables: tables})
+	}
+	return out, nil
+}
+
+// isTerminal returns true if f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// isColorTerminal returns true if f is a terminal that supports colors, unless
+// disabled with $NO_COLOR.
+func isColorTerminal(f *os.File) bool {
+	return os.Getenv("NO_COLOR") == "" && isTerminal(f)
 }
 
-type jsonTable struct {
-	Metric  string
-	Unit    string
-	Configs []string
-	Rows    []*jsonRow
+// printComparisons prints all the comparisons in the requested format.
+//
+// stats adds the p-value, sample sizes and effect size columns to the text
+// format.
+func printComparisons(w io.Writer, format string, c []*comparison, stats bool) error {
+	switch format {
+	case "text":
+		color := false
+		if f, ok := w.(*os.File); ok && isColorTerminal(f) {
+			w = colorable.NewColorable(f)
+			color = true
+		}
+		for i, cmp := range c {
+			if len(c) > 1 {
+				if i != 0 {
+					fmt.Fprintf(w, "\n")
+				}
+				fmt.Fprintf(w, "%s vs %s\n", cmp.old, cmp.new)
+			}
+			if err := benchcmp.WriteText(w, cmp.tables, &benchcmp.TextOptions{Color: color, Stats: stats}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "markdown":
+		for i, cmp := range c {
+			if len(c) > 1 {
+				if i != 0 {
+					fmt.Fprintf(w, "\n")
+				}
+				fmt.Fprintf(w, "### %s vs %s\n\n", mdEscape(cmp.old), mdEscape(cmp.new))
+			}
+			if err := markdownBenchstat(w, cmp.tables); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		var t []*table
+		for _, cmp := range c {
+			t = append(t, cmp.tables...)
+		}
+		return benchcmp.WriteJSON(w, t)
+	case "html":
+		return htmlReport(w, c)
+	case "gha":
+		return printGHA(w, c, -1, nil)
+	default:
+		return errors.New("internal error")
+	}
 }
 
-type jsonRow struct {
-	Benchmark string
-	Metrics   []*jsonMetrics
-	PctDelta  float64
-	Delta     string
-	Note      string
-	Change    int
+// printPartial prints the comparisons of a partial session, marked as such
+// with the number of series completed by each side.
+func printPartial(w io.Writer, format string, s *session, c []*comparison, stats bool) error {
+	l := make([]string, 0, len(s.Sides))
+	for _, ss := range s.Sides {
+		l = append(l, fmt.Sprintf("%s %d", ss.Name, ss.Completed))
+	}
+	msg := fmt.Sprintf("only the %d series completed by all sides are compared; series completed: %s", s.Series, strings.Join(l, ", "))
+	if format == "markdown" {
+		fmt.Fprintf(w, "> **Partial results**: %s\n\n", mdEscape(msg))
+	} else {
+		fmt.Fprintf(w, "PARTIAL RESULTS: %s\n\n", msg)
+	}
+	return printComparisons(w, format, c, stats)
 }
 
-type jsonMetrics struct {
-	Values  []float64 // measured values
-	RValues []float64 // Values with outliers removed
-	Min     float64   // min of RValues
-	Mean    float64   // mean of RValues
-	Max     float64   // max of RValues
+// raceWarning is shown with the results of test binaries built with -race.
+const raceWarning = "the test binaries were built with -race, which inflates the absolute numbers several times; only the comparison is meaningful"
+
+// printRaceWarning prints raceWarning when the session was built with -race:
+// before the results with the text and markdown formats, on stderr with the
+// others.
+func printRaceWarning(w io.Writer, format string, s *session) {
+	if !s.Race {
+		return
+	}
+	switch format {
+	case "text":
+		fmt.Fprintf(w, "RACE DETECTOR: %s\n\n", raceWarning)
+	case "markdown":
+		fmt.Fprintf(w, "> **Race detector**: %s\n\n", mdEscape(raceWarning))
+	default:
+		stderrLog.warnf("%s", raceWarning)
+	}
 }
 
 func mainImpl() error {
-	// Reduce runtime interference. 'ba' is meant to be relatively short running
-	// and the amount of data processed is small so GC is unnecessary.
-	runtime.LockOSThread()
-	debug.SetGCPercent(0)
+	// The subcommands do not run benchmarks and ba serve is long running, so
+	// they keep the default runtime settings.
+	if len(os.Args) > 1 && os.Args[1] == "merge" {
+		return mergeMain(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		return serveMain(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "analyze" {
+		return analyzeMain(os.Args[2:])
+	}
+	args := os.Args[1:]
+	isDaemon := len(args) > 0 && args[0] == "daemon"
+	if isDaemon {
+		args = args[1:]
+	} else {
+		// Reduce runtime interference. 'ba' is meant to be relatively short
+		// running and the amount of data processed is small so GC is
+		// unnecessary. ba daemon runs for days so it keeps it.
+		runtime.LockOSThread()
+		debug.SetGCPercent(0)
+	}
 	pkg := flag.String("pkg", "./...", "package to bench")
 	bench := flag.String("bench", ".", "benchmark to run, default to all")
-	against := flag.String("against", "origin/main", "commitref to benchmark against")
+	against := flag.String("against", "origin/main", "commitref to benchmark against; use a comma separated list to compare multiple commits side by side")
+	from := flag.String("from", "", "commitref to use as the old side instead of -against; both sides are run in a worktree")
+	to := flag.String("to", "", "commitref to use as the new side instead of HEAD; both sides are run in a worktree")
 	benchtime := flag.Duration("benchtime", 100*time.Millisecond, "duration of each benchmark")
-	format := flag.String("format", "text", "format to print; either text or json")
+	format := flag.String("format", "text", "format to print; one of text, json, markdown, html for a standalone page with box plots, csv for one row per benchmark run, gha for GitHub Actions or benchseries for the raw results with golang.org/x/perf/benchseries keys")
 	count := flag.Int("count", 2, "count to run per attempt")
+	benchmem := flag.Bool("benchmem", false, "print memory allocation statistics (B/op and allocs/op)")
 	series := flag.Int("series", 3, "series to run the benchmark")
 	// TODO(maruel): This does not seem to help.
 	nowarm := flag.Bool("nowarm", true, "do not run an extra warmup series")
+	spin := flag.Bool("spin", false, "before each series, keep all the CPUs busy until their frequency is stable, up to 10s, and report it")
+	autowarm := flag.Bool("autowarm", true, "discard the leading series that are significantly slower than the following ones, e.g. due to cold caches")
+	cmdNew := flag.String("cmd", "", "command to run instead of go test; it must print benchmark results in benchfmt format on stdout")
+	goOld := flag.String("go-old", "", "go command to build the old side with, e.g. /usr/local/go1.21/bin/go, to compare two toolchains on the current checkout instead of commits")
+	goNew := flag.String("go-new", "", "go command to build the new side with, e.g. gotip; see -go-old")
+	buildTime := flag.Bool("buildtime", false, "also compare the wall time of go build with an empty build cache on each side, as the GoBuild benchmark")
+	nm := flag.Bool("nm", false, "also compare the size of each package linked in the test binaries, via go tool nm")
+	pgo := flag.String("pgo", "", "benchmark the current checkout built with -pgo=off against built with this PGO profile, e.g. default.pgo, instead of commits")
+	binary := flag.String("binary", "", "compare two prebuilt test binaries, e.g. 'old.test,new.test', instead of commits; git is not used")
+	cmdOld := flag.String("cmd-old", "", "command to run on the -against side instead of -cmd")
+	earlyStopOn := flag.Bool("early-stop", false, "after each series, stop running series once the confidence interval of the change of every benchmark is entirely above or below its -fail-on-regression threshold, so more series cannot change the outcome")
+	failOnRegression := flag.Float64("fail-on-regression", -1, "exit with an error if any benchmark regressed by more than this percent with statistical significance; disabled when negative")
+	var failFor thresholds
+	flag.Var(&failFor, "fail-on-regression-for", "per benchmark -fail-on-regression as 'regexp=percent', e.g. 'Parse=2'; can be specified multiple times and the first match wins")
+	doBisect := flag.Bool("bisect", false, "find the first commit between -against and HEAD where a benchmark regressed by more than -threshold")
+	threshold := percent(5)
+	flag.Var(&threshold, "threshold", "regression threshold for -bisect and -notify-url, and the change to detect for -calibrate")
+	calibrate := flag.Bool("calibrate", false, "benchmark HEAD against itself to measure the noise floor of the machine and suggest the -count and -series needed to detect a change of -threshold")
+	bundle := flag.String("bundle", "", "save the raw results and session metadata into this zip file")
+	saveBaselineFile := flag.String("save-baseline", "", "only benchmark the current checkout and save the results into this file, to compare against later with -baseline, e.g. in CI on the main branch")
+	baselineFile := flag.String("baseline", "", "only benchmark the current checkout and compare it against the results saved in this file by -save-baseline, instead of -against")
+	outDir := flag.String("o", "", "write the raw benchmark output of each side and the session metadata into this directory")
+	rangeSpec := flag.String("range", "", "benchmark every commit of the first parent history in this revision range, e.g. v1.0.0..HEAD, and print a CSV time series per benchmark, or JSON with -format json")
+	rangeStep := flag.Int("range-step", 1, "only benchmark every Nth commit with -range")
+	githubComment := flag.Bool("github-comment", false, "post the results as a comment on the GitHub pull request of the current branch, updating it on re-runs; uses $GITHUB_TOKEN")
+	notifyURL := flag.String("notify-url", "", "POST a JSON notification to this webhook when a benchmark regressed by more than -threshold with statistical significance; Slack webhooks are supported")
+	record := flag.Bool("record", false, "append the results of each commit to the history database")
+	upload := flag.String("upload", "", "publish the median ns/op, B/op and allocs/op of each benchmark to a metrics store, e.g. influx://host:8086/db or pushgateway://host:9091/job")
+	history := flag.String("history", "", "do not run benchmarks, print the recorded history of this benchmark over the last -last commits instead")
+	last := flag.Int("last", 20, "number of commits to print with -history")
+	var labels labelFlag
+	flag.Var(&labels, "label", "attach this key=value experiment label, e.g. host=ci-5950x, to the results as a benchfmt configuration line and to the history recorded by -record; with -history, only print the results recorded with it; can be specified multiple times")
+	historyDB := flag.String("history-db", defaultHistoryPath(), "path of the history database for -record and -history")
+	resume := flag.String("resume", "", "save the progress into this directory after each series, and continue from it if it already contains an interrupted run")
+	allowMissing := flag.Bool("allow-missing", false, "continue when the sides did not run the same benchmarks, e.g. a benchmark added in the new commit, and report them on one side only, instead of failing after the first series")
+	allowMixed := flag.Bool("allow-mixed", false, "allow -resume to continue a run recorded on a different machine or toolchain")
+	replay := flag.String("replay", "", "do not run benchmarks, render the results saved in this bundle or -o directory instead")
+	numaNode := flag.Int("numa-node", -1, "pin the benchmark processes CPU and memory to this NUMA node using numactl")
+	numaCross := flag.Int("numa-cross", -1, "benchmark HEAD with memory on -numa-node vs on this NUMA node instead of against a commit")
+	shuffle := flag.String("shuffle", "off", "randomize the order of the sides in each series and of the benchmarks to avoid a systematic bias; 'on' or a seed")
+	interleave := flag.Bool("interleave", false, "alternate sides at every iteration instead of running -count iterations on one side then the other; reduces the impact of thermal drift")
+	stable := flag.Float64("stable", 0, "keep running series after -series until the 95% confidence interval of every benchmark is within ±this percent, e.g. 2")
+	maxtime := flag.Duration("maxtime", 10*time.Minute, "maximum duration to run series for with -stable")
+	pin := flag.String("pin", "", "run the benchmark processes on this CPU list, e.g. '2' or '2,4-5'; ideally isolated cores")
+	noaslr := flag.Bool("noaslr", false, "run the benchmark processes without address space layout randomization and with their environment padded to a fixed size, so the code, heap and stack layout is the same on every run")
+	nice := flag.Int("nice", 0, "niceness of the benchmark processes, from -20 to -1, so background tasks preempt them less; requires root or CAP_SYS_NICE")
+	fifo := flag.Bool("fifo", false, "run the benchmark processes with the SCHED_FIFO real-time scheduling policy, so only the kernel and other real-time tasks preempt them; requires root or CAP_SYS_NICE")
+	retries := flag.Int("retries", 0, "number of times to retry building or a series that failed, e.g. due to a crash or an OOM, instead of aborting")
+	throttle := flag.String("throttle", "warn", "what to do with series collected while the CPU was thermally throttled; one of warn, discard or rerun")
+	strictEnv := flag.Bool("strict-env", false, "refuse to run when CPU frequency scaling or turbo boost is enabled instead of warning")
+	cpu := flag.String("cpu", "1", "comma separated list of GOMAXPROCS values to run each benchmark with, e.g. 1,4,16; each value is compared in its own tables")
+	adaptive := flag.Bool("adaptive", false, "probe each benchmark with -benchtime 1x first, then use a fixed iteration count per benchmark and run the slow ones fewer times")
+	timebudget := flag.Duration("timebudget", 0, "total time to finish within; -count is reduced and the slowest benchmarks skipped to fit, based on the first series")
+	dirty := flag.Bool("dirty", false, "benchmark a copy of the working tree as is, with the uncommitted changes, as the new side against -against")
+	quick := flag.Bool("quick", false, "preset for a fast sanity check: -benchtime 50ms -count 2 -series 2 -alpha 0.1; explicit flags take precedence")
+	thorough := flag.Bool("thorough", false, "preset for publishable results: -benchtime 1s -count 5 -series 10 -alpha 0.01 -nowarm=false -strict-env; explicit flags take precedence")
+	interval := flag.Duration("interval", 6*time.Hour, "with ba daemon, time between two checks for new commits")
+	branch := flag.String("branch", "main", "with ba daemon, branch whose new commits are benchmarked")
+	maxCommits := flag.Int("max-commits", 10, "with ba daemon, maximum number of new commits to benchmark per check, the newest ones")
+	serve := flag.String("serve", "", "with ba daemon, address to serve the history database on, e.g. localhost:8080")
+	watchFlag := flag.Bool("watch", false, "benchmark a copy of the working tree against -against again each time a source file changes, with 2 series unless -series is set")
+	stream := flag.Bool("stream", false, "print a JSON line with the comparisons so far after each series on stdout, then a last one with Final set instead of -format, to follow a long run")
+	autostash := flag.Bool("autostash", false, "benchmark the uncommitted changes as part of HEAD instead of refusing to run; with -inplace they are stashed while the other refs are checked out")
+	isolateCache := flag.Bool("isolate-cache", false, "build the test binaries of each side with its own empty GOCACHE, so both sides pay the same compilation cost and share no build artifact; slower")
+	changedOnly := flag.Bool("changed-only", false, "only benchmark the packages of -pkg affected by the changes since the merge base with -against, including via their dependencies")
+	testflags := flag.String("testflags", "", "go test flags to add, e.g. '-race -short'; build flags are passed to go test -c and the others to the test binary")
+	race := flag.Bool("race", false, "build the test binaries with the race detector, as go test -race; the absolute numbers are inflated several times, only the comparison is meaningful")
+	tags := flag.String("tags", "", "build tags to build the test binaries with, as go test -tags")
+	gcflags := flag.String("gcflags", "", "flags to build the test binaries with, as go test -gcflags")
+	ldflags := flag.String("ldflags", "", "flags to link the test binaries with, as go test -ldflags")
+	verify := flag.String("verify", "", "run the unit tests matching this regexp with go test -count=1 on each side before benchmarking, e.g. '.', and abort if they fail")
+	inplace := flag.Bool("inplace", false, "check out -against in the current tree instead of using a temporary git worktree")
+	cpuprofile := flag.String("cpuprofile", "", "directory to save a CPU profile of each side into; the top functions by CPU time delta are printed")
+	memprofile := flag.String("memprofile", "", "directory to save a heap profile of each side into; the top allocation sites by bytes and objects delta are printed")
+	mutexprofile := flag.String("mutexprofile", "", "directory to save a mutex contention profile of each side into; the top contention sites by contentions and delay delta are printed")
+	traceBench := flag.String("trace", "", "benchmark, e.g. BenchmarkFoo, to record an execution trace of on each side; the GC, goroutine and scheduler latency statistics are printed")
+	blockprofile := flag.String("blockprofile", "", "directory to save a blocking profile of each side into; the top blocking sites by contentions and delay delta are printed")
+	var gogc, gomemlimit, godebug runtimeFlag
+	flag.Var(&gogc, "gogc", "GOGC of the benchmark processes, e.g. off or 400; empty resets it to the runtime's default; by default, the one of ba's environment is passed through with a warning")
+	flag.Var(&gomemlimit, "gomemlimit", "GOMEMLIMIT of the benchmark processes, e.g. 512MiB; empty resets it to the runtime's default; by default, the one of ba's environment is passed through with a warning")
+	flag.Var(&godebug, "godebug", "GODEBUG of the benchmark processes, e.g. madvdontneed=1; empty resets it to the runtime's default; by default, the one of ba's environment is passed through with a warning")
+	gcStats := flag.Bool("gcstats", false, "record the garbage collections of each test binary via GODEBUG=gctrace=1 and compare their count, total pause and peak heap")
+	perf := flag.Bool("perf", false, "record hardware counters (instructions, cycles, branch and cache misses) of each benchmark process via linux perf stat")
+	resctrl := flag.Bool("resctrl", false, "record memory bandwidth and peak LLC occupancy of each benchmark process via linux resctrl; requires root")
+	containerImage := flag.String("container", "", "run the test binaries of both sides in a docker or podman container of this image, with the checkouts mounted read-only; the binaries are built for linux without cgo")
+	containerCPUs := flag.String("container-cpus", "2", "CPU limit of the -container, e.g. 1.5; unlimited when empty")
+	containerMemory := flag.String("container-memory", "2g", "memory limit of the -container; unlimited when empty")
+	cgroup := flag.Bool("cgroup", false, "run each benchmark process in a transient systemd scope restricted to the -pin CPUs, -cgroup-memory and with -cgroup-weight")
+	cgroupMemory := flag.String("cgroup-memory", "", "memory limit of the -cgroup scope, e.g. 4G; unlimited when empty")
+	cgroupWeight := flag.Int("cgroup-weight", 10000, "CPU weight of the -cgroup scope, from 1 to 10000; the default for other processes is 100")
+	remoteHost := flag.String("remote", "", "run the test binaries on this ssh destination, e.g. user@host; they are cross compiled without cgo and copied over along with the checkouts with rsync; with a comma separated list of hosts, the series are run concurrently across them")
+	goarch := flag.String("goarch", "", "GOARCH to cross compile the test binaries for, e.g. arm64; it requires -qemu or -remote machines of this architecture")
+	qemu := flag.Bool("qemu", false, "run the test binaries cross compiled for linux and -goarch under qemu-user")
+	alpha := flag.Float64("alpha", 0.05, "p-value cutoff to consider a change significant")
+	deltaTest := deltaTestFlag("utest")
+	flag.Var(&deltaTest, "delta-test", "significance test to use; one of utest, ttest or none")
+	geomean := flag.Bool("geomean", false, "add a row with the geometric mean of all the benchmarks")
+	group := flag.Bool("group", false, "group the sub-benchmarks under their parent benchmark, e.g. Encode/size=1K under Encode, with a geomean row per group")
+	showStats := flag.Bool("stats", false, "print the p-value, the sample sizes and the effect size as Cohen's d in their own columns with -format text")
+	trim := percent(0)
+	maxCV := percent(0)
+	flag.Var(&maxCV, "max-cv", "report the benchmarks whose coefficient of variation exceeds this percent on any side apart as too noisy to compare, without a delta, e.g. 5%")
+	renames := renamesFlag{}
+	better := betterFlag{}
+	flag.Var(better, "better", "whether higher or lower values of a custom metric reported with b.ReportMetric are better, as 'unit=higher' or 'unit=lower', e.g. 'hit-ratio=higher'; can be specified multiple times")
+	flag.Var(renames, "rename", "compare a benchmark renamed between the sides as one, as 'old=new', e.g. 'Parse=ParseJSON'; can be specified multiple times")
+	flag.Var(&trim, "trim", "discard the worst percent of the values of each benchmark and side, e.g. the slowest iterations, to tame GC and scheduler spikes")
+	winsorize := flag.Bool("winsorize", false, "with -trim, replace the worst values with the worst value kept instead of discarding them")
+	verbose := flag.Bool("v", false, "print every command run and the output of the benchmark processes on stderr")
+	quiet := flag.Bool("q", false, "only print the results and the errors; no progress nor warnings on stderr")
+	dryRun := flag.Bool("n", false, "dry run: print the git and go commands that would be run and an estimate of the duration, without running anything")
+	config := flag.String("config", "", "file with the default value of the flags not specified on the command line; defaults to "+configFile+" at the root of the repository")
+	envMatrix := flag.String("env-matrix", "", "repeat the comparison under each of these ';' separated environments, e.g. 'GOGC=100;GOGC=off GOMEMLIMIT=1GiB', and print the results of each separately")
+	memconfig := flag.String("memconfig", "", "benchmark HEAD under these ';' separated memory configurations instead of against a commit, e.g. 'thp=off;GODEBUG=madvdontneed=1;GOMEMLIMIT=1GiB GOGC=off'")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "usage: ba <flags>\n")
+		fmt.Fprintf(os.Stderr, "       ba analyze <flags>\n")
+		fmt.Fprintf(os.Stderr, "       ba daemon <flags>\n")
+		fmt.Fprintf(os.Stderr, "       ba merge <flags> <files>\n")
+		fmt.Fprintf(os.Stderr, "       ba serve <flags>\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "ba (benches against) run benchmarks on two different commits and\n")
 		fmt.Fprintf(os.Stderr, "prints out the result with benchstat.\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		flag.PrintDefaults()
 	}
-	flag.Parse()
+	_ = flag.CommandLine.Parse(args)
 	if flag.NArg() != 0 {
 		return errors.New("unexpected argument")
 	}
+	// The flags set by the config file are validated like the ones on the
+	// command line.
+	repo = detectVCS(".")
+	if err := loadConfig(flag.CommandLine, *config); err != nil {
+		return err
+	}
+	{
+		var err error
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "interval", "branch", "max-commits", "serve":
+				if err == nil && !isDaemon {
+					err = fmt.Errorf("-%s is only supported by ba daemon", f.Name)
+				}
+			case "against", "from", "to", "range", "bisect", "watch", "replay", "history", "memconfig", "numa-cross", "binary", "go-old", "go-new", "pgo", "calibrate", "env-matrix", "baseline", "save-baseline", "dirty", "changed-only", "inplace", "remote", "container", "n", "stream", "bundle", "o", "resume", "github-comment":
+				if err == nil && isDaemon {
+					err = fmt.Errorf("-%s cannot be used with ba daemon", f.Name)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if isDaemon && (*interval <= 0 || *maxCommits < 1) {
+		return errors.New("-interval and -max-commits must be positive")
+	}
+	if *quick && *thorough {
+		return errors.New("-quick and -thorough are mutually exclusive")
+	}
+	switch {
+	case *verbose && *quiet:
+		return errors.New("-v and -q are mutually exclusive")
+	case *verbose:
+		stderrLog.level = levelVerbose
+	case *quiet:
+		stderrLog.level = levelQuiet
+	}
+	for name, on := range map[string]bool{"quick": *quick, "thorough": *thorough} {
+		if on {
+			if err := applyPreset(flag.CommandLine, name); err != nil {
+				return err
+			}
+		}
+	}
+	if repo.name() != "git" {
+		var err error
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "bisect", "range", "history", "github-comment", "autostash", "dirty", "changed-only", "watch":
+				if err == nil && f.Value.String() != f.DefValue {
+					err = fmt.Errorf("-%s requires git, this is a %s repository", f.Name, repo.name())
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
 	switch *format {
-	case "text", "json":
+	case "text", "json", "markdown", "gha":
+	case "html":
+		if *rangeSpec != "" {
+			return errors.New("-format html is not supported with -range")
+		}
+	case "csv":
+		if *doBisect {
+			return errors.New("-format csv is not supported with -bisect")
+		}
+	case "benchseries":
+		if *doBisect || *rangeSpec != "" {
+			return errors.New("-format benchseries is not supported with -bisect or -range")
+		}
 	default:
 		return errors.New("unsupported -format")
 	}
+	if *baselineFile != "" || *saveBaselineFile != "" {
+		switch {
+		case *baselineFile != "" && *saveBaselineFile != "":
+			return errors.New("-baseline and -save-baseline are mutually exclusive")
+		case *doBisect || *replay != "" || *watchFlag || *envMatrix != "":
+			return errors.New("-baseline and -save-baseline cannot be used with -bisect, -replay, -watch or -env-matrix")
+		}
+	}
+	if *stream {
+		switch {
+		case *format != "text" && *format != "json":
+			return errors.New("-stream cannot be used with -format " + *format)
+		case *doBisect || *calibrate || *rangeSpec != "" || *watchFlag || *dryRun || *replay != "":
+			return errors.New("-stream cannot be used with -bisect, -calibrate, -range, -watch, -n or -replay")
+		}
+	}
+	if *showStats && *format != "text" {
+		return errors.New("-stats requires -format text")
+	}
+	if *githubComment {
+		// Fail early instead of after running the benchmarks.
+		if _, err := newGitHubClient(); err != nil {
+			return err
+		}
+	}
+	var up *uploader
+	if *upload != "" {
+		var err error
+		if up, err = newUploader(*upload); err != nil {
+			return err
+		}
+	}
+	switch *throttle {
+	case "warn", "discard", "rerun":
+	default:
+		return errors.New("unsupported -throttle")
+	}
+	if *alpha <= 0 || *alpha >= 1 {
+		return errors.New("-alpha must be between 0 and 1")
+	}
+	if trim < 0 || trim >= 50 {
+		return errors.New("-trim must be between 0 and 50%")
+	}
+	if *winsorize && trim == 0 {
+		return errors.New("-winsorize requires -trim")
+	}
+	var rtEnv []string
+	{
+		set := map[string]string{}
+		for k, f := range map[string]*runtimeFlag{"GOGC": &gogc, "GOMEMLIMIT": &gomemlimit, "GODEBUG": &godebug} {
+			if f.set {
+				set[k] = f.value
+			}
+		}
+		var inherited []string
+		var err error
+		if rtEnv, inherited, err = runtimeEnv(set, os.Getenv); err != nil {
+			return err
+		}
+		for _, e := range inherited {
+			if *replay != "" || *history != "" {
+				// No benchmark process is started.
+				break
+			}
+			k := e[:strings.IndexByte(e, '=')]
+			stderrLog.warnf("the benchmark processes inherit %s from the environment; use -%s to set it explicitly", e, strings.ToLower(k))
+		}
+	}
+	topts := &tableOptions{Alpha: *alpha, DeltaTest: string(deltaTest), Geomean: *geomean, Trim: float64(trim), Winsorize: *winsorize, Renames: renames, MaxCV: float64(maxCV), Group: *group, Better: better}
+	o := &benchOptions{
+		pkg:          *pkg,
+		bench:        *bench,
+		benchtime:    *benchtime,
+		count:        *count,
+		benchmem:     *benchmem,
+		cpu:          *cpu,
+		inplace:      *inplace,
+		autostash:    *autostash,
+		interleave:   *interleave,
+		autowarm:     *autowarm,
+		stable:       *stable,
+		maxtime:      *maxtime,
+		timebudget:   *timebudget,
+		adaptive:     *adaptive,
+		throttle:     *throttle,
+		retries:      *retries,
+		resume:       *resume,
+		allowMixed:   *allowMixed,
+		labels:       labels,
+		allowMissing: *allowMissing,
+		runtime:      rtEnv,
+		resctrl:      *resctrl,
+		perf:         *perf,
+		gcStats:      *gcStats,
+		spin:         *spin,
+		nm:           *nm,
+		buildTime:    *buildTime,
+		verify:       *verify,
+		isolateCache: *isolateCache,
+	}
+	for _, f := range []struct{ name, value string }{{"tags", *tags}, {"gcflags", *gcflags}, {"ldflags", *ldflags}} {
+		if f.value != "" {
+			o.buildFlags = append(o.buildFlags, "-"+f.name+"="+f.value)
+		}
+	}
+	build, test, err2 := parseTestFlags(*testflags)
+	if err2 != nil {
+		return err2
+	}
+	o.buildFlags = append(o.buildFlags, build...)
+	o.testFlags = test
+	if *race && !contains(o.buildFlags, "-race") {
+		o.buildFlags = append(o.buildFlags, "-race")
+	}
+	o.race = contains(o.buildFlags, "-race")
+	for _, c := range strings.Split(o.cpu, ",") {
+		if n, err := strconv.Atoi(c); err != nil || n < 1 {
+			return fmt.Errorf("invalid -cpu %q", o.cpu)
+		}
+	}
+	if o.adaptive && o.cpu != "1" {
+		return errors.New("-adaptive cannot be used with -cpu")
+	}
+	if *earlyStopOn {
+		switch {
+		case *failOnRegression < 0 && len(failFor) == 0:
+			return errors.New("-early-stop requires -fail-on-regression or -fail-on-regression-for")
+		case *rangeSpec != "" || *envMatrix != "" || *stable > 0:
+			return errors.New("-early-stop cannot be used with -range, -env-matrix or -stable")
+		}
+		o.earlyStop = &earlyStop{threshold: *failOnRegression, per: failFor, opts: topts}
+	}
+	if *shuffle != "off" {
+		seed := time.Now().UnixNano()
+		if *shuffle != "on" {
+			var err error
+			if seed, err = strconv.ParseInt(*shuffle, 10, 64); err != nil {
+				return errors.New("-shuffle must be off, on or a seed")
+			}
+		}
+		stderrLog.infof("-shuffle %d", seed)
+		/* #nosec G404 */
+		o.shuffle = rand.New(rand.NewSource(seed))
+	}
+	if *cmdOld == "" {
+		*cmdOld = *cmdNew
+	}
+	if *numaNode != -1 && *numaCross == -1 {
+		var err error
+		if o.wrap, err = numaWrap(*numaNode, *numaNode); err != nil {
+			return err
+		}
+	}
+	if *pin != "" {
+		var err error
+		if o.pin, err = parseCPUList(*pin); err != nil {
+			return err
+		}
+		// Fail early if pinning is not supported.
+		restore, err := pinThread(o.pin)
+		if err != nil {
+			return err
+		}
+		restore()
+		checkIsolated(o.pin)
+	}
+	if *cgroup {
+		var err error
+		if o.cgroup, err = cgroupWrap(o.pin, *cgroupMemory, *cgroupWeight); err != nil {
+			return err
+		}
+	}
+	if *noaslr {
+		// Fail early if disabling ASLR is not supported.
+		restore, err := disableASLRThread()
+		if err != nil {
+			return err
+		}
+		restore()
+		o.noaslr = true
+	}
+	if *nice != 0 || *fifo {
+		if *nice < -20 || *nice > 0 {
+			return errors.New("-nice must be between -20 and -1; a positive niceness lets background tasks preempt the benchmarks more")
+		}
+		// Fail early if the priority cannot be raised.
+		restore, err := priorityThread(*nice, *fifo)
+		if err != nil {
+			return err
+		}
+		restore()
+		o.nice = *nice
+		o.fifo = *fifo
+	}
+	if *remoteHost != "" {
+		switch {
+		case *cmdNew != "" || *cmdOld != "" || *containerImage != "" || o.perf || o.gcStats || o.resctrl || len(o.pin) != 0 || *cgroup || *noaslr || o.spin || o.nice != 0 || o.fifo:
+			return errors.New("-remote cannot be used with -cmd, -container, -perf, -gcstats, -resctrl, -pin, -cgroup, -noaslr, -spin, -nice or -fifo")
+		case *cpuprofile != "" || *memprofile != "" || *mutexprofile != "" || *blockprofile != "" || *traceBench != "":
+			return errors.New("-remote cannot be used with -cpuprofile, -memprofile, -mutexprofile, -blockprofile or -trace")
+		case *numaNode != -1 || *numaCross != -1 || *memconfig != "":
+			return errors.New("-remote cannot be used with -numa-node, -numa-cross or -memconfig")
+		case strings.Contains(*remoteHost, ",") && (o.inplace || o.timebudget != 0):
+			return errors.New("-remote with multiple hosts cannot be used with -inplace or -timebudget")
+		}
+	}
+	// The environment of the local machine is irrelevant with -remote.
+	if *replay == "" && *remoteHost == "" {
+		if err := checkEnv(o.pin, *strictEnv); err != nil {
+			return err
+		}
+	}
+	for _, p := range []struct {
+		dir string
+		fn  func(string) *profileKind
+	}{{*cpuprofile, cpuProfile}, {*memprofile, memProfile}, {*mutexprofile, mutexProfile}, {*blockprofile, blockProfile}} {
+		if p.dir == "" {
+			continue
+		}
+		d, err := filepath.Abs(p.dir)
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(d, 0o755); err != nil {
+			return err
+		}
+		o.profiles = append(o.profiles, p.fn(d))
+	}
+	o.trace = *traceBench
+	if o.race {
+		switch {
+		case *cmdNew != "" || *cmdOld != "" || *binary != "":
+			return errors.New("-race cannot be used with -cmd or -binary since ba does not build them")
+		case *containerImage != "" || *goarch != "" || *remoteHost != "":
+			// The race detector requires cgo.
+			return errors.New("-race cannot be used with -container, -goarch or -remote since they build without cgo")
+		}
+	}
+	if *containerImage != "" {
+		switch {
+		case *cmdNew != "" || *cmdOld != "":
+			return errors.New("-container cannot be used with -cmd")
+		case o.perf || o.gcStats || o.resctrl || *cgroup || *noaslr || o.nice != 0 || o.fifo:
+			return errors.New("-container cannot be used with -perf, -gcstats, -resctrl, -cgroup, -noaslr, -nice or -fifo")
+		case *numaNode != -1 || *numaCross != -1 || *memconfig != "":
+			return errors.New("-container cannot be used with -numa-node, -numa-cross or -memconfig")
+		case o.trace != "":
+			return errors.New("-container cannot be used with -trace")
+		}
+		var err error
+		if o.container, err = newContainer(*containerImage, *containerCPUs, *containerMemory); err != nil {
+			return err
+		}
+		// Statically linked so it runs in any linux image.
+		o.buildEnv = []string{"GOOS=linux", "CGO_ENABLED=0"}
+	}
+	if *qemu {
+		switch {
+		case *goarch == "":
+			return errors.New("-qemu requires -goarch")
+		case *cmdNew != "" || *cmdOld != "" || *binary != "":
+			return errors.New("-qemu cannot be used with -cmd or -binary")
+		case *remoteHost != "" || *containerImage != "":
+			return errors.New("-qemu cannot be used with -remote or -container")
+		case o.perf || o.resctrl:
+			return errors.New("-qemu cannot be used with -perf or -resctrl since they would measure the emulator")
+		}
+		var err error
+		if o.qemu, err = qemuWrap(*goarch); err != nil {
+			return err
+		}
+		// Statically linked so no sysroot is needed.
+		o.buildEnv = []string{"GOOS=linux", "GOARCH=" + *goarch, "CGO_ENABLED=0"}
+	} else if *goarch != "" && *remoteHost == "" {
+		return errors.New("-goarch requires -qemu or -remote")
+	}
+	if o.perf {
+		if err := checkPerf(); err != nil {
+			return err
+		}
+	}
+	if o.spin {
+		if err := checkSpin(); err != nil {
+			return err
+		}
+	}
+	if o.resctrl {
+		// Fail early if monitoring is not supported.
+		r, err := newResctrlGroup()
+		if err != nil {
+			return err
+		}
+		if _, err = r.stop(); err != nil {
+			return err
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, os.Interrupt)
+	signal.Notify(ch, cleanupSignals...)
 	go func() {
 		<-ch
 		cancel()
 	}()
 
-	oldStats, newStats, err := runBenchmarks(ctx, *against, *pkg, *bench, *benchtime, *count, *series, *nowarm)
-	t, err2 := genBenchTables(*against, "HEAD", oldStats, newStats)
-	if err == nil {
-		err = err2
+	if isDaemon {
+		return daemon(ctx, o, &daemonOptions{interval: *interval, branch: *branch, maxCommits: *maxCommits, db: *historyDB, serve: *serve, last: *last, labels: labels.labels()}, *series, *nowarm)
+	}
+	if *history != "" {
+		return printHistory(os.Stdout, *historyDB, *history, "HEAD", *last, labels.labels())
+	}
+	if *remoteHost != "" && *dryRun {
+		// Do not connect.
+		o.remote = &remote{host: *remoteHost}
+	} else if *remoteHost != "" && *replay == "" {
+		defer func() {
+			for _, r := range o.remotes {
+				if err2 := r.close(o); err2 != nil {
+					stderrLog.errorf("%s", err2)
+				}
+			}
+		}()
+		for _, h := range strings.Split(*remoteHost, ",") {
+			r, err := newRemote(ctx, o, h)
+			if err != nil {
+				return err
+			}
+			o.remotes = append(o.remotes, r)
+			if r.goos != o.remotes[0].goos || r.goarch != o.remotes[0].goarch {
+				return fmt.Errorf("-remote %s is %s/%s but %s is %s/%s", h, r.goos, r.goarch, o.remotes[0].host, o.remotes[0].goos, o.remotes[0].goarch)
+			}
+		}
+		// The first machine does everything not sharded.
+		o.remote = o.remotes[0]
+		if *goarch != "" && *goarch != o.remote.goarch {
+			return fmt.Errorf("-goarch %s but -remote %s is %s", *goarch, o.remote.host, o.remote.goarch)
+		}
+		o.buildEnv = []string{"GOOS=" + o.remote.goos, "GOARCH=" + o.remote.goarch, "CGO_ENABLED=0"}
+	}
+	if *doBisect {
+		if *dryRun {
+			return errors.New("-n is not supported with -bisect since the commits to benchmark depend on the results")
+		}
+		if *replay != "" || *memconfig != "" || *envMatrix != "" || *numaCross != -1 || *from != "" || *to != "" || *binary != "" || *goOld != "" || *goNew != "" || *pgo != "" || strings.Contains(*against, ",") {
+			return errors.New("-bisect only supports a single -against")
+		}
+		c, sha1, err := bisect(ctx, o, *against, *cmdOld, *cmdNew, *series, *nowarm, float64(threshold), topts)
+		if err != nil {
+			return err
+		}
+		desc, _ := git("log", "-1", "--format=%h %s", sha1)
+		if *format == "json" || *format == "html" {
+			fmt.Fprintf(os.Stderr, "first bad commit: %s\n", desc)
+		} else {
+			fmt.Printf("first bad commit: %s\n\n", desc)
+		}
+		return printComparisons(os.Stdout, *format, []*comparison{c}, *showStats)
+	}
+
+	var s *session
+	var err error
+	if *replay != "" {
+		if s, err = readBundle(*replay); err != nil {
+			return err
+		}
+		stderrLog.infof("replaying %s recorded %s with %s", strings.Join(s.names(), " vs "), s.Start.Format(time.RFC3339), s.GoVersion)
+	} else {
+		var sides []*side
+		modes := 0
+		for _, b := range []bool{*memconfig != "", *numaCross != -1, *from != "" || *to != "", *binary != "", *goOld != "" || *goNew != "", *pgo != "", *rangeSpec != "", *calibrate, *baselineFile != "" || *saveBaselineFile != ""} {
+			if b {
+				modes++
+			}
+		}
+		if modes > 1 {
+			return errors.New("-memconfig, -numa-cross, -from/-to, -binary, -go-old/-go-new, -pgo, -range, -calibrate and -baseline/-save-baseline are mutually exclusive")
+		}
+		base := ""
+		if *baselineFile != "" {
+			if base, err = readBaseline(*baselineFile); err != nil {
+				return err
+			}
+		}
+		// withBaseline adds the baseline as the first side of the session.
+		withBaseline := func(s *session) *session {
+			if base == "" {
+				return s
+			}
+			c := *s
+			c.Sides = append([]*sessionSide{{Name: filepath.Base(*baselineFile), Output: base, Completed: s.Series}}, s.Sides...)
+			return &c
+		}
+		if *baselineFile != "" || *saveBaselineFile != "" {
+			// Only the current checkout is benchmarked.
+			sides = []*side{{name: "HEAD", cmd: *cmdNew}}
+		} else if *rangeSpec != "" {
+			if sides, err = rangeSides(*rangeSpec, *rangeStep); err != nil {
+				return err
+			}
+			for _, d := range sides {
+				d.cmd = *cmdNew
+			}
+			// The benchmarks added or removed in the range are part of the
+			// time series.
+			o.allowMissing = true
+		} else if *goOld != "" || *goNew != "" {
+			if *cmdNew != "" {
+				return errors.New("-go-old/-go-new and -cmd are mutually exclusive")
+			}
+			if sides, err = toolchainSides(*goOld, *goNew); err != nil {
+				return err
+			}
+		} else if *pgo != "" {
+			if *cmdNew != "" {
+				return errors.New("-pgo and -cmd are mutually exclusive")
+			}
+			if sides, err = pgoSides(*pgo); err != nil {
+				return err
+			}
+		} else if *binary != "" {
+			if *cmdNew != "" {
+				return errors.New("-binary and -cmd are mutually exclusive")
+			}
+			if sides, err = binarySides(*binary); err != nil {
+				return err
+			}
+		} else if *calibrate {
+			if *format != "text" {
+				return errors.New("-calibrate only supports -format text")
+			}
+			sides = calibrateSides()
+			for _, d := range sides {
+				d.cmd = *cmdNew
+			}
+		} else if *numaCross != -1 {
+			if sides, err = numaCrossSides(*numaNode, *numaCross); err != nil {
+				return err
+			}
+			for _, d := range sides {
+				d.cmd = *cmdNew
+			}
+		} else if *memconfig != "" {
+			if sides, err = parseMemConfigs(*memconfig); err != nil {
+				return err
+			}
+			for _, d := range sides {
+				d.cmd = *cmdNew
+			}
+		} else {
+			for _, ref := range strings.Split(*against, ",") {
+				sides = append(sides, &side{name: ref, ref: ref, cmd: *cmdOld})
+			}
+			sides = append(sides, &side{name: "HEAD", cmd: *cmdNew})
+			if len(sides) > 2 && (*from != "" || *to != "") {
+				return errors.New("-from/-to do not support multiple -against")
+			}
+			if *from != "" || *to != "" {
+				// Both sides are run from a worktree.
+				if *from != "" {
+					sides[0].name = *from
+					sides[0].ref = *from
+				}
+				sides[1].name = *to
+				sides[1].ref = *to
+				if *to == "" {
+					sides[1].name = "HEAD"
+					sides[1].ref = "HEAD"
+				}
+			}
+		}
+		matrix := 0
+		if *envMatrix != "" {
+			if *memconfig != "" || *numaCross != -1 || *rangeSpec != "" || *calibrate {
+				return errors.New("-env-matrix cannot be used with -memconfig, -numa-cross, -range or -calibrate")
+			}
+			m, err := parseEnvMatrix(*envMatrix)
+			if err != nil {
+				return err
+			}
+			matrix = len(sides)
+			sides = matrixSides(sides, m)
+		}
+		if *watchFlag {
+			switch {
+			case *from != "" || *to != "" || *memconfig != "" || *numaCross != -1 || *binary != "" || *goOld != "" || *goNew != "" || *pgo != "" || *rangeSpec != "" || *calibrate || *envMatrix != "":
+				return errors.New("-watch only supports -against")
+			case o.inplace || *dirty || *changedOnly || *dryRun:
+				return errors.New("-watch cannot be used with -inplace, -dirty, -changed-only or -n")
+			case *bundle != "" || *outDir != "" || *record || up != nil:
+				return errors.New("-watch cannot be used with -bundle, -o, -record or -upload")
+			case *format != "text" || *githubComment:
+				return errors.New("-watch only supports -format text")
+			}
+			n := 2
+			flag.Visit(func(f *flag.Flag) {
+				if f.Name == "series" {
+					n = *series
+				}
+			})
+			return watch(ctx, o, sides, n, *nowarm, func(s *session) error {
+				c, err := genComparisons(s, topts)
+				if err != nil {
+					return err
+				}
+				return printComparisons(os.Stdout, *format, c, *showStats)
+			})
+		}
+		if *dirty {
+			switch {
+			case *from != "" || *to != "" || *memconfig != "" || *numaCross != -1 || *binary != "" || *goOld != "" || *goNew != "" || *pgo != "" || *rangeSpec != "" || *calibrate:
+				return errors.New("-dirty only supports -against")
+			case o.inplace:
+				return errors.New("-dirty cannot be used with -inplace; use -autostash")
+			}
+			d := sides[len(sides)-1]
+			d.name = "working tree"
+			if *dryRun {
+				d.dir = "<copy of the working tree>"
+			} else {
+				dir, cleanup, err := copyWorkingTree(o)
+				if err != nil {
+					return err
+				}
+				defer cleanup()
+				d.dir = dir
+			}
+		}
+		if *changedOnly {
+			if *from != "" || *to != "" || *memconfig != "" || *numaCross != -1 || *binary != "" || *goOld != "" || *goNew != "" || *pgo != "" || *rangeSpec != "" || *calibrate {
+				return errors.New("-changed-only only supports -against")
+			}
+			only, err := changedPackages(strings.Split(*against, ","), o.pkgPattern())
+			if err != nil {
+				return err
+			}
+			if only == nil {
+				stderrLog.infof("the module files changed; benchmarking all the packages")
+			} else if len(only) == 0 {
+				stderrLog.infof("no package is affected by the changes against %s", *against)
+				return nil
+			} else {
+				names := make([]string, 0, len(only))
+				for p := range only {
+					names = append(names, p)
+				}
+				sort.Strings(names)
+				stderrLog.infof("benchmarking the packages affected by the changes: %s", strings.Join(names, ", "))
+				o.only = only
+			}
+		}
+		if *dryRun {
+			return printPlan(os.Stdout, o, sides, *series, *nowarm)
+		}
+		// Multiple -against are shown side by side.
+		columns := *memconfig == "" && *numaCross == -1 && matrix == 0 && len(sides) > 2
+		if *stream {
+			o.stream = func(s *session) error {
+				s.Columns = columns
+				s.Matrix = matrix
+				c, err := genComparisons(withBaseline(s), topts)
+				if err != nil {
+					return err
+				}
+				return writeStreamLine(os.Stdout, s, c, false)
+			}
+		}
+		s, err = runSession(ctx, o, sides, *series, *nowarm)
+		if base != "" {
+			if d := machineDiff(outputMachine(base), s.Machine); len(s.Machine) != 0 && len(d) != 0 {
+				stderrLog.warnf("the baseline was recorded on a different machine or toolchain:\n  %s", strings.Join(d, "\n  "))
+			}
+			s = withBaseline(s)
+		}
+		s.Columns = columns
+		s.Matrix = matrix
+		s.Range = *rangeSpec != ""
+		s.Calibrate = *calibrate
+		if *bundle != "" && s.hasOutput() {
+			if err2 := writeBundle(*bundle, s); err == nil {
+				err = err2
+			}
+		}
+		if *outDir != "" && s.hasOutput() {
+			if err2 := writeDir(*outDir, s); err == nil {
+				err = err2
+			}
+		}
+		if *record && err == nil {
+			n, err2 := recordHistory(*historyDB, s)
+			if err = err2; err == nil {
+				stderrLog.infof("recorded %d results in %s", n, *historyDB)
+			}
+		}
+		if up != nil && err == nil {
+			n, err2 := up.upload(ctx, http.DefaultClient, s)
+			if err = err2; err == nil {
+				stderrLog.infof("uploaded %d results", n)
+			}
+		}
 	}
+	if *saveBaselineFile != "" {
+		if err == nil {
+			if err = saveBaseline(*saveBaselineFile, s); err == nil {
+				stderrLog.infof("saved %d series to %s", s.Series, *saveBaselineFile)
+			}
+		}
+		return err
+	}
+	if err == nil && s.Range {
+		return printTimeSeries(os.Stdout, *format, s)
+	}
+	if err == nil && s.Calibrate {
+		return printCalibration(os.Stdout, s, threshold, *alpha, *count)
+	}
+	c, err2 := genComparisons(s, topts)
 	if err != nil {
+		if err2 == nil && s.Partial && s.Series != 0 && *stream {
+			if err2 = writeStreamLine(os.Stdout, s, c, true); err2 != nil {
+				stderrLog.errorf("%s", err2)
+			}
+		} else if err2 == nil && s.Partial && s.Series != 0 && !s.Range && !s.Calibrate && (*format == "text" || *format == "markdown") {
+			printRaceWarning(os.Stdout, *format, s)
+			if err2 = printPartial(os.Stdout, *format, s, c, *showStats); err2 != nil {
+				stderrLog.errorf("%s", err2)
+			}
+		}
 		return err
 	}
-	switch *format {
-	case "text":
-		err = printBenchstat(os.Stdout, t)
-	case "json":
-		err = jsonBenchstat(os.Stdout, t)
-	default:
-		err = errors.New("internal error")
+	if err2 != nil {
+		return err2
 	}
-	return err
+	if *stream {
+		// Keep the JSON lines parseable; the warning goes to stderr.
+		printRaceWarning(os.Stdout, "stream", s)
+	} else {
+		printRaceWarning(os.Stdout, *format, s)
+	}
+	if *stream {
+		err = writeStreamLine(os.Stdout, s, c, true)
+	} else if *format == "gha" {
+		err = printGHA(os.Stdout, c, *failOnRegression, failFor)
+	} else if *format == "benchseries" {
+		err = printBenchseries(os.Stdout, s)
+	} else if *format == "csv" {
+		err = printCSV(os.Stdout, s)
+	} else {
+		err = printComparisons(os.Stdout, *format, c, *showStats)
+	}
+	if err != nil {
+		return err
+	}
+	if *githubComment {
+		branch, err := git("rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return errors.New(branch)
+		}
+		if err = postGitHubComment(ctx, c, branch); err != nil {
+			return err
+		}
+	}
+	if *notifyURL != "" {
+		sent, err := notifyRegressions(ctx, http.DefaultClient, *notifyURL, c, float64(threshold))
+		if err != nil {
+			return err
+		}
+		if sent {
+			stderrLog.infof("regression notification sent")
+		}
+	}
+	if *failOnRegression >= 0 || len(failFor) != 0 {
+		return checkRegressions(c, *failOnRegression, failFor)
+	}
+	return nil
+}
+
+// runSession runs the benchmarks and returns the recorded session.
+//
+// When the first side has a ref, the tree is checked out as needed.
+func runSession(ctx context.Context, o *benchOptions, sides []*side, series int, nowarm bool) (*session, error) {
+	s := newSession(sides)
+	if o.remote == nil {
+		// Otherwise the benchmarks run on another machine.
+		s.Machine = machineConfig()
+	}
+	s.Labels = o.labels.config()
+	s.Runtime = o.runtime
+	s.Race = o.race
+	first := len(o.commands)
+	if o.binDir == "" {
+		d, err := os.MkdirTemp("", "ba-bin-")
+		if err != nil {
+			return s, err
+		}
+		o.binDir = d
+		o.binaries = map[string][]*testBinary{}
+		defer func() {
+			_ = os.RemoveAll(d)
+			o.binDir = ""
+			o.binaries = nil
+			o.plans = nil
+		}()
+	}
+	branch := ""
+	if against := sides[0].ref; against != "" {
+		// The last side is the newest.
+		head := sides[len(sides)-1].ref
+		// With -autostash, the uncommitted changes are benchmarked as part of
+		// HEAD.
+		if ((head == "" && sides[len(sides)-1].dir == "") || o.inplace) && !o.autostash {
+			if err := isPristine(); err != nil {
+				return s, err
+			}
+		}
+		if head == "" {
+			head = "HEAD"
+		}
+		var commits int
+		var err error
+		if branch, commits, err = getInfos(against, head); err != nil {
+			return s, err
+		}
+		if !o.inplace {
+			cleanup, err := addWorktrees(o, sides)
+			if err != nil {
+				return s, err
+			}
+			defer cleanup()
+		}
+		if head == "HEAD" {
+			head = branch
+		}
+		stderrLog.infof("%s...%s (%d commits), %s x %d times/batch, batch repeated %d times.", head, against, commits, o.benchtime, o.count, series)
+	} else {
+		stderrLog.infof("%d configurations, %s x %d times/batch, batch repeated %d times.", len(sides), o.benchtime, o.count, series)
+	}
+	stats := make([]string, len(sides))
+	done := 0
+	if o.resume != "" {
+		prev, err := readDir(o.resume)
+		if err == nil {
+			if !s.sameSides(prev) {
+				return s, fmt.Errorf("-resume: %s was recorded for different sides or commits", o.resume)
+			}
+			d := machineDiff(prev.Machine, s.Machine)
+			if len(prev.Machine) != 0 && len(d) != 0 && !o.allowMixed {
+				return s, fmt.Errorf("-resume: %s was recorded on a different machine, use -allow-mixed to continue anyway:\n  %s", o.resume, strings.Join(d, "\n  "))
+			}
+			for i, ss := range prev.Sides {
+				stats[i] = ss.Output
+				if len(d) != 0 && len(s.Machine) != 0 && stats[i] != "" {
+					// The configuration lines apply to the results that follow.
+					stats[i] += strings.Join(s.Machine, "\n") + "\n"
+				}
+			}
+			done = prev.Series
+			s.Start = prev.Start
+			stderrLog.infof("resuming after %d series", done)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return s, err
+		}
+	}
+	if o.resume != "" || o.stream != nil {
+		o.checkpoint = func(stats []string, n int) error {
+			for i := range stats {
+				s.Sides[i].Output = withMachine(s.config(i), stats[i])
+			}
+			s.Series = n
+			s.Commands = o.commands[first:]
+			s.Duration = time.Since(s.Start).Round(time.Millisecond)
+			if o.resume != "" {
+				if err := writeDir(o.resume, s); err != nil {
+					return err
+				}
+			}
+			if o.stream != nil {
+				return o.stream(s)
+			}
+			return nil
+		}
+		defer func() {
+			o.checkpoint = nil
+		}()
+	}
+	res, err := runBenchmarks(ctx, o, branch, sides, stats, done, series, nowarm)
+	s.Series = res.Series
+	s.Partial = res.Partial
+	for i, r := range res.Sides {
+		if res.Partial {
+			s.Sides[i].Completed = r.Completed
+		}
+		s.Sides[i].Output = withMachine(s.config(i), r.Output)
+	}
+	if err == nil {
+		err = recordSizes(ctx, o, sides, s)
+	}
+	if err == nil && len(o.profiles) != 0 && ctx.Err() == nil {
+		var profiles [][]string
+		profiles, err = profileSides(ctx, o, branch, sides)
+		for k := 0; k < len(o.profiles) && err == nil; k++ {
+			err = diffProfiles(ctx, os.Stderr, s.names(), o.profiles[k], profiles[k])
+		}
+	}
+	if err == nil && o.trace != "" && ctx.Err() == nil {
+		err = traceSides(ctx, os.Stderr, o, branch, sides, o.trace)
+	}
+	s.Commands = o.commands[first:]
+	s.Duration = time.Since(s.Start).Round(time.Millisecond)
+	return s, err
 }
 
 func main() {
 	if err := mainImpl(); err != nil {
-		fmt.Fprintf(os.Stderr, "ba: %s\n", err)
+		stderrLog.errorf("%s", err)
 		os.Exit(1)
 	}
 }
@@ -6,11 +6,18 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+
+	"github.com/maruel/pat/benchcmp"
+	"github.com/mgutz/ansi"
 )
 
-func BenchmarkPrintBenchstat(b *testing.B) {
-	old := `BenchmarkGobEncode   	100	  13552735 ns/op	  56.63 MB/s
+const testOld = `BenchmarkGobEncode   	100	  13552735 ns/op	  56.63 MB/s
 BenchmarkJSONEncode  	 50	  32395067 ns/op	  59.90 MB/s
 BenchmarkGobEncode   	100	  13553943 ns/op	  56.63 MB/s
 BenchmarkJSONEncode  	 50	  32334214 ns/op	  60.01 MB/s
@@ -19,7 +26,8 @@ BenchmarkJSONEncode  	 50	  31992891 ns/op	  60.65 MB/s
 BenchmarkGobEncode   	100	  13683198 ns/op	  56.09 MB/s
 BenchmarkJSONEncode  	 50	  31735022 ns/op	  61.15 MB/s
 `
-	new := `BenchmarkGobEncode   	 100	  11773189 ns/op	  65.19 MB/s
+
+const testNew = `BenchmarkGobEncode   	 100	  11773189 ns/op	  65.19 MB/s
 BenchmarkJSONEncode  	  50	  32036529 ns/op	  60.57 MB/s
 BenchmarkGobEncode   	 100	  11942588 ns/op	  64.27 MB/s
 BenchmarkJSONEncode  	  50	  32156552 ns/op	  60.34 MB/s
@@ -30,18 +38,373 @@ BenchmarkJSONEncode  	  50	  31559706 ns/op	  61.49 MB/s
 BenchmarkGobEncode   	 100	  11815924 ns/op	  64.96 MB/s
 BenchmarkJSONEncode  	  50	  31765634 ns/op	  61.09 MB/s
 `
+
+func TestJSONBenchstat(t *testing.T) {
+	tables, err := genBenchTables("HEAD~1", "HEAD", testOld, testNew, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.Buffer{}
+	if err = benchcmp.WriteJSON(&buf, tables); err != nil {
+		t.Fatal(err)
+	}
+	var got []*benchcmp.JSONTable
+	if err = json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Unit != "sec/op" || got[0].Better != -1 || len(got[0].Rows) != 2 {
+		t.Fatal(buf.String())
+	}
+	r := got[0].Rows[0]
+	if r.Benchmark != "GobEncode" || r.PValue == nil || *r.PValue >= 0.05 || r.Cells[0].N != 4 || r.Cells[1].N != 5 {
+		t.Fatal(buf.String())
+	}
+}
+
+func BenchmarkPrintBenchstat(b *testing.B) {
 	x := [1024]byte{}
 	buf := bytes.NewBuffer(x[:])
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		t, err := genBenchTables("HEAD~1", "HEAD", old, new)
+		t, err := genBenchTables("HEAD~1", "HEAD", testOld, testNew, defaultTableOptions)
 		if err != nil {
 			b.Fatal(err)
 		}
-		if err := printBenchstat(buf, t); err != nil {
+		if err := benchcmp.WriteText(buf, t, nil); err != nil {
 			b.Fatal(err)
 		}
 		buf.Reset()
 	}
 }
+
+func TestMarkdownBenchstat(t *testing.T) {
+	tables, err := genBenchTables("HEAD~1", "HEAD", testOld, testNew, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.Buffer{}
+	if err = markdownBenchstat(&buf, tables); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, "| name | HEAD~1 sec/op | HEAD sec/op | delta | |\n") || !strings.Contains(got, "| GobEncode | 13.58m ± ∞ | 11.79m ± ∞ | **-13.21%** | (p=0.016 n=4+5) ¹ |\n") || !strings.Contains(got, "\n¹ need >= 6 samples for confidence interval at level 0.95\n") {
+		t.Fatal(got)
+	}
+}
+
+func TestMarkdownBenchstatOneSided(t *testing.T) {
+	old := strings.Repeat("BenchmarkA \t1\t10 ns/op\nBenchmarkOld \t1\t5 ns/op\n", 2)
+	new := strings.Repeat("BenchmarkA \t1\t12 ns/op\n", 2)
+	tables, err := genBenchTables("HEAD~1", "HEAD", old, new, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.Buffer{}
+	if err = markdownBenchstat(&buf, tables); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, "| A | 10.00n ± ∞ | 12.00n ± ∞ |") || strings.Contains(got, "| Old | 5.000n ± ∞ |  |") || !strings.Contains(got, "\n\n| only in old | sec/op | |\n|:-----|-----:|:--|\n| Old | 5.000n ± ∞ | ¹ |\n") {
+		t.Fatal(got)
+	}
+}
+
+func TestMarkdownBenchstatNoisy(t *testing.T) {
+	old := strings.Repeat("BenchmarkA \t1\t10 ns/op\nBenchmarkA \t1\t20 ns/op\n", 2)
+	new := strings.Repeat("BenchmarkA \t1\t12 ns/op\n", 4)
+	topts := *defaultTableOptions
+	topts.MaxCV = 5
+	tables, err := genBenchTables("HEAD~1", "HEAD", old, new, &topts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.Buffer{}
+	if err = markdownBenchstat(&buf, tables); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if !strings.HasPrefix(got, "| too noisy to compare | HEAD~1 CV | HEAD CV | |\n|:-----|-----:|-----:|:--|\n| A | 38.5% | 0.0% | ¹ |\n") {
+		t.Fatal(got)
+	}
+}
+
+func TestPrintBenchstatColor(t *testing.T) {
+	tables, err := genBenchTables("HEAD~1", "HEAD", testOld, testNew, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.Buffer{}
+	if err = benchcmp.WriteText(&buf, tables[:1], &benchcmp.TextOptions{Color: true}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if strings.Contains(lines[0], "\033") {
+		t.Fatal(lines[0])
+	}
+	if !strings.HasPrefix(lines[1], ansi.LightGreen+"GobEncode ") || !strings.HasSuffix(lines[1], ansi.Reset) {
+		t.Fatalf("%q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], ansi.ColorCode("default+d")+"JSONEncode ") {
+		t.Fatalf("%q", lines[2])
+	}
+	// The columns are aligned as without colors.
+	plain := bytes.Buffer{}
+	if err = benchcmp.WriteText(&plain, tables[:1], nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.NewReplacer(ansi.LightGreen, "", ansi.ColorCode("default+d"), "", ansi.Reset, "").Replace(buf.String()); got != plain.String() {
+		t.Fatalf("%q\n!=\n%q", plain.String(), got)
+	}
+}
+
+func TestCheckRegressions(t *testing.T) {
+	// Swap old and new so GobEncode regresses.
+	tables, err := genBenchTables("HEAD~1", "HEAD", testNew, testOld, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := []*comparison{{old: "HEAD~1", new: "HEAD", tables: tables}}
+	if err = checkRegressions(c, 20, nil); err != nil {
+		t.Fatal(err)
+	}
+	err = checkRegressions(c, 14, nil)
+	if err == nil || err.Error() != "1 benchmark(s) regressed by more than 14%:\n  GobEncode sec/op +15.22%" {
+		t.Fatal(err)
+	}
+	var per thresholds
+	if err = per.Set("^Gob=20%"); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkRegressions(c, 14, per); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkRegressions(c, -1, thresholds{{regexp.MustCompile("Encode"), 14}}); err == nil || err.Error() != "1 benchmark(s) regressed by more than their threshold:\n  GobEncode sec/op +15.22%" {
+		t.Fatal(err)
+	}
+	if err = checkRegressions(c, -1, thresholds{{regexp.MustCompile("Decode"), 10}}); err != nil {
+		t.Fatal(err)
+	}
+	// The GOMAXPROCS suffix is ignored, like with -early-stop.
+	procs := func(s string) string {
+		return regexp.MustCompile(`(?m)^(Benchmark\S+)`).ReplaceAllString(s, "$1-8")
+	}
+	if tables, err = genBenchTables("HEAD~1", "HEAD", procs(testNew), procs(testOld), defaultTableOptions); err != nil {
+		t.Fatal(err)
+	}
+	c = []*comparison{{old: "HEAD~1", new: "HEAD", tables: tables}}
+	if err = checkRegressions(c, 14, thresholds{{regexp.MustCompile("^GobEncode$"), 20}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkRegressions(c, 20, thresholds{{regexp.MustCompile("^GobEncode$"), 14}}); err == nil {
+		t.Fatal("expected regression")
+	}
+	if l := (thresholds{{regexp.MustCompile("^A$"), 3}}).limit("A-8", 5); l != 3 {
+		t.Fatal(l)
+	}
+}
+
+func TestPrintGHA(t *testing.T) {
+	tables, err := genBenchTables("HEAD~1", "HEAD", testNew, testOld, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := []*comparison{{old: "HEAD~1", new: "HEAD", tables: tables}}
+	summary := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", summary)
+	var b bytes.Buffer
+	if err = printGHA(&b, c, 14, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	want := "\n::error title=Benchmark regression::GobEncode sec/op +15.22%25\n" +
+		"::warning title=Benchmark regression::GobEncode B/s -13.21%25\n"
+	if !strings.HasSuffix(got, want) {
+		t.Fatal(got)
+	}
+	d, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(d), "## Benchmarks\n\n| name |") {
+		t.Fatal(string(d))
+	}
+}
+
+func TestDeltaTestFlag(t *testing.T) {
+	d := deltaTestFlag("utest")
+	for _, v := range []string{"utest", "ttest", "none"} {
+		if err := d.Set(v); err != nil || d.String() != v {
+			t.Fatal(v, err)
+		}
+	}
+	if err := d.Set("foo"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestPercent(t *testing.T) {
+	var p percent
+	for _, v := range []string{"10%", "10"} {
+		if err := p.Set(v); err != nil || p != 10 {
+			t.Fatal(v, p, err)
+		}
+	}
+	if p.String() != "10%" {
+		t.Fatal(p.String())
+	}
+	if err := p.Set("a%"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGenBenchTablesProcs(t *testing.T) {
+	old := strings.Repeat("BenchmarkA \t1\t10 ns/op\nBenchmarkA-4 \t1\t5 ns/op\n", 6)
+	tables, err := genBenchTables("old", "new", old, old, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 || tables[0].Procs != 1 || tables[1].Procs != 4 {
+		t.Fatalf("%+v", tables)
+	}
+	if n := tables[1].Name(tables[1].Rows[0]); n != "A-4" {
+		t.Fatal(n)
+	}
+	// A single GOMAXPROCS value is kept in the name.
+	tables, err = genBenchTables("old", "new", strings.Repeat("BenchmarkA-4 \t1\t5 ns/op\n", 6), strings.Repeat("BenchmarkA-4 \t1\t6 ns/op\n", 6), defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].Procs != 0 || tables[0].Rows[0].Benchmark != "A-4" {
+		t.Fatalf("%+v", tables[0])
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	tables, err := genBenchTables("HEAD~1", "HEAD", testOld, testNew, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = printComparisons(&buf, "html", []*comparison{{old: "HEAD~1", new: "HEAD", tables: tables}}, false); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<th>HEAD~1</th><th>HEAD</th><th>delta</th>",
+		`<tr class="better"><td>GobEncode</td><td>13.58m ± ∞</td><td>11.79m ± ∞</td><td>-13.21%</td><td>(p=0.016 n=4&#43;5) ¹</td><td><svg `,
+		"need &gt;= 6 samples for confidence interval at level 0.95",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("missing %q\n%s", want, got)
+		}
+	}
+	// One box and one dot per value.
+	if n := strings.Count(got, "<rect "); n != 8 {
+		t.Fatal(n)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	v := []float64{1, 2, 3, 4, 5}
+	if q := quantile(v, 0.5); q != 3 {
+		t.Fatal(q)
+	}
+	if q := quantile(v, 0.25); q != 2 {
+		t.Fatal(q)
+	}
+	if q := quantile([]float64{1, 2}, 0.5); q != 1.5 {
+		t.Fatal(q)
+	}
+	if q := quantile([]float64{7}, 0.75); q != 7 {
+		t.Fatal(q)
+	}
+}
+
+func TestPrintComparisonsStats(t *testing.T) {
+	tables, err := genBenchTables("HEAD~1", "HEAD", testOld, testNew, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = printComparisons(&buf, "text", []*comparison{{old: "HEAD~1", new: "HEAD", tables: tables[:1]}}, true); err != nil {
+		t.Fatal(err)
+	}
+	want := "name        old sec/op  new sec/op    delta      p    n       d\n" +
+		"GobEncode   13.58m ± ∞  11.79m ± ∞  -13.21%  0.016  4+5  -19.29  ¹\n"
+	if got := buf.String(); !strings.HasPrefix(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestPrintPartial(t *testing.T) {
+	tables, err := genBenchTables("HEAD~1", "HEAD", testOld, testNew, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &session{
+		Sides:   []*sessionSide{{Name: "HEAD~1", Completed: 5}, {Name: "HEAD", Completed: 4}},
+		Series:  4,
+		Partial: true,
+	}
+	var buf bytes.Buffer
+	if err = printPartial(&buf, "text", s, []*comparison{{old: "HEAD~1", new: "HEAD", tables: tables[:1]}}, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "PARTIAL RESULTS: only the 4 series completed by all sides are compared; series completed: HEAD~1 5, HEAD 4\n\n" +
+		"name        old sec/op  new sec/op    delta\n"
+	if got := buf.String(); !strings.HasPrefix(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestPrintRaceWarning(t *testing.T) {
+	var buf bytes.Buffer
+	printRaceWarning(&buf, "text", &session{})
+	if buf.Len() != 0 {
+		t.Fatal(buf.String())
+	}
+	s := &session{Sides: []*sessionSide{{Name: "HEAD"}}, Race: true}
+	printRaceWarning(&buf, "markdown", s)
+	if want := "> **Race detector**: the test binaries were built with -race, which inflates the absolute numbers several times; only the comparison is meaningful\n\n"; buf.String() != want {
+		t.Fatal(buf.String())
+	}
+	if got := strings.Join(s.config(0), "\n"); got != "race: on" {
+		t.Fatal(got)
+	}
+}
+
+func TestBetterFlag(t *testing.T) {
+	b := betterFlag{}
+	for _, v := range []string{"hit-ratio=higher", "p99-ns=lower"} {
+		if err := b.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := b.String(); got != "hit-ratio=higher,p99-sec=lower" {
+		t.Fatal(got)
+	}
+	for _, v := range []string{"a", "=higher", "a=better"} {
+		if b.Set(v) == nil {
+			t.Fatal(v)
+		}
+	}
+}
+
+func TestRenamesFlag(t *testing.T) {
+	r := renamesFlag{}
+	for _, v := range []string{"BenchmarkParse=BenchmarkParseJSON", "A=B"} {
+		if err := r.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := r.String(); got != "A=B,Parse=ParseJSON" {
+		t.Fatal(got)
+	}
+	for _, v := range []string{"A=C", "=B", "A=", "A"} {
+		if r.Set(v) == nil {
+			t.Fatal(v)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/maruel/pat/benchcmp"
+)
+
+// markdownBenchstat prints the tables as GitHub flavored markdown tables.
+// Significant deltas are in bold.
+func markdownBenchstat(w io.Writer, tables []*table) error {
+	for i, t := range tables {
+		if i != 0 {
+			if _, err := fmt.Fprintf(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if t.Procs != 0 {
+			if _, err := fmt.Fprintf(w, "GOMAXPROCS=%d\n\n", t.Procs); err != nil {
+				return err
+			}
+		}
+		hdr := "| name |"
+		sep := "|:-----|"
+		for _, c := range t.Configs {
+			hdr += " " + mdEscape(c) + " " + t.Unit + " |"
+			sep += "-----:|"
+		}
+		if t.Delta {
+			hdr += " delta |"
+			sep += "-----:|"
+		}
+		hdr += " |"
+		sep += ":--|"
+		markers, notes := benchcmp.Footnotes(t)
+		var lines []string
+		oneSided, noisy := false, false
+		group := ""
+		for j, row := range t.Rows {
+			if row.Noisy {
+				noisy = true
+				continue
+			}
+			if t.OneSided(row) != -1 {
+				oneSided = true
+				continue
+			}
+			name := row.Benchmark
+			if row.Group != group {
+				group = row.Group
+				if group != "" {
+					// The heading of the group, followed by the sub-benchmarks
+					// indented.
+					lines = append(lines, "| **"+mdEscape(group)+"** |"+strings.Repeat(" |", strings.Count(hdr, "|")-2))
+				}
+			}
+			if group != "" {
+				name = "&nbsp;&nbsp;" + name[len(group)+1:]
+			}
+			l := "| " + mdEscape(name) + " |"
+			for _, c := range row.Cells {
+				if c != nil {
+					l += " " + c.Format(t.Unit)
+				}
+				l += " |"
+			}
+			if t.Delta {
+				d := row.Delta
+				if row.Change != 0 {
+					d = "**" + d + "**"
+				}
+				l += " " + d + " |"
+			}
+			note := row.Note
+			if note != "" {
+				note = "(" + note + ")"
+			}
+			lines = append(lines, l+" "+strings.TrimSpace(note+" "+markers[j])+" |")
+		}
+		sections := 0
+		if len(lines) != 0 || (!oneSided && !noisy) {
+			if _, err := fmt.Fprintf(w, "%s\n%s\n%s", hdr, sep, strings.Join(append(lines, ""), "\n")); err != nil {
+				return err
+			}
+			sections++
+		}
+		for i, c := range []string{"old", "new"} {
+			var l []string
+			for j, row := range t.Rows {
+				if !row.Noisy && t.OneSided(row) == i {
+					l = append(l, "| "+mdEscape(row.Benchmark)+" | "+row.Cells[i].Format(t.Unit)+" | "+markers[j]+" |")
+				}
+			}
+			if len(l) == 0 {
+				continue
+			}
+			if sections != 0 {
+				if _, err := fmt.Fprintf(w, "\n"); err != nil {
+					return err
+				}
+			}
+			sections++
+			if _, err := fmt.Fprintf(w, "| only in %s | %s | |\n|:-----|-----:|:--|\n%s\n", c, t.Unit, strings.Join(l, "\n")); err != nil {
+				return err
+			}
+		}
+		if noisy {
+			if sections != 0 {
+				if _, err := fmt.Fprintf(w, "\n"); err != nil {
+					return err
+				}
+			}
+			h := "| too noisy to compare |"
+			sp := "|:-----|"
+			for _, c := range t.Configs {
+				h += " " + mdEscape(c) + " CV |"
+				sp += "-----:|"
+			}
+			var l []string
+			for j, row := range t.Rows {
+				if row.Noisy {
+					l = append(l, "| "+mdEscape(row.Benchmark)+" | "+strings.Join(cvCells(row), " | ")+" | "+markers[j]+" |")
+				}
+			}
+			if _, err := fmt.Fprintf(w, "%s |\n%s:--|\n%s\n", h, sp, strings.Join(l, "\n")); err != nil {
+				return err
+			}
+		}
+		if len(notes) != 0 {
+			if _, err := fmt.Fprintf(w, "\n%s\n", strings.Join(notes, "<br>\n")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cvCells returns the coefficient of variation of each configuration of a row
+// too noisy to compare.
+func cvCells(r *row) []string {
+	out := make([]string, len(r.Cells))
+	for i, c := range r.Cells {
+		if c != nil {
+			if cv := c.CV(); !math.IsNaN(cv) {
+				out[i] = fmt.Sprintf("%.1f%%", cv)
+			}
+		}
+	}
+	return out
+}
+
+// mdEscape escapes characters that would break a markdown table cell.
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", "\\|", "*", "\\*", "_", "\\_").Replace(s)
+}
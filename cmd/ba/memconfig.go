@@ -0,0 +1,49 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseMemConfigs parses the -memconfig flag.
+//
+// Configurations are separated with ';' and each configuration is a space
+// separated list of settings. A setting is either thp=on, thp=off or an
+// environment variable like GOMEMLIMIT=1GiB. The returned sides always start
+// with the default configuration as the baseline.
+func parseMemConfigs(v string) ([]*side, error) {
+	sides := []*side{{name: "default"}}
+	for _, c := range strings.Split(v, ";") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		s := &side{name: c}
+		for _, f := range strings.Fields(c) {
+			i := strings.IndexByte(f, '=')
+			if i <= 0 {
+				return nil, fmt.Errorf("invalid memory setting %q; expected KEY=VALUE", f)
+			}
+			switch k, val := f[:i], f[i+1:]; k {
+			case "thp":
+				switch val {
+				case "on", "off":
+					s.thp = val
+				default:
+					return nil, fmt.Errorf("invalid memory setting %q; thp must be on or off", f)
+				}
+			default:
+				s.env = append(s.env, f)
+			}
+		}
+		sides = append(sides, s)
+	}
+	if len(sides) == 1 {
+		return nil, fmt.Errorf("-memconfig %q has no configuration", v)
+	}
+	return sides, nil
+}
@@ -0,0 +1,31 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMemConfigs(t *testing.T) {
+	got, err := parseMemConfigs("thp=off; thp=on; GODEBUG=madvdontneed=1 GOMEMLIMIT=1GiB;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*side{
+		{name: "default"},
+		{name: "thp=off", thp: "off"},
+		{name: "thp=on", thp: "on"},
+		{name: "GODEBUG=madvdontneed=1 GOMEMLIMIT=1GiB", env: []string{"GODEBUG=madvdontneed=1", "GOMEMLIMIT=1GiB"}},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("%#v != %#v", want, got)
+	}
+	for _, v := range []string{"", ";", "thp=maybe", "GOGC"} {
+		if _, err := parseMemConfigs(v); err == nil {
+			t.Fatalf("%q: expected error", v)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mergeInput is a benchmark output to merge.
+type mergeInput struct {
+	path   string
+	label  string
+	output string
+}
+
+// readMergeInputs reads the results saved in path: a raw benchmark output as
+// written by -o or -save-baseline, or the output of each side of a bundle or
+// a -o directory.
+//
+// Each result is labeled by the value of the configuration key by when
+// present, otherwise by the file name or the side name.
+func readMergeInputs(path, by string) ([]*mergeInput, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var in []*mergeInput
+	if fi.IsDir() || strings.HasSuffix(path, ".zip") {
+		s, err := readBundle(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, ss := range s.Sides {
+			in = append(in, &mergeInput{path: path, label: ss.Name, output: ss.Output})
+		}
+	} else {
+		/* #nosec G304 */
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		in = append(in, &mergeInput{path: path, label: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), output: string(b)})
+	}
+	if by != "" {
+		for _, i := range in {
+			if v := configValue(i.output, by); v != "" {
+				i.label = v
+			}
+		}
+	}
+	return in, nil
+}
+
+// configValue returns the last value of the benchfmt configuration key in
+// out, or "" if it is not set.
+func configValue(out, key string) string {
+	v := ""
+	for _, l := range strings.Split(out, "\n") {
+		if strings.HasPrefix(l, key+":") {
+			v = strings.TrimSpace(l[len(key)+1:])
+		}
+	}
+	return v
+}
+
+// mergeSession pools the results with the same label into one side each, in
+// the order they are first seen. The first side is the baseline.
+//
+// Results recorded on different machines or toolchains are refused unless
+// allowMixed is set, since they cannot be meaningfully pooled.
+func mergeSession(in []*mergeInput, allowMixed bool) (*session, error) {
+	var ref *mergeInput
+	for _, i := range in {
+		m := outputMachine(i.output)
+		if len(m) == 0 {
+			continue
+		}
+		if ref == nil {
+			ref = i
+			continue
+		}
+		if d := machineDiff(outputMachine(ref.output), m); len(d) != 0 {
+			if !allowMixed {
+				return nil, fmt.Errorf("%s was recorded on a different machine than %s, use -allow-mixed to merge anyway:\n  %s", i.path, ref.path, strings.Join(d, "\n  "))
+			}
+			stderrLog.warnf("%s was recorded on a different machine than %s:\n  %s", i.path, ref.path, strings.Join(d, "\n  "))
+		}
+	}
+	s := &session{}
+	sides := map[string]*sessionSide{}
+	for _, i := range in {
+		ss := sides[i.label]
+		if ss == nil {
+			ss = &sessionSide{Name: i.label}
+			sides[i.label] = ss
+			s.Sides = append(s.Sides, ss)
+		}
+		if ss.Output != "" && !strings.HasSuffix(ss.Output, "\n") {
+			ss.Output += "\n"
+		}
+		ss.Output += i.output
+	}
+	if len(s.Sides) < 2 {
+		return nil, errors.New("merge: all the results have the same label, nothing to compare; use -by to select the configuration key to compare")
+	}
+	return s, nil
+}
+
+// mergeMain implements "ba merge", which compares results saved by previous
+// runs, e.g. to aggregate nightly runs.
+func mergeMain(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	format := fs.String("format", "text", "format to print; one of text, json, markdown or html")
+	by := fs.String("by", "", "benchfmt configuration key labeling the results to compare, e.g. 'commit'; results with the same label are pooled; defaults to the file name, or the side name for a bundle or a -o directory")
+	allowMixed := fs.Bool("allow-mixed", false, "merge results recorded on different machines or toolchains")
+	alpha := fs.Float64("alpha", 0.05, "p-value cutoff to consider a change significant")
+	geomean := fs.Bool("geomean", false, "add a row with the geometric mean of all the benchmarks")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: ba merge <flags> <files>\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "ba merge compares the results saved by -o, -bundle or -save-baseline,\n")
+		fmt.Fprintf(os.Stderr, "pooling the results with the same label.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fs.PrintDefaults()
+	}
+	// Allow the flags after the files.
+	var paths []string
+	for {
+		_ = fs.Parse(args)
+		if fs.NArg() == 0 {
+			break
+		}
+		paths = append(paths, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	switch *format {
+	case "text", "json", "markdown", "html":
+	default:
+		return fmt.Errorf("merge: unsupported -format %s", *format)
+	}
+	if len(paths) == 0 {
+		return errors.New("merge: specify the files to merge")
+	}
+	var in []*mergeInput
+	for _, p := range paths {
+		i, err := readMergeInputs(p, *by)
+		if err != nil {
+			return err
+		}
+		in = append(in, i...)
+	}
+	s, err := mergeSession(in, *allowMixed)
+	if err != nil {
+		return err
+	}
+	c, err := genComparisons(s, &tableOptions{Alpha: *alpha, DeltaTest: "utest", Geomean: *geomean})
+	if err != nil {
+		return err
+	}
+	return printComparisons(os.Stdout, *format, c, false)
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigValue(t *testing.T) {
+	out := "commit: abc\nBenchmarkFoo 1 2 ns/op\ncommit: def\nBenchmarkFoo 1 2 ns/op\n"
+	if got := configValue(out, "commit"); got != "def" {
+		t.Fatal(got)
+	}
+	if got := configValue(out, "branch"); got != "" {
+		t.Fatal(got)
+	}
+}
+
+func TestMergeSession(t *testing.T) {
+	d := t.TempDir()
+	files := map[string]string{
+		"mon.bench": "cores: 8\nbranch: main\nBenchmarkFoo 1 2 ns/op\n",
+		"tue.bench": "cores: 8\nbranch: main\nBenchmarkFoo 1 3 ns/op",
+		"pr.bench":  "cores: 8\nbranch: pr\nBenchmarkFoo 1 4 ns/op\n",
+		"big.bench": "cores: 64\nbranch: pr\nBenchmarkFoo 1 1 ns/op\n",
+	}
+	for n, c := range files {
+		if err := os.WriteFile(filepath.Join(d, n), []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(by string, names ...string) []*mergeInput {
+		var in []*mergeInput
+		for _, n := range names {
+			i, err := readMergeInputs(filepath.Join(d, n), by)
+			if err != nil {
+				t.Fatal(err)
+			}
+			in = append(in, i...)
+		}
+		return in
+	}
+	s, err := mergeSession(read("branch", "mon.bench", "tue.bench", "pr.bench"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.names(); len(got) != 2 || got[0] != "main" || got[1] != "pr" {
+		t.Fatal(got)
+	}
+	if want := files["mon.bench"] + files["tue.bench"]; s.Sides[0].Output != want {
+		t.Fatalf("%q != %q", want, s.Sides[0].Output)
+	}
+	if s, err = mergeSession(read("", "mon.bench", "pr.bench"), false); err != nil || s.Sides[0].Name != "mon" {
+		t.Fatal(err)
+	}
+	if _, err = mergeSession(read("branch", "mon.bench", "tue.bench"), false); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err = mergeSession(read("branch", "mon.bench", "big.bench"), false); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err = mergeSession(read("branch", "mon.bench", "big.bench"), true); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// modulePattern is a package pattern to run the go command with, from a
+// module directory.
+type modulePattern struct {
+	dir, pattern string
+}
+
+// splitModules returns the package patterns to run the go command with for
+// -pkg, from dir.
+//
+// The go command stops at module boundaries and the root of a go.work
+// workspace is usually not a module itself, so a ./... pattern is expanded to
+// ./... in each module under it, run from the module's directory.
+func splitModules(dir, pkg string) []modulePattern {
+	def := []modulePattern{{dir: dir, pattern: pkg}}
+	sub := ""
+	switch {
+	case pkg == "./...":
+	case strings.HasPrefix(pkg, "./") && strings.HasSuffix(pkg, "/..."):
+		sub = strings.TrimSuffix(pkg[2:], "/...")
+	default:
+		return def
+	}
+	base := dir
+	if base == "" {
+		base = "."
+	}
+	root := filepath.Join(base, sub)
+	var nested []string
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || p == root {
+			return nil
+		}
+		// Like the go command.
+		if n := d.Name(); n == "vendor" || n == "testdata" || strings.HasPrefix(n, ".") || strings.HasPrefix(n, "_") {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+			nested = append(nested, p)
+		}
+		return nil
+	})
+	// A nested module must be listed from its own directory.
+	first := def[0]
+	if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil && root != filepath.Clean(base) {
+		first = modulePattern{dir: root, pattern: "./..."}
+	}
+	if len(nested) == 0 {
+		return []modulePattern{first}
+	}
+	var out []modulePattern
+	if inModule(root) {
+		out = append(out, first)
+	}
+	for _, n := range nested {
+		out = append(out, modulePattern{dir: n, pattern: "./..."})
+	}
+	return out
+}
+
+// inModule returns true if dir is in a module, i.e. it or one of its parents
+// contains a go.mod.
+func inModule(dir string) bool {
+	a, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(a, "go.mod")); err == nil {
+			return true
+		}
+		p := filepath.Dir(a)
+		if p == a {
+			return false
+		}
+		a = p
+	}
+}
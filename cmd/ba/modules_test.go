@@ -0,0 +1,52 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitModules(t *testing.T) {
+	d := t.TempDir()
+	for _, f := range []string{"a/go.mod", "b/c/go.mod", "b/c/vendor/x/go.mod", "testdata/go.mod", ".hidden/go.mod", "go.work"} {
+		p := filepath.Join(d, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The workspace root is not a module.
+	want := []modulePattern{{filepath.Join(d, "a"), "./..."}, {filepath.Join(d, "b", "c"), "./..."}}
+	if got := splitModules(d, "./..."); !reflect.DeepEqual(want, got) {
+		t.Fatalf("%v != %v", want, got)
+	}
+	want = []modulePattern{{filepath.Join(d, "b", "c"), "./..."}}
+	if got := splitModules(d, "./b/..."); !reflect.DeepEqual(want, got) {
+		t.Fatalf("%v != %v", want, got)
+	}
+	want = []modulePattern{{filepath.Join(d, "a"), "./..."}}
+	if got := splitModules(d, "./a/..."); !reflect.DeepEqual(want, got) {
+		t.Fatalf("%v != %v", want, got)
+	}
+	// A pattern that is not a directory tree is left as is.
+	for _, pkg := range []string{".", "example.com/..."} {
+		want = []modulePattern{{d, pkg}}
+		if got := splitModules(d, pkg); !reflect.DeepEqual(want, got) {
+			t.Fatalf("%s: %v != %v", pkg, want, got)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(d, "go.mod"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want = []modulePattern{{d, "./..."}, {filepath.Join(d, "a"), "./..."}, {filepath.Join(d, "b", "c"), "./..."}}
+	if got := splitModules(d, "./..."); !reflect.DeepEqual(want, got) {
+		t.Fatalf("%v != %v", want, got)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// notification is the payload posted to -notify-url. text makes it
+// compatible with Slack like incoming webhooks.
+type notification struct {
+	Text        string   `json:"text"`
+	Repo        string   `json:"repo,omitempty"`
+	Host        string   `json:"host,omitempty"`
+	Comparisons []string `json:"comparisons"`
+	Threshold   float64  `json:"threshold"`
+	Regressions []string `json:"regressions"`
+}
+
+// notifyRegressions posts a notification to u when a statistically
+// significant regression larger than threshold percent is found. Slack
+// webhooks only get the text.
+//
+// It returns true if a notification was sent.
+func notifyRegressions(ctx context.Context, c *http.Client, u string, cmp []*comparison, threshold float64) (bool, error) {
+	r := regressions(cmp, threshold, nil)
+	if len(r) == 0 {
+		return false, nil
+	}
+	n := &notification{Threshold: threshold, Regressions: r}
+	if out, err := repo.root(""); err == nil {
+		n.Repo = out
+	}
+	n.Host, _ = os.Hostname()
+	for _, x := range cmp {
+		n.Comparisons = append(n.Comparisons, x.old+" vs "+x.new)
+	}
+	n.Text = fmt.Sprintf("ba: %d benchmark(s) regressed by more than %g%% in %s:\n• %s", len(r), threshold, strings.Join(n.Comparisons, ", "), strings.Join(r, "\n• "))
+	var payload interface{} = n
+	if p, err := url.Parse(u); err == nil && p.Host == "hooks.slack.com" {
+		payload = map[string]string{"text": n.Text}
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(b))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return false, fmt.Errorf("-notify-url: %s\n%s", resp.Status, body)
+	}
+	return true, nil
+}
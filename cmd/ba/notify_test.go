@@ -0,0 +1,50 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNotifyRegressions(t *testing.T) {
+	tables, err := genBenchTables("HEAD~1", "HEAD", testNew, testOld, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := []*comparison{{old: "HEAD~1", new: "HEAD", tables: tables}}
+	var got []*notification
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := &notification{}
+		if err := json.NewDecoder(r.Body).Decode(n); err != nil {
+			t.Error(err)
+		}
+		got = append(got, n)
+	}))
+	defer s.Close()
+	ctx := context.Background()
+	if sent, err := notifyRegressions(ctx, s.Client(), s.URL, c, 50); sent || err != nil {
+		t.Fatal(sent, err)
+	}
+	if sent, err := notifyRegressions(ctx, s.Client(), s.URL, c, 14); !sent || err != nil {
+		t.Fatal(sent, err)
+	}
+	if len(got) != 1 {
+		t.Fatal(got)
+	}
+	if want := []string{"GobEncode sec/op +15.22%"}; !reflect.DeepEqual(got[0].Regressions, want) {
+		t.Fatal(got[0].Regressions)
+	}
+	if want := []string{"HEAD~1 vs HEAD"}; !reflect.DeepEqual(got[0].Comparisons, want) {
+		t.Fatal(got[0].Comparisons)
+	}
+	if got[0].Text == "" {
+		t.Fatal("missing text")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+// numaNodes returns the number of NUMA nodes on the system.
+func numaNodes() (int, error) {
+	if runtime.GOOS != "linux" {
+		return 0, errors.New("NUMA pinning is only supported on linux")
+	}
+	m, err := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	if err != nil {
+		return 0, err
+	}
+	if len(m) == 0 {
+		return 0, errors.New("no NUMA node found")
+	}
+	return len(m), nil
+}
+
+// numaWrap returns the numactl command prefix to run the benchmark process
+// on the CPUs of NUMA node cpu with its memory allocated on NUMA node mem.
+func numaWrap(cpu, mem int) ([]string, error) {
+	n, err := numaNodes()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range []int{cpu, mem} {
+		if i < 0 || i >= n {
+			return nil, fmt.Errorf("invalid NUMA node %d; this system has %d node(s)", i, n)
+		}
+	}
+	if _, err = exec.LookPath("numactl"); err != nil {
+		return nil, errors.New("numactl is required for NUMA pinning")
+	}
+	return []string{"numactl", "--cpunodebind=" + strconv.Itoa(cpu), "--membind=" + strconv.Itoa(mem)}, nil
+}
+
+// numaCrossSides returns the sides to compare memory on the local node vs
+// memory on a remote node.
+func numaCrossSides(node, remote int) ([]*side, error) {
+	if node == -1 {
+		node = 0
+	}
+	if node == remote {
+		return nil, errors.New("-numa-cross must be different from -numa-node")
+	}
+	local, err := numaWrap(node, node)
+	if err != nil {
+		return nil, err
+	}
+	cross, err := numaWrap(node, remote)
+	if err != nil {
+		return nil, err
+	}
+	stderrLog.infof("comparing memory on node %d vs node %d, running on node %d", node, remote, node)
+	return []*side{
+		{name: "mem=node" + strconv.Itoa(node), wrap: local},
+		{name: "mem=node" + strconv.Itoa(remote), wrap: cross},
+	}, nil
+}
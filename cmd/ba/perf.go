@@ -0,0 +1,83 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// perfEvents are the hardware counters recorded with -perf.
+var perfEvents = []string{"instructions", "cycles", "branch-misses", "cache-misses"}
+
+// perfWrap prefixes the command with perf stat, writing the counters as CSV
+// into the file out.
+func perfWrap(c *exec.Cmd, out string) error {
+	p, err := exec.LookPath("perf")
+	if err != nil {
+		return errors.New("perf is required for -perf")
+	}
+	c.Path = p
+	c.Args = append([]string{"perf", "stat", "-x", ",", "-e", strings.Join(perfEvents, ","), "-o", out, "--"}, c.Args...)
+	return nil
+}
+
+// readPerfStat reads the output of perf stat -x , and returns the counters
+// as a benchfmt line.
+//
+// The counters cover the whole process, including the benchmarks calibration
+// runs, so they are reported as a single run of the pseudo benchmark "Perf".
+func readPerfStat(path string) (string, error) {
+	/* #nosec G304 */
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	out := "BenchmarkPerf 1"
+	n := 0
+	for _, l := range strings.Split(string(b), "\n") {
+		if l == "" || l[0] == '#' {
+			continue
+		}
+		// value,unit,event,run time,percentage,...
+		f := strings.Split(l, ",")
+		if len(f) < 3 || strings.HasPrefix(f[0], "<") {
+			// <not supported> or <not counted>.
+			continue
+		}
+		event := f[2]
+		if i := strings.IndexByte(event, ':'); i != -1 {
+			// Strip the modifiers, e.g. "instructions:u".
+			event = event[:i]
+		}
+		out += " " + f[0] + " " + event
+		n++
+	}
+	if n == 0 {
+		return "", errors.New("perf stat recorded no counter; check /proc/sys/kernel/perf_event_paranoid")
+	}
+	return out + "\n", nil
+}
+
+// checkPerf fails early if -perf is not supported.
+func checkPerf() error {
+	if runtime.GOOS != "linux" {
+		return errors.New("-perf is only supported on linux")
+	}
+	if _, err := exec.LookPath("perf"); err != nil {
+		return errors.New("perf is required for -perf")
+	}
+	if b, err := os.ReadFile("/proc/sys/kernel/perf_event_paranoid"); err == nil {
+		if v, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil && v > 2 && os.Getuid() != 0 {
+			return fmt.Errorf("perf_event_paranoid is %d; hardware counters are not available to non-root users", v)
+		}
+	}
+	return nil
+}
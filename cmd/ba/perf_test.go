@@ -0,0 +1,36 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPerfStat(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "perf.csv")
+	d := "# started on Mon Jan  2 03:04:05 2026\n\n" +
+		"1234567,,instructions:u,1000,100.00,1.50,insn per cycle\n" +
+		"823045,,cycles:u,1000,100.00,,\n" +
+		"<not supported>,,branch-misses:u,0,100.00,,\n" +
+		"42,,cache-misses,1000,100.00,,\n"
+	if err := os.WriteFile(p, []byte(d), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readPerfStat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "BenchmarkPerf 1 1234567 instructions 823045 cycles 42 cache-misses\n"; got != want {
+		t.Fatalf("%q", got)
+	}
+	if err = os.WriteFile(p, []byte("<not supported>,,cycles,0,100.00,,\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = readPerfStat(p); err == nil {
+		t.Fatal("expected error")
+	}
+}
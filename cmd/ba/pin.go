@@ -0,0 +1,40 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseCPUList parses a linux style CPU list, e.g. "2,4-6".
+func parseCPUList(v string) ([]int, error) {
+	var out []int
+	for _, r := range strings.Split(strings.TrimSpace(v), ",") {
+		if r == "" {
+			continue
+		}
+		lo, hi := r, r
+		if i := strings.IndexByte(r, '-'); i != -1 {
+			lo, hi = r[:i], r[i+1:]
+		}
+		a, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU list %q", v)
+		}
+		b, err := strconv.Atoi(hi)
+		if err != nil || a < 0 || b < a {
+			return nil, fmt.Errorf("invalid CPU list %q", v)
+		}
+		for i := a; i <= b; i++ {
+			out = append(out, i)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("invalid CPU list %q", v)
+	}
+	return out, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// cpuMask is a cpu_set_t supporting up to 1024 CPUs.
+type cpuMask [16]uint64
+
+// pinThread sets the CPU affinity of the calling OS thread to cpus. It is
+// inherited by the child processes started from this thread, so the caller
+// must lock the goroutine to its thread.
+//
+// The returned function must be called to restore the previous affinity.
+func pinThread(cpus []int) (func(), error) {
+	var old, m cpuMask
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, 0, unsafe.Sizeof(old), uintptr(unsafe.Pointer(&old))); errno != 0 {
+		return nil, errno
+	}
+	for _, c := range cpus {
+		if c >= len(m)*64 {
+			return nil, fmt.Errorf("CPU %d is out of range", c)
+		}
+		m[c/64] |= 1 << uint(c%64)
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(m), uintptr(unsafe.Pointer(&m))); errno != 0 {
+		return nil, fmt.Errorf("failed to pin to CPUs %v: %w", cpus, errno)
+	}
+	return func() {
+		_, _, _ = syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(old), uintptr(unsafe.Pointer(&old)))
+	}, nil
+}
+
+// checkIsolated warns about CPUs that are not isolated from the scheduler via
+// the isolcpus kernel parameter.
+func checkIsolated(cpus []int) {
+	b, err := os.ReadFile("/sys/devices/system/cpu/isolated")
+	if err != nil {
+		return
+	}
+	iso := map[int]bool{}
+	if l, err := parseCPUList(string(b)); err == nil {
+		for _, c := range l {
+			iso[c] = true
+		}
+	}
+	for _, c := range cpus {
+		if !iso[c] {
+			stderrLog.warnf("CPU %d is not isolated; consider booting with isolcpus", c)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+//go:build !linux
+
+package main
+
+import "errors"
+
+func pinThread(cpus []int) (func(), error) {
+	return nil, errors.New("-pin is only supported on linux")
+}
+
+func checkIsolated(cpus []int) {
+}
@@ -0,0 +1,25 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	got, err := parseCPUList("2,4-6\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 4, 5, 6}; !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+	for _, v := range []string{"", "a", "3-1", "-1", "1-"} {
+		if _, err = parseCPUList(v); err == nil {
+			t.Fatalf("%q: expected error", v)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// presets are the flags set by -quick and -thorough.
+var presets = map[string][]configEntry{
+	// A fast sanity check: 4 samples per side is the minimum for a
+	// significant delta.
+	"quick": {
+		{name: "benchtime", value: "50ms"},
+		{name: "count", value: "2"},
+		{name: "series", value: "2"},
+		{name: "alpha", value: "0.1"},
+	},
+	// For results worth publishing: more samples, a warmup series, a lower
+	// false positive rate and a quiet machine.
+	"thorough": {
+		{name: "benchtime", value: "1s"},
+		{name: "count", value: "5"},
+		{name: "series", value: "10"},
+		{name: "alpha", value: "0.01"},
+		{name: "nowarm", value: "false"},
+		{name: "strict-env", value: "true"},
+	},
+}
+
+// applyPreset sets the flags of the preset that were not specified on the
+// command line or in the config file.
+func applyPreset(fs *flag.FlagSet, name string) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, e := range presets[name] {
+		if set[e.name] {
+			continue
+		}
+		if err := fs.Set(e.name, e.value); err != nil {
+			return fmt.Errorf("-%s: %s: %w", name, e.name, err)
+		}
+	}
+	return nil
+}
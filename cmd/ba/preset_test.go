@@ -0,0 +1,38 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestApplyPreset(t *testing.T) {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	benchtime := fs.Duration("benchtime", 100*time.Millisecond, "")
+	count := fs.Int("count", 2, "")
+	series := fs.Int("series", 3, "")
+	alpha := fs.Float64("alpha", 0.05, "")
+	nowarm := fs.Bool("nowarm", true, "")
+	strict := fs.Bool("strict-env", false, "")
+	if err := fs.Parse([]string{"-count", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyPreset(fs, "thorough"); err != nil {
+		t.Fatal(err)
+	}
+	// -count is kept.
+	if *benchtime != time.Second || *count != 3 || *series != 10 || *alpha != 0.01 || *nowarm || !*strict {
+		t.Fatal(*benchtime, *count, *series, *alpha, *nowarm, *strict)
+	}
+	if err := applyPreset(fs, "quick"); err != nil {
+		t.Fatal(err)
+	}
+	// Everything was set by then.
+	if *benchtime != time.Second {
+		t.Fatal(*benchtime)
+	}
+}
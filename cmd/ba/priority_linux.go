@@ -0,0 +1,56 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// schedFIFO is SCHED_FIFO from linux/sched.h.
+const schedFIFO = 1
+
+// priorityThread sets the niceness of the calling OS thread to nice and, when
+// fifo is set, its scheduling policy to SCHED_FIFO at the lowest real-time
+// priority, like nice and chrt -f 1. They are inherited by the child processes
+// started from this thread, so the caller must lock the goroutine to its
+// thread.
+//
+// It requires root or CAP_SYS_NICE. The returned function must be called to
+// restore the previous priority.
+func priorityThread(nice int, fifo bool) (func(), error) {
+	// The raw syscall returns 20-nice, since a negative value is an error.
+	r, _, errno := syscall.RawSyscall(syscall.SYS_GETPRIORITY, syscall.PRIO_PROCESS, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	oldNice := 20 - int(r)
+	oldPolicy, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETSCHEDULER, 0, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	var oldParam int32
+	if _, _, errno = syscall.RawSyscall(syscall.SYS_SCHED_GETPARAM, 0, uintptr(unsafe.Pointer(&oldParam)), 0); errno != 0 {
+		return nil, errno
+	}
+	restore := func() {
+		_, _, _ = syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER, 0, oldPolicy, uintptr(unsafe.Pointer(&oldParam)))
+		_, _, _ = syscall.RawSyscall(syscall.SYS_SETPRIORITY, syscall.PRIO_PROCESS, 0, uintptr(oldNice))
+	}
+	if nice != 0 {
+		if _, _, errno = syscall.RawSyscall(syscall.SYS_SETPRIORITY, syscall.PRIO_PROCESS, 0, uintptr(nice)); errno != 0 {
+			return nil, fmt.Errorf("failed to set the niceness to %d: %w", nice, errno)
+		}
+	}
+	if fifo {
+		p := int32(1)
+		if _, _, errno = syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER, 0, schedFIFO, uintptr(unsafe.Pointer(&p))); errno != 0 {
+			restore()
+			return nil, fmt.Errorf("failed to set the SCHED_FIFO policy: %w", errno)
+		}
+	}
+	return restore, nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestPriorityThread(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	nice := func() int {
+		r, _, errno := syscall.RawSyscall(syscall.SYS_GETPRIORITY, syscall.PRIO_PROCESS, 0, 0)
+		if errno != 0 {
+			t.Fatal(errno)
+		}
+		return 20 - int(r)
+	}
+	old := nice()
+	restore, err := priorityThread(old-1, false)
+	if err != nil {
+		t.Skip(err)
+	}
+	if got := nice(); got != old-1 {
+		restore()
+		t.Fatal(got)
+	}
+	restore()
+	if got := nice(); got != old {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+//go:build !linux
+
+package main
+
+import "errors"
+
+func priorityThread(nice int, fifo bool) (func(), error) {
+	return nil, errors.New("-nice and -fifo are only supported on linux")
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+)
+
+// command is like exec.Command, with the child in its own process group where
+// it matters. See newProcessGroup.
+//
+// Unlike exec.CommandContext, canceling is handled by killOnCancel so the
+// whole process tree is killed.
+func command(name string, args ...string) *exec.Cmd {
+	/* #nosec G204 */
+	c := exec.Command(name, args...)
+	newProcessGroup(c)
+	return c
+}
+
+// killOnCancel kills the process tree of the started command c when ctx is
+// canceled, until the returned function is called after c.Wait.
+func killOnCancel(ctx context.Context, c *exec.Cmd) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = killTree(c.Process)
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// combinedOutput is like c.CombinedOutput but kills the process tree when ctx
+// is canceled.
+func combinedOutput(ctx context.Context, c *exec.Cmd) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	c.Stdout = &buf
+	c.Stderr = &buf
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+	stop := killOnCancel(ctx, c)
+	err := c.Wait()
+	stop()
+	return buf.Bytes(), err
+}
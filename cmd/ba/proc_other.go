@@ -0,0 +1,57 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// cleanupSignals cancel the benchmarks so the original ref is checked back
+// out: Ctrl-C, SIGTERM on CI job cancellation and SIGHUP when the terminal is
+// closed.
+var cleanupSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// hasTerminal is true when ba has a controlling terminal.
+var hasTerminal = func() bool {
+	f, err := os.Open("/dev/tty")
+	if err != nil {
+		return false
+	}
+	_ = f.Close()
+	return true
+}()
+
+// newProcessGroup starts the child in its own process group so killTree can
+// kill its descendants too, e.g. on SIGTERM when a CI job is canceled.
+//
+// It is a no-op when ba has a controlling terminal. The terminal already sends
+// SIGINT and SIGHUP to the whole foreground process group, and the children
+// may need the terminal, e.g. sudo in -cmd or ssh asking for a password for
+// -remote. A child in a background process group reading from it would be
+// stopped with SIGTTIN and hang ba.
+func newProcessGroup(c *exec.Cmd) {
+	if hasTerminal {
+		return
+	}
+	if c.SysProcAttr == nil {
+		c.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	c.SysProcAttr.Setpgid = true
+}
+
+// killTree kills the process group of the process, i.e. the process and all
+// its descendants, e.g. the compiler started by go test or the command run by
+// sh -c. It only kills the process when it was not started in its own process
+// group.
+func killTree(p *os.Process) error {
+	if err := syscall.Kill(-p.Pid, syscall.SIGKILL); err != nil {
+		return p.Kill()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+//go:build !windows
+
+package main
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestKillTree(t *testing.T) {
+	defer func(v bool) { hasTerminal = v }(hasTerminal)
+	hasTerminal = false
+	c := command("sh", "-c", "sleep 60 & echo $!; wait")
+	out, err := c.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	l, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = killTree(c.Process); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Wait()
+	// The orphaned grandchild is reaped by init asynchronously.
+	for start := time.Now(); syscall.Kill(pid, 0) == nil; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("sleep %d survived", pid)
+		}
+	}
+}
+
+func TestKillTreeTerminal(t *testing.T) {
+	defer func(v bool) { hasTerminal = v }(hasTerminal)
+	hasTerminal = true
+	// sudo may ask for the password on the terminal, which it cannot read from
+	// a background process group.
+	if c := command("sudo", "-v"); c.SysProcAttr != nil && c.SysProcAttr.Setpgid {
+		t.Fatal("sudo must stay in the foreground process group")
+	}
+	c := command("sleep", "60")
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := killTree(c.Process); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Wait(); err == nil {
+		t.Fatal("expected sleep to be killed")
+	}
+}
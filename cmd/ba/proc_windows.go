@@ -0,0 +1,36 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// cleanupSignals cancel the benchmarks so the original ref is checked back
+// out. SIGTERM is sent when the console window is closed.
+var cleanupSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// newProcessGroup starts the child in its own process group so a Ctrl-C in
+// the console is only delivered to ba, which then stops the child itself.
+// Otherwise git could be interrupted while checking the original ref back out.
+func newProcessGroup(c *exec.Cmd) {
+	if c.SysProcAttr == nil {
+		c.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	c.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// killTree kills the process and all its descendants, e.g. the compiler
+// started by go test.
+func killTree(p *os.Process) error {
+	/* #nosec G204 */
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(p.Pid)).Run(); err != nil {
+		return p.Kill()
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// profileKind is a type of profile to record on each side.
+type profileKind struct {
+	// name is used in the profile file names, e.g. "cpu".
+	name string
+	// dir is the directory to save the merged profiles into.
+	dir string
+	// flag is the test binary flag to record the profile, e.g.
+	// "-test.cpuprofile". extra are more flags to pass along.
+	flag  string
+	extra []string
+	// samples are the pprof sample indexes to diff, "" for the default one.
+	samples []string
+}
+
+// cpuProfile returns the -cpuprofile profile kind.
+func cpuProfile(dir string) *profileKind {
+	return &profileKind{name: "cpu", dir: dir, flag: "-test.cpuprofile", samples: []string{""}}
+}
+
+// memProfile returns the -memprofile profile kind. Every allocation is
+// sampled to get precise counts; it is fine since this run is not measured.
+func memProfile(dir string) *profileKind {
+	return &profileKind{
+		name:    "mem",
+		dir:     dir,
+		flag:    "-test.memprofile",
+		extra:   []string{"-test.memprofilerate", "1"},
+		samples: []string{"alloc_space", "alloc_objects"},
+	}
+}
+
+// mutexProfile returns the -mutexprofile profile kind. Every contention event
+// is recorded.
+func mutexProfile(dir string) *profileKind {
+	return &profileKind{
+		name:    "mutex",
+		dir:     dir,
+		flag:    "-test.mutexprofile",
+		extra:   []string{"-test.mutexprofilefraction", "1"},
+		samples: []string{"contentions", "delay"},
+	}
+}
+
+// blockProfile returns the -blockprofile profile kind. Every blocking event
+// is recorded.
+func blockProfile(dir string) *profileKind {
+	return &profileKind{
+		name:    "block",
+		dir:     dir,
+		flag:    "-test.blockprofile",
+		extra:   []string{"-test.blockprofilerate", "1"},
+		samples: []string{"contentions", "delay"},
+	}
+}
+
+// profileSides runs the benchmarks of each side once more with the profilers
+// enabled. This is done after the measured series since profiling adds
+// overhead. The profiles of each side are merged into
+// <kind.dir>/<i>-<name>.<kind.name>.pprof.
+//
+// It returns the merged profiles of each kind for each side, "" for sides
+// with a custom command.
+func profileSides(ctx context.Context, o *benchOptions, branch string, sides []*side) ([][]string, error) {
+	stderrLog.infof("profiling")
+	out := make([][]string, len(o.profiles))
+	for k := range o.profiles {
+		out[k] = make([]string, len(sides))
+	}
+	for i, s := range sides {
+		if s.cmd != "" {
+			continue
+		}
+		bins, err := buildTestBinaries(ctx, o, s)
+		if err != nil {
+			return out, err
+		}
+		// Raw profiles of each kind, one per test binary.
+		raw := make([][]string, len(o.profiles))
+		err = inSide(o, branch, s, func() error {
+			for j, b := range bins {
+				var args []string
+				for k, p := range o.profiles {
+					f := filepath.Join(p.dir, strconv.Itoa(i)+"-"+strconv.Itoa(j)+"."+p.name+".pprof")
+					args = append(append(args, p.flag, f), p.extra...)
+					raw[k] = append(raw[k], f)
+				}
+				if _, err2 := runTestBinary(ctx, o, s, b, o.bench, o.benchtime.String(), o.count, args...); err2 != nil {
+					return err2
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return out, err
+		}
+		for k, p := range o.profiles {
+			if len(raw[k]) == 0 {
+				continue
+			}
+			out[k][i] = filepath.Join(p.dir, strconv.Itoa(i)+"-"+unsafeChars.ReplaceAllString(s.name, "_")+"."+p.name+".pprof")
+			args := append([]string{"tool", "pprof", "-proto", "-output", out[k][i]}, raw[k]...)
+			o.logCmd("go %s", strings.Join(args, " "))
+			/* #nosec G204 */
+			if b, err := exec.CommandContext(ctx, "go", args...).CombinedOutput(); err != nil {
+				return out, fmt.Errorf("failed to merge the profiles: %w\n%s", err, b)
+			}
+			for _, f := range raw[k] {
+				_ = os.Remove(f)
+			}
+		}
+	}
+	return out, nil
+}
+
+// diffProfiles writes the functions whose samples changed the most between
+// the first profile and each other one.
+//
+// The profiles are normalized since the benchmarks run for about -benchtime
+// on each side, so it is the distribution of the samples that changes.
+func diffProfiles(ctx context.Context, w io.Writer, names []string, p *profileKind, profiles []string) error {
+	for i := 1; i < len(profiles); i++ {
+		if profiles[0] == "" || profiles[i] == "" {
+			continue
+		}
+		for _, sample := range p.samples {
+			args := []string{"tool", "pprof", "-top", "-nodecount", "20", "-normalize"}
+			title := p.name
+			if sample != "" {
+				args = append(args, "-sample_index", sample)
+				title += " " + sample
+			}
+			args = append(args, "-diff_base", profiles[0], profiles[i])
+			fmt.Fprintf(w, "%s profile delta %s vs %s:\n", title, names[0], names[i])
+			/* #nosec G204 */
+			c := exec.CommandContext(ctx, "go", args...)
+			c.Stdout = w
+			var stderr bytes.Buffer
+			c.Stderr = &stderr
+			if err := c.Run(); err != nil {
+				return fmt.Errorf("go tool pprof: %w\n%s", err, stderr.String())
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+	return nil
+}
+
+// unsafeChars matches the characters not to use in a file name.
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
@@ -0,0 +1,149 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// progress is the status line shown on a terminal while the series run.
+//
+// All the methods are no-ops on a nil progress.
+type progress struct {
+	w     io.Writer
+	start time.Time
+	// first is the number of series done before start, e.g. with -resume.
+	first int
+	// series is the number of series to run; more are run with -stable.
+	series int
+	stop   chan struct{}
+
+	mu   sync.Mutex
+	done int
+	// doneAt is when the last series completed, relative to start.
+	doneAt time.Duration
+	side   string
+	shown  bool
+	closed bool
+}
+
+// newProgress returns a status line written to f, refreshed every second.
+//
+// It returns nil when f is not a terminal, so piped output is not polluted.
+func newProgress(f *os.File, done, series int) *progress {
+	if !isTerminal(f) {
+		return nil
+	}
+	p := &progress{w: f, start: time.Now(), first: done, series: series, done: done, stop: make(chan struct{})}
+	go func() {
+		t := time.NewTicker(time.Second)
+		defer t.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-t.C:
+				p.mu.Lock()
+				if p.shown {
+					p.draw()
+				}
+				p.mu.Unlock()
+			}
+		}
+	}()
+	return p
+}
+
+// setSide shows that the side is now running.
+func (p *progress) setSide(name string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.side = name
+	p.draw()
+}
+
+// seriesDone records that n series are done.
+func (p *progress) seriesDone(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done = n
+	p.doneAt = time.Since(p.start)
+	p.erase()
+}
+
+// hide erases the status line so a message can be printed. It is shown again
+// by show.
+func (p *progress) hide() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.erase()
+}
+
+// show redraws the status line erased by hide.
+func (p *progress) show() {
+	if p == nil {
+		return
+	}
+	if p.side != "" && !p.closed {
+		p.draw()
+	}
+	p.mu.Unlock()
+}
+
+// close erases the status line for good.
+func (p *progress) close() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.stop)
+		p.erase()
+	}
+}
+
+func (p *progress) draw() {
+	fmt.Fprintf(p.w, "\r\033[K%s", p.line(time.Since(p.start)))
+	p.shown = true
+}
+
+func (p *progress) erase() {
+	if p.shown {
+		fmt.Fprintf(p.w, "\r\033[K")
+		p.shown = false
+	}
+}
+
+// line returns the status after elapsed.
+func (p *progress) line(elapsed time.Duration) string {
+	out := fmt.Sprintf("series %d", p.done+1)
+	if p.done < p.series {
+		out += fmt.Sprintf("/%d", p.series)
+	}
+	out += ", " + p.side + ", " + elapsed.Round(time.Second).String() + " elapsed"
+	if n := p.done - p.first; n > 0 && p.done < p.series {
+		// Extrapolate from the duration of the series done so far.
+		left := p.doneAt/time.Duration(n)*time.Duration(p.series-p.done) - (elapsed - p.doneAt)
+		if left < 0 {
+			left = 0
+		}
+		out += ", ~" + left.Round(time.Second).String() + " left"
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestProgressLine(t *testing.T) {
+	p := &progress{series: 4, side: "HEAD~1"}
+	if got := p.line(3 * time.Second); got != "series 1/4, HEAD~1, 3s elapsed" {
+		t.Fatal(got)
+	}
+	p.done, p.doneAt = 2, 20*time.Second
+	if got := p.line(25 * time.Second); got != "series 3/4, HEAD~1, 25s elapsed, ~15s left" {
+		t.Fatal(got)
+	}
+	// Resumed after 1 series; the ETA is based on the series run here.
+	p.first = 1
+	if got := p.line(25 * time.Second); got != "series 3/4, HEAD~1, 25s elapsed, ~35s left" {
+		t.Fatal(got)
+	}
+	// Past -series with -stable.
+	p.done = 4
+	if got := p.line(time.Minute); got != "series 5, HEAD~1, 1m0s elapsed" {
+		t.Fatal(got)
+	}
+}
+
+func TestProgressHide(t *testing.T) {
+	var b bytes.Buffer
+	p := &progress{w: &b, start: time.Now(), series: 2, stop: make(chan struct{})}
+	p.hide()
+	p.show()
+	if b.Len() != 0 {
+		t.Fatalf("%q", b.String())
+	}
+	p.setSide("HEAD")
+	p.hide()
+	b.WriteString("log\n")
+	p.show()
+	p.close()
+	want := "\r\033[Kseries 1/2, HEAD, 0s elapsed\r\033[Klog\n\r\033[Kseries 1/2, HEAD, 0s elapsed\r\033[K"
+	if b.String() != want {
+		t.Fatalf("%q\n!=\n%q", want, b.String())
+	}
+	// A nil progress is a no-op.
+	var n *progress
+	n.setSide("x")
+	n.hide()
+	n.show()
+	n.close()
+}
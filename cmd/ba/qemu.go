@@ -0,0 +1,56 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// qemuArch returns the architecture name used by qemu-user for goarch, e.g.
+// "aarch64" for arm64.
+func qemuArch(goarch string) (string, error) {
+	switch goarch {
+	case "amd64":
+		return "x86_64", nil
+	case "386":
+		return "i386", nil
+	case "arm64":
+		return "aarch64", nil
+	case "loong64":
+		return "loongarch64", nil
+	case "mipsle":
+		return "mipsel", nil
+	case "mips64le":
+		return "mips64el", nil
+	case "arm", "mips", "mips64", "ppc64", "ppc64le", "riscv64", "s390x":
+		return goarch, nil
+	default:
+		return "", fmt.Errorf("-goarch %s is not supported by qemu-user", goarch)
+	}
+}
+
+// qemuWrap returns the command prefix to run the linux test binaries compiled
+// for goarch under qemu-user, as specified by -qemu.
+//
+// Either the dynamically linked qemu-<arch> or the qemu-<arch>-static from the
+// distribution's qemu-user-static package is used.
+func qemuWrap(goarch string) ([]string, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.New("-qemu is only supported on linux")
+	}
+	a, err := qemuArch(goarch)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range []string{"qemu-" + a, "qemu-" + a + "-static"} {
+		if p, err := exec.LookPath(n); err == nil {
+			return []string{p}, nil
+		}
+	}
+	return nil, fmt.Errorf("qemu-%s is required for -qemu -goarch %s", a, goarch)
+}
@@ -0,0 +1,29 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestQemuArch(t *testing.T) {
+	data := []struct {
+		goarch, want string
+	}{
+		{"amd64", "x86_64"},
+		{"arm64", "aarch64"},
+		{"arm", "arm"},
+		{"riscv64", "riscv64"},
+		{"mips64le", "mips64el"},
+	}
+	for i, l := range data {
+		if got, err := qemuArch(l.goarch); err != nil || got != l.want {
+			t.Fatalf("#%d: %s %v", i, got, err)
+		}
+	}
+	for _, a := range []string{"", "wasm", "aarch64"} {
+		if _, err := qemuArch(a); err == nil {
+			t.Fatal(a)
+		}
+	}
+}
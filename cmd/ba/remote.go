@@ -0,0 +1,197 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// remote runs the test binaries on another machine over ssh, as specified by
+// -remote.
+type remote struct {
+	// host is the ssh destination, e.g. user@host.
+	host string
+	// dir is the temporary directory on the remote machine.
+	dir string
+	// goos and goarch are the platform of the remote machine.
+	goos   string
+	goarch string
+	// synced is the remote copy of each local directory already synced.
+	synced map[string]string
+}
+
+// newRemote creates a temporary directory on host and detects its platform.
+//
+// close must be called to delete the temporary directory.
+func newRemote(ctx context.Context, o *benchOptions, host string) (*remote, error) {
+	o.logCmd("ssh %s uname -sm && mktemp -d", host)
+	/* #nosec G204 */
+	out, err := exec.CommandContext(ctx, "ssh", host, "uname -sm && mktemp -d").Output()
+	if err != nil {
+		return nil, fmt.Errorf("-remote %s: %w", host, err)
+	}
+	l := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(l) != 2 {
+		return nil, fmt.Errorf("-remote %s: unexpected output %q", host, out)
+	}
+	r := &remote{host: host, dir: l[1], synced: map[string]string{}}
+	if r.goos, r.goarch, err = goPlatform(l[0]); err != nil {
+		_ = r.close(o)
+		return nil, err
+	}
+	return r, nil
+}
+
+// goPlatform returns GOOS and GOARCH for the output of uname -sm.
+func goPlatform(uname string) (string, string, error) {
+	f := strings.Fields(uname)
+	if len(f) != 2 {
+		return "", "", fmt.Errorf("unexpected uname %q", uname)
+	}
+	goos := strings.ToLower(f[0])
+	switch goos {
+	case "linux", "darwin", "freebsd", "netbsd", "openbsd":
+	default:
+		return "", "", fmt.Errorf("unsupported remote OS %q", f[0])
+	}
+	goarch := ""
+	switch f[1] {
+	case "x86_64", "amd64":
+		goarch = "amd64"
+	case "aarch64", "arm64":
+		goarch = "arm64"
+	case "i386", "i686":
+		goarch = "386"
+	case "armv6l", "armv7l":
+		goarch = "arm"
+	case "riscv64":
+		goarch = "riscv64"
+	case "ppc64le":
+		goarch = "ppc64le"
+	case "s390x":
+		goarch = "s390x"
+	default:
+		return "", "", fmt.Errorf("unsupported remote architecture %q", f[1])
+	}
+	return goos, goarch, nil
+}
+
+// close deletes the temporary directory on the remote machine.
+func (r *remote) close(o *benchOptions) error {
+	o.logCmd("ssh %s rm -rf %s", r.host, r.dir)
+	/* #nosec G204 */
+	if out, err := exec.Command("ssh", r.host, "rm -rf "+shellQuote(r.dir)).CombinedOutput(); err != nil {
+		return fmt.Errorf("-remote %s: %w\n%s", r.host, err, out)
+	}
+	return nil
+}
+
+// sync copies the local directory to the remote machine once per key and
+// returns the remote copy.
+func (r *remote) sync(ctx context.Context, o *benchOptions, key, local string) (string, error) {
+	if d, ok := r.synced[key]; ok {
+		return d, nil
+	}
+	d := path.Join(r.dir, strconv.Itoa(len(r.synced)))
+	o.logCmd("rsync -a --delete --exclude=.git %s/ %s:%s/", local, r.host, d)
+	/* #nosec G204 */
+	c := exec.CommandContext(ctx, "rsync", "-a", "--delete", "--exclude=.git", local+string(os.PathSeparator), r.host+":"+d+"/")
+	if out, err := c.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("rsync %s: %w\n%s", local, err, out)
+	}
+	r.synced[key] = d
+	return d, nil
+}
+
+// command returns the ssh command to run the test binary b with args on the
+// remote machine, from the copy of the package directory.
+//
+// The checkout and the binary are copied over first. The side's environment
+// and wrapper are applied on the remote machine.
+func (r *remote) command(ctx context.Context, o *benchOptions, s *side, b *testBinary, wrap, args []string) ([]string, error) {
+	bd := filepath.Dir(b.path)
+	rbin, err := r.sync(ctx, o, bd, bd)
+	if err != nil {
+		return nil, err
+	}
+	wd := r.dir
+	if b.root != "" {
+		// Keyed by the binaries too since -inplace checks out every side in the
+		// same directory.
+		rroot, err := r.sync(ctx, o, b.root+"\x00"+bd, b.root)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(b.root, b.dir)
+		if err != nil {
+			return nil, err
+		}
+		wd = path.Join(rroot, filepath.ToSlash(rel))
+	}
+	cmd := append([]string{"exec", "env"}, s.runEnv(o)...)
+	cmd = append(append(cmd, wrap...), path.Join(rbin, filepath.Base(b.path)))
+	cmd = append(cmd, args...)
+	for i := range cmd {
+		cmd[i] = shellQuote(cmd[i])
+	}
+	return []string{"ssh", r.host, "cd " + shellQuote(wd) + " && " + strings.Join(cmd, " ")}, nil
+}
+
+// shellQuote quotes s for a POSIX shell when needed.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-+=./:,@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// runShards runs n series concurrently, each on its own remote machine, and
+// appends the results to stats in order.
+func runShards(ctx context.Context, o *benchOptions, branch string, sides []*side, stats []string, n int) error {
+	outs := make([][]string, n)
+	cmds := make([][]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for k := 0; k < n; k++ {
+		// The test binaries are already built so the options are only read,
+		// except for the commands log and the random source.
+		oc := *o
+		oc.remote = o.remotes[k]
+		oc.commands = nil
+		if o.shuffle != nil {
+			/* #nosec G404 */
+			oc.shuffle = rand.New(rand.NewSource(o.shuffle.Int63()))
+		}
+		wg.Add(1)
+		go func(k int, oc benchOptions) {
+			defer wg.Done()
+			outs[k] = make([]string, len(sides))
+			errs[k] = runSeries(ctx, &oc, branch, sides, outs[k])
+			cmds[k] = oc.commands
+		}(k, oc)
+	}
+	wg.Wait()
+	for k := range outs {
+		o.commands = append(o.commands, cmds[k]...)
+		for j := range stats {
+			stats[j] += outs[k][j]
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestGoPlatform(t *testing.T) {
+	data := []struct {
+		uname, goos, goarch string
+	}{
+		{"Linux x86_64", "linux", "amd64"},
+		{"Linux aarch64", "linux", "arm64"},
+		{"Darwin arm64", "darwin", "arm64"},
+		{"Linux armv7l", "linux", "arm"},
+	}
+	for i, l := range data {
+		goos, goarch, err := goPlatform(l.uname)
+		if err != nil || goos != l.goos || goarch != l.goarch {
+			t.Fatalf("#%d: %s %s %v", i, goos, goarch, err)
+		}
+	}
+	for _, u := range []string{"", "Linux", "Linux mips", "SunOS x86_64"} {
+		if _, _, err := goPlatform(u); err == nil {
+			t.Fatal(u)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	data := []struct {
+		in, want string
+	}{
+		{"", "''"},
+		{"-test.bench", "-test.bench"},
+		{"GOGC=off", "GOGC=off"},
+		{"^$", "'^$'"},
+		{"a b", "'a b'"},
+		{"it's", `'it'\''s'`},
+	}
+	for i, l := range data {
+		if got := shellQuote(l.in); got != l.want {
+			t.Fatalf("#%d: %s != %s", i, got, l.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const resctrlRoot = "/sys/fs/resctrl"
+
+// resctrlGroup is a resctrl monitoring group tracking the memory bandwidth
+// and LLC occupancy of a process and its children.
+//
+// See https://docs.kernel.org/arch/x86/resctrl.html
+type resctrlGroup struct {
+	dir   string
+	begin time.Time
+	done  chan struct{}
+	wg    sync.WaitGroup
+	peak  uint64
+}
+
+func newResctrlGroup() (*resctrlGroup, error) {
+	root := filepath.Join(resctrlRoot, "mon_groups")
+	if _, err := os.Stat(root); err != nil {
+		return nil, errors.New("resctrl monitoring is not available; try: mount -t resctrl resctrl " + resctrlRoot)
+	}
+	dir := filepath.Join(root, "ba-"+strconv.Itoa(os.Getpid()))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &resctrlGroup{dir: dir, done: make(chan struct{})}, nil
+}
+
+// start moves the process in the group. Processes it starts afterward are
+// tracked too.
+func (r *resctrlGroup) start(pid int) error {
+	/* #nosec G306 */
+	if err := os.WriteFile(filepath.Join(r.dir, "tasks"), []byte(strconv.Itoa(pid)), 0o644); err != nil {
+		return err
+	}
+	r.begin = time.Now()
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		// LLC occupancy is only meaningful while the process is alive, so
+		// sample it.
+		t := time.NewTicker(100 * time.Millisecond)
+		defer t.Stop()
+		for {
+			if v, err := r.read("llc_occupancy"); err == nil && v > r.peak {
+				r.peak = v
+			}
+			select {
+			case <-r.done:
+				return
+			case <-t.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// stop stops the monitoring, deletes the group and returns the measurements
+// as a benchfmt line.
+func (r *resctrlGroup) stop() (string, error) {
+	close(r.done)
+	r.wg.Wait()
+	total, err := r.read("mbm_total_bytes")
+	d := time.Since(r.begin)
+	if err2 := os.Remove(r.dir); err == nil {
+		err = err2
+	}
+	if err != nil || r.begin.IsZero() {
+		return "", err
+	}
+	mb := float64(total) / 1000000.
+	return fmt.Sprintf("BenchmarkResctrl 1 %.3f mbm-MB %.3f mbm-MB/s %.1f llc-peak-KiB\n", mb, mb/d.Seconds(), float64(r.peak)/1024.), nil
+}
+
+// read returns the sum of the value over all the L3 domains.
+func (r *resctrlGroup) read(name string) (uint64, error) {
+	m, err := filepath.Glob(filepath.Join(r.dir, "mon_data", "mon_L3_*", name))
+	if err != nil {
+		return 0, err
+	}
+	if len(m) == 0 {
+		return 0, fmt.Errorf("resctrl: %s is not supported on this CPU", name)
+	}
+	var total uint64
+	for _, p := range m {
+		/* #nosec G304 */
+		d, err := os.ReadFile(p)
+		if err != nil {
+			return 0, err
+		}
+		v, err := strconv.ParseUint(strings.TrimSpace(string(d)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("resctrl: %s: %w", p, err)
+		}
+		total += v
+	}
+	return total, nil
+}
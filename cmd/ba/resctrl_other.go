@@ -0,0 +1,23 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+//go:build !linux
+
+package main
+
+import "errors"
+
+type resctrlGroup struct{}
+
+func newResctrlGroup() (*resctrlGroup, error) {
+	return nil, errors.New("resctrl is only supported on linux")
+}
+
+func (r *resctrlGroup) start(pid int) error {
+	return errors.New("resctrl is only supported on linux")
+}
+
+func (r *resctrlGroup) stop() (string, error) {
+	return "", nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// runtimeVars are the environment variables of the Go runtime that change the
+// measurements the most, set with the flag of the same name in lower case.
+//
+// ba disables its own garbage collector with debug.SetGCPercent, which does
+// not leak to the benchmark processes; these do.
+var runtimeVars = []string{"GOGC", "GOMEMLIMIT", "GODEBUG"}
+
+// runtimeFlag is a flag setting one of runtimeVars. It records whether it was
+// specified, since an empty value resets the variable to the runtime's default
+// instead of inheriting it.
+type runtimeFlag struct {
+	value string
+	set   bool
+}
+
+func (r *runtimeFlag) Set(v string) error {
+	r.value = v
+	r.set = true
+	return nil
+}
+
+func (r *runtimeFlag) String() string {
+	return r.value
+}
+
+var reMemLimit = regexp.MustCompile(`^(off|[0-9]+(B|KiB|MiB|GiB|TiB)?)$`)
+
+// checkRuntimeVar returns an error if v is not a valid value of the runtime
+// environment variable k. An empty value is the runtime's default.
+func checkRuntimeVar(k, v string) error {
+	if v == "" {
+		return nil
+	}
+	switch k {
+	case "GOGC":
+		if _, err := strconv.Atoi(v); err != nil && v != "off" {
+			return fmt.Errorf("invalid -gogc %q: expected a percentage or off", v)
+		}
+	case "GOMEMLIMIT":
+		if !reMemLimit.MatchString(v) {
+			return fmt.Errorf("invalid -gomemlimit %q: expected a size like 512MiB or off", v)
+		}
+	case "GODEBUG":
+		for _, s := range strings.Split(v, ",") {
+			if i := strings.IndexByte(s, '='); i <= 0 {
+				return fmt.Errorf("invalid -godebug %q: expected comma separated key=value settings", v)
+			}
+		}
+	}
+	return nil
+}
+
+// runtimeEnv returns the runtime environment variables of the benchmark
+// processes, as "KEY=value". set is the values specified with -gogc,
+// -gomemlimit and -godebug by variable, possibly empty to reset it to the
+// runtime's default; the variables not in set are inherited from ba's
+// environment via getenv and also returned in inherited, so they are passed
+// to -remote and -container too and recorded.
+func runtimeEnv(set map[string]string, getenv func(string) string) (env, inherited []string, err error) {
+	for _, k := range runtimeVars {
+		v, ok := set[k]
+		if !ok {
+			if v = getenv(k); v == "" {
+				continue
+			}
+			inherited = append(inherited, k+"="+v)
+		}
+		if err = checkRuntimeVar(k, v); err != nil {
+			return nil, nil, err
+		}
+		env = append(env, k+"="+v)
+	}
+	return env, inherited, nil
+}
+
+// runtimeConfig returns the benchmark configuration lines of the runtime
+// environment variables set to a non default value in env, e.g. "gogc: off".
+// The last value of a variable wins, as for a process environment.
+func runtimeConfig(env []string) []string {
+	var out []string
+	for _, k := range runtimeVars {
+		v := ""
+		for _, e := range env {
+			if strings.HasPrefix(e, k+"=") {
+				v = e[len(k)+1:]
+			}
+		}
+		if v != "" {
+			out = append(out, strings.ToLower(k)+": "+v)
+		}
+	}
+	return out
+}
+
+// runEnv returns the environment variables added to the side's benchmark
+// processes: the runtime environment of -gogc, -gomemlimit and -godebug, then
+// the side's own, which takes precedence.
+func (s *side) runEnv(o *benchOptions) []string {
+	if len(o.runtime) == 0 {
+		return s.env
+	}
+	return append(o.runtime[:len(o.runtime):len(o.runtime)], s.env...)
+}
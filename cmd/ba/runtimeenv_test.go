@@ -0,0 +1,57 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuntimeEnv(t *testing.T) {
+	getenv := func(k string) string {
+		return map[string]string{"GOGC": "off", "GODEBUG": "gctrace=1"}[k]
+	}
+	data := []struct {
+		set       map[string]string
+		env       []string
+		inherited []string
+	}{
+		{nil, []string{"GOGC=off", "GODEBUG=gctrace=1"}, []string{"GOGC=off", "GODEBUG=gctrace=1"}},
+		{map[string]string{"GOGC": "200", "GOMEMLIMIT": "1GiB"}, []string{"GOGC=200", "GOMEMLIMIT=1GiB", "GODEBUG=gctrace=1"}, []string{"GODEBUG=gctrace=1"}},
+		{map[string]string{"GOGC": "", "GODEBUG": ""}, []string{"GOGC=", "GODEBUG="}, nil},
+	}
+	for i, l := range data {
+		env, inherited, err := runtimeEnv(l.set, getenv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(l.env, env) || !reflect.DeepEqual(l.inherited, inherited) {
+			t.Fatalf("#%d: %q %q", i, env, inherited)
+		}
+	}
+	for _, set := range []map[string]string{{"GOGC": "none"}, {"GOMEMLIMIT": "1G"}, {"GODEBUG": "gctrace"}} {
+		if _, _, err := runtimeEnv(set, getenv); err == nil {
+			t.Fatal(set)
+		}
+	}
+}
+
+func TestRuntimeConfig(t *testing.T) {
+	got := runtimeConfig([]string{"GOGC=200", "GODEBUG=", "GOMEMLIMIT=1GiB", "FOO=bar", "GOGC=off"})
+	if want := []string{"gogc: off", "gomemlimit: 1GiB"}; !reflect.DeepEqual(want, got) {
+		t.Fatalf("%q", got)
+	}
+	s := &session{
+		Machine: []string{"cores: 8"},
+		Runtime: []string{"GOGC=200"},
+		Sides:   []*sessionSide{{Name: "a"}, {Name: "b", Env: []string{"GOGC=off"}}},
+	}
+	if want := []string{"cores: 8", "gogc: 200"}; !reflect.DeepEqual(want, s.config(0)) {
+		t.Fatalf("%q", s.config(0))
+	}
+	if want := []string{"cores: 8", "gogc: off"}; !reflect.DeepEqual(want, s.config(1)) {
+		t.Fatalf("%q", s.config(1))
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/benchmath"
+)
+
+// rangeSides returns a side for every step-th commit of the first parent
+// history in the git revision range spec, e.g. "v1.0.0..HEAD". The start of
+// the range is included first as the baseline and the end is always
+// included.
+func rangeSides(spec string, step int) ([]*side, error) {
+	i := strings.Index(spec, "..")
+	if i <= 0 || i+2 >= len(spec) || strings.Contains(spec, "...") {
+		return nil, errors.New("-range must be in the form <from>..<to>")
+	}
+	if step < 1 {
+		return nil, errors.New("-range-step must be at least 1")
+	}
+	out, err := git("rev-list", "--first-parent", "--reverse", spec)
+	if err != nil {
+		return nil, errors.New(out)
+	}
+	if out == "" {
+		return nil, errors.New("-range is empty")
+	}
+	commits := strings.Split(out, "\n")
+	base, err := git("rev-parse", spec[:i]+"^{commit}")
+	if err != nil {
+		return nil, errors.New(base)
+	}
+	var sides []*side
+	add := func(sha1 string) error {
+		short, err := git("rev-parse", "--short", sha1)
+		if err != nil {
+			return errors.New(short)
+		}
+		sides = append(sides, &side{name: short, ref: sha1})
+		return nil
+	}
+	if err = add(base); err != nil {
+		return nil, err
+	}
+	for j := step - 1; j < len(commits); j += step {
+		if err = add(commits[j]); err != nil {
+			return nil, err
+		}
+	}
+	if (len(commits))%step != 0 {
+		if err = add(commits[len(commits)-1]); err != nil {
+			return nil, err
+		}
+	}
+	return sides, nil
+}
+
+// seriesPoint is the summary of one benchmark metric at one side.
+type seriesPoint struct {
+	Side   string
+	SHA1   string `json:",omitempty"`
+	Center float64
+	// Lo and Hi are the confidence interval, nil when there are too few
+	// samples.
+	Lo *float64 `json:",omitempty"`
+	Hi *float64 `json:",omitempty"`
+	N  int
+}
+
+// timeSeries is the evolution of one benchmark metric across the sides.
+type timeSeries struct {
+	Pkg    string `json:",omitempty"`
+	Name   string
+	Unit   string
+	Points []seriesPoint
+}
+
+// genTimeSeries summarizes each benchmark metric of each side with its
+// median and 95% confidence interval.
+func genTimeSeries(s *session) ([]*timeSeries, error) {
+	var out []*timeSeries
+	m := map[string]*timeSeries{}
+	for _, ss := range s.Sides {
+		smp, err := parseSamples(ss.Output)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range smp.keys {
+			ts := m[k]
+			if ts == nil {
+				f := strings.SplitN(k, " ", 3)
+				ts = &timeSeries{Pkg: f[0], Name: f[1], Unit: f[2]}
+				m[k] = ts
+				out = append(out, ts)
+			}
+			v := smp.values[k]
+			sum := benchmath.AssumeNothing.Summary(benchmath.NewSample(v, &benchmath.DefaultThresholds), 0.95)
+			p := seriesPoint{Side: ss.Name, SHA1: ss.SHA1, Center: sum.Center, N: len(v)}
+			if !math.IsInf(sum.Lo, 0) && !math.IsInf(sum.Hi, 0) {
+				p.Lo = &sum.Lo
+				p.Hi = &sum.Hi
+			}
+			ts.Points = append(ts.Points, p)
+		}
+	}
+	return out, nil
+}
+
+// printTimeSeries writes the time series as JSON when format is json,
+// otherwise as CSV with one row per benchmark metric and side.
+func printTimeSeries(w io.Writer, format string, s *session) error {
+	ts, err := genTimeSeries(s)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(ts)
+	}
+	c := csv.NewWriter(w)
+	_ = c.Write([]string{"pkg", "benchmark", "unit", "side", "sha1", "median", "lo", "hi", "n"})
+	for _, t := range ts {
+		for _, p := range t.Points {
+			_ = c.Write([]string{
+				t.Pkg, t.Name, t.Unit, p.Side, p.SHA1,
+				strconv.FormatFloat(p.Center, 'g', -1, 64),
+				formatOptFloat(p.Lo),
+				formatOptFloat(p.Hi),
+				strconv.Itoa(p.N),
+			})
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
+
+func formatOptFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'g', -1, 64)
+}
@@ -0,0 +1,38 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintTimeSeries(t *testing.T) {
+	s := &session{
+		Sides: []*sessionSide{
+			{Name: "aaa", SHA1: "aaaa", Output: "pkg: foo\nBenchmarkFoo 1 10 ns/op\n"},
+			{Name: "bbb", SHA1: "bbbb", Output: "pkg: foo\n" + strings.Repeat("BenchmarkFoo 1 20 ns/op\n", 10)},
+		},
+		Range: true,
+	}
+	var b bytes.Buffer
+	if err := printTimeSeries(&b, "text", s); err != nil {
+		t.Fatal(err)
+	}
+	want := "pkg,benchmark,unit,side,sha1,median,lo,hi,n\n" +
+		"foo,Foo,sec/op,aaa,aaaa,1e-08,,,1\n" +
+		"foo,Foo,sec/op,bbb,bbbb,2e-08,2e-08,2e-08,10\n"
+	if got := b.String(); got != want {
+		t.Fatalf("%q", got)
+	}
+	b.Reset()
+	if err := printTimeSeries(&b, "json", s); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); !strings.Contains(got, `"Hi": 2e-8`) || strings.Contains(got, "Inf") {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,419 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maruel/pat/benchcmp"
+	"golang.org/x/perf/benchmath"
+	"golang.org/x/perf/benchunit"
+)
+
+// Trend chart geometry, in pixels.
+const (
+	chartWidth  = 640
+	chartHeight = 200
+	chartMargin = 40
+)
+
+// trendPoint is the summary of a benchmark metric at one commit.
+type trendPoint struct {
+	Short   string
+	Subject string
+	Center  float64
+	// Lo and Hi are the confidence interval, infinite when there are too few
+	// samples.
+	Lo float64
+	Hi float64
+	N  int
+	// Delta is the change from the previous point, e.g. "+12.34%" or "~" when
+	// not significant.
+	Delta string
+	// Change is 1 when the point is a significant improvement over the
+	// previous one, -1 a significant regression, 0 otherwise.
+	Change int
+
+	// commit is the index of the commit in the commits passed to trends.
+	commit int
+	values []float64
+}
+
+// trend is the evolution of a benchmark metric over the commits.
+type trend struct {
+	// Pkg is the package of the benchmark, empty with -cmd.
+	Pkg    string
+	Name   string
+	Unit   string
+	Points []*trendPoint
+}
+
+// Regressions returns the number of significant regressions of the trend.
+func (t *trend) Regressions() int {
+	n := 0
+	for _, p := range t.Points {
+		if p.Change < 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// trends returns the evolution of each benchmark metric of the entries over
+// the commits, oldest first, sorted by name, package then unit.
+//
+// Only the commits with results are included. Each point is compared with the
+// previous one with a Mann-Whitney U-test.
+func trends(entries []*historyEntry, commits []commitInfo, alpha float64) []*trend {
+	type key struct{ pkg, name, unit string }
+	values := map[key]map[string][]float64{}
+	var keys []key
+	for _, h := range entries {
+		k := key{h.Pkg, h.Name, h.Unit}
+		m := values[k]
+		if m == nil {
+			m = map[string][]float64{}
+			values[k] = m
+			keys = append(keys, k)
+		}
+		m[h.SHA1] = append(m[h.SHA1], h.Values...)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		if keys[i].pkg != keys[j].pkg {
+			return keys[i].pkg < keys[j].pkg
+		}
+		return keys[i].unit < keys[j].unit
+	})
+	var out []*trend
+	for _, k := range keys {
+		t := &trend{Pkg: k.pkg, Name: k.name, Unit: k.unit}
+		better := benchcmp.DefaultOptions.BetterOf(k.unit)
+		var prev *benchmath.Sample
+		for i, c := range commits {
+			v := values[k][c.sha1]
+			if len(v) == 0 {
+				continue
+			}
+			smp := benchmath.NewSample(v, &benchmath.DefaultThresholds)
+			sum := benchmath.AssumeNothing.Summary(smp, 0.95)
+			p := &trendPoint{Short: c.short, Subject: c.subject, Center: sum.Center, Lo: sum.Lo, Hi: sum.Hi, N: len(v), commit: i, values: v}
+			if prev != nil {
+				old := t.Points[len(t.Points)-1].Center
+				cmp := benchmath.AssumeNothing.Compare(prev, smp)
+				cmp.Alpha = alpha
+				p.Delta = cmp.FormatDelta(old, p.Center)
+				if p.Delta != "~" && better != 0 && old != p.Center {
+					p.Change = better
+					if p.Center < old {
+						p.Change = -better
+					}
+				}
+			}
+			t.Points = append(t.Points, p)
+			prev = smp
+		}
+		if len(t.Points) != 0 {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// trendChart returns an SVG chart of the center of each point with its
+// confidence interval, the significant changes colored.
+func trendChart(t *trend) template.HTML {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range t.Points {
+		for _, v := range []float64{p.Center, p.Lo, p.Hi} {
+			if !math.IsInf(v, 0) {
+				lo = math.Min(lo, v)
+				hi = math.Max(hi, v)
+			}
+		}
+	}
+	if hi == lo {
+		hi, lo = hi+math.Abs(hi)*0.01+1e-12, lo-math.Abs(lo)*0.01-1e-12
+	}
+	x := func(i int) float64 {
+		if len(t.Points) == 1 {
+			return chartWidth / 2
+		}
+		return chartMargin + float64(i)*float64(chartWidth-2*chartMargin)/float64(len(t.Points)-1)
+	}
+	y := func(v float64) float64 {
+		return chartHeight - chartMargin/2 - (v-lo)/(hi-lo)*(chartHeight-chartMargin)
+	}
+	cls := benchunit.ClassOf(t.Unit)
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="0" y="%.1f">%s</text>`, y(hi)+4, html.EscapeString(benchunit.Scale(hi, cls)))
+	fmt.Fprintf(&b, `<text x="0" y="%.1f">%s</text>`, y(lo)+4, html.EscapeString(benchunit.Scale(lo, cls)))
+	var line []string
+	for i, p := range t.Points {
+		line = append(line, fmt.Sprintf("%.1f,%.1f", x(i), y(p.Center)))
+	}
+	fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="#69c"/>`, strings.Join(line, " "))
+	for i, p := range t.Points {
+		if !math.IsInf(p.Lo, 0) && !math.IsInf(p.Hi, 0) {
+			fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#aaa"/>`, x(i), y(p.Lo), x(i), y(p.Hi))
+		}
+		fill := "#333"
+		switch p.Change {
+		case 1:
+			fill = "#080"
+		case -1:
+			fill = "#c00"
+		}
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s %s %s</title></circle>`, x(i), y(p.Center), fill, html.EscapeString(p.Short), html.EscapeString(benchunit.Scale(p.Center, cls)), html.EscapeString(p.Delta))
+	}
+	b.WriteString(`</svg>`)
+	/* #nosec G203 */
+	return template.HTML(b.String())
+}
+
+var dashboardTmpl = template.Must(template.New("").Funcs(template.FuncMap{
+	"chart": trendChart,
+	"scale": func(v float64, unit string) string {
+		return benchunit.Scale(v, benchunit.ClassOf(unit))
+	},
+	"ci": func(p *trendPoint, unit string) string {
+		if math.IsInf(p.Lo, 0) || math.IsInf(p.Hi, 0) {
+			return ""
+		}
+		cls := benchunit.ClassOf(unit)
+		return benchunit.Scale(p.Lo, cls) + "–" + benchunit.Scale(p.Hi, cls)
+	},
+	"last": func(t *trend) *trendPoint {
+		return t.Points[len(t.Points)-1]
+	},
+	"class": func(change int) string {
+		switch change {
+		case 1:
+			return "better"
+		case -1:
+			return "worse"
+		default:
+			return ""
+		}
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ba {{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 0.5em; }
+th, td { padding: 2px 8px; text-align: right; white-space: nowrap; }
+th:first-child, td:first-child, td:last-child { text-align: left; }
+tr:nth-child(even) { background: #f4f4f4; }
+tr.better { color: #080; }
+tr.worse { color: #c00; }
+svg text { font-size: 10px; fill: #333; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- if .Bench}}
+<p><a href="/">all benchmarks</a></p>
+{{- range .Trends}}
+<h2>{{if .Pkg}}{{.Pkg}} {{end}}{{.Unit}}</h2>
+{{chart .}}
+<table>
+<tr><th>commit</th><th>{{.Unit}}</th><th>95% CI</th><th>n</th><th>delta</th><th>subject</th></tr>
+{{- $unit := .Unit}}
+{{- range .Points}}
+<tr class="{{class .Change}}"><td>{{.Short}}</td><td>{{scale .Center $unit}}</td><td>{{ci . $unit}}</td><td>{{.N}}</td><td>{{.Delta}}</td><td>{{.Subject}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- else}}
+<table>
+<tr><th>name</th><th>package</th><th>unit</th><th>last</th><th>delta</th><th>regressions</th></tr>
+{{- range .Trends}}
+{{- $p := last .}}
+<tr class="{{class $p.Change}}"><td><a href="/bench?pkg={{.Pkg}}&amp;name={{.Name}}">{{.Name}}</a></td><td>{{.Pkg}}</td><td>{{.Unit}}</td><td>{{scale $p.Center .Unit}}</td><td>{{$p.Delta}}</td><td>{{.Regressions}}</td></tr>
+{{- end}}
+</table>
+<p><a href="/history.jsonl">history.jsonl</a></p>
+{{- end}}
+</body>
+</html>
+`))
+
+// dashboard renders the history database as trend charts over the commits.
+type dashboard struct {
+	// db is the history database.
+	db string
+	// rev is the revision whose first parent history is shown.
+	rev string
+	// last is the number of commits shown.
+	last  int
+	alpha float64
+	// labels filters the results to the ones recorded with these experiment
+	// labels.
+	labels map[string]string
+	// mu is held while reading the database, so it is not read while being
+	// appended to.
+	mu *sync.Mutex
+}
+
+// handler serves the list of the benchmarks at /, the trend charts of one of
+// them at /bench?pkg=<pkg>&name=<name> and all the entries of the database as
+// JSON lines at /history.jsonl. Without pkg, the benchmarks of that name in
+// all the packages are shown.
+func (d *dashboard) handler() http.Handler {
+	m := http.NewServeMux()
+	m.HandleFunc("/history.jsonl", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := os.Stat(d.db); errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		d.mu.Lock()
+		entries, err := readHistory(d.db, "", "", nil)
+		d.mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		e := json.NewEncoder(w)
+		for _, h := range entries {
+			if err = e.Encode(h); err != nil {
+				return
+			}
+		}
+	})
+	m.HandleFunc("/bench", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "missing name", http.StatusBadRequest)
+			return
+		}
+		q := r.URL.Query()
+		d.render(w, q.Has("pkg"), q.Get("pkg"), name)
+	})
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		d.render(w, false, "", "")
+	})
+	return m
+}
+
+// render writes the page of the benchmark name, only in the package pkg when
+// byPkg is set, or of all of them.
+func (d *dashboard) render(w http.ResponseWriter, byPkg bool, pkg, name string) {
+	d.mu.Lock()
+	entries, err := readHistory(d.db, name, machineFingerprint(), d.labels)
+	d.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if byPkg {
+		var e []*historyEntry
+		for _, h := range entries {
+			if h.Pkg == pkg {
+				e = append(e, h)
+			}
+		}
+		entries = e
+	}
+	commits, err := logCommits(d.rev, d.last)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := struct {
+		Title  string
+		Bench  bool
+		Trends []*trend
+	}{Title: fmt.Sprintf("last %d commits of %s", d.last, d.rev), Trends: trends(entries, commits, d.alpha)}
+	if name != "" {
+		if len(data.Trends) == 0 {
+			http.Error(w, fmt.Sprintf("no history for %s in the last %d commits on this machine", name, d.last), http.StatusNotFound)
+			return
+		}
+		data.Title = strings.TrimSpace(pkg + " " + data.Trends[0].Name)
+		data.Bench = true
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err = dashboardTmpl.Execute(w, data); err != nil {
+		stderrLog.errorf("%s", err)
+	}
+}
+
+// listenAndServe serves h on addr until the returned function is called.
+func listenAndServe(addr string, h http.Handler) (func(), error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = srv.Serve(l)
+	}()
+	stderrLog.infof("serving the history on http://%s/", l.Addr())
+	return func() { _ = srv.Close() }, nil
+}
+
+// serveMain implements "ba serve", which serves the history database as a
+// web dashboard.
+func serveMain(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	db := fs.String("db", defaultHistoryPath(), "path of the history database written by -record or ba daemon")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	rev := fs.String("rev", "HEAD", "revision whose first parent history is shown")
+	last := fs.Int("last", 50, "number of commits to show")
+	alpha := fs.Float64("alpha", 0.05, "p-value cutoff to consider a change between two commits significant")
+	var labels labelFlag
+	fs.Var(&labels, "label", "only show the results recorded with this key=value -label; can be specified multiple times")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: ba serve <flags>\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "ba serve serves the trend of each benchmark recorded in the history\n")
+		fmt.Fprintf(os.Stderr, "database over the commits, highlighting the regressions.\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		return errors.New("unexpected argument")
+	}
+	if *last < 2 {
+		return errors.New("-last must be at least 2")
+	}
+	d := &dashboard{db: *db, rev: *rev, last: *last, alpha: *alpha, labels: labels.labels(), mu: &sync.Mutex{}}
+	stop, err := listenAndServe(*addr, d.handler())
+	if err != nil {
+		return err
+	}
+	defer stop()
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, cleanupSignals...)
+	<-ch
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTrends(t *testing.T) {
+	commits := []commitInfo{{"aaaa", "a", "first"}, {"bbbb", "b", "second"}, {"cccc", "c", "third"}, {"dddd", "d", "fourth"}}
+	base := []float64{10, 10.1, 10.2, 9.9, 10, 10.1}
+	slow := []float64{20, 20.1, 20.2, 19.9, 20, 20.1}
+	entries := []*historyEntry{
+		{SHA1: "aaaa", Name: "Foo", Unit: "sec/op", Values: base},
+		{SHA1: "bbbb", Name: "Foo", Unit: "sec/op", Values: base},
+		{SHA1: "dddd", Name: "Foo", Unit: "sec/op", Values: slow},
+		{SHA1: "aaaa", Name: "Foo", Unit: "B/s", Values: base},
+		{SHA1: "dddd", Name: "Foo", Unit: "B/s", Values: slow},
+		{SHA1: "eeee", Name: "Bar", Unit: "sec/op", Values: base},
+	}
+	got := trends(entries, commits, 0.05)
+	if len(got) != 2 || got[0].Unit != "B/s" || got[1].Unit != "sec/op" {
+		t.Fatal(got)
+	}
+	if p := got[0].Points; len(p) != 2 || p[1].Change != 1 || p[1].Delta != "+99.50%" {
+		t.Fatalf("%+v", p[1])
+	}
+	p := got[1].Points
+	if len(p) != 3 || p[0].Delta != "" || p[1].Change != 0 || p[1].Delta != "~" || p[2].Change != -1 || p[2].Short != "d" {
+		t.Fatalf("%+v %+v %+v", p[0], p[1], p[2])
+	}
+	if got[1].Regressions() != 1 {
+		t.Fatal(got[1].Regressions())
+	}
+	if c := string(trendChart(got[1])); !strings.HasPrefix(c, "<svg") || !strings.Contains(c, `fill="#c00"`) {
+		t.Fatal(c)
+	}
+	// The benchmarks of the same name in another package are not mixed in.
+	entries = append(entries, &historyEntry{SHA1: "bbbb", Pkg: "example.com/y", Name: "Foo", Unit: "sec/op", Values: slow})
+	got = trends(entries, commits, 0.05)
+	if len(got) != 3 || got[2].Pkg != "example.com/y" || len(got[2].Points) != 1 || len(got[1].Points) != 3 || got[1].Points[1].Change != 0 {
+		t.Fatal(got)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "history.db")
+	d := &dashboard{db: db, rev: "HEAD", last: 20, alpha: 0.05, mu: &sync.Mutex{}}
+	srv := httptest.NewServer(d.handler())
+	defer srv.Close()
+	for p, want := range map[string]int{"/": 404, "/bench": 400, "/foo": 404, "/history.jsonl": 404} {
+		resp, err := http.Get(srv.URL + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatal(p, resp.StatusCode)
+		}
+	}
+	s := &session{Sides: []*sessionSide{{Name: "aaaa", SHA1: "aaaa", Output: "BenchmarkFoo 1 10 ns/op\n"}}}
+	if _, err := recordHistory(db, s); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(srv.URL + "/history.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatal(resp.StatusCode, err)
+	}
+	if !strings.HasPrefix(string(b), `{"SHA1":"aaaa",`) || strings.Count(string(b), "\n") != 1 {
+		t.Fatalf("%q", b)
+	}
+}
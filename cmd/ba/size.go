@@ -0,0 +1,166 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maruel/pat/benchcmp"
+	"golang.org/x/perf/benchmath"
+)
+
+// recordSizes records the size of the test binaries of each side and, with
+// -nm, the size of each package linked in them.
+func recordSizes(ctx context.Context, o *benchOptions, sides []*side, s *session) error {
+	for i, d := range sides {
+		bins := o.binaries[d.buildKey()]
+		if len(bins) == 0 {
+			continue
+		}
+		ss := s.Sides[i]
+		ss.BinarySizes = map[string]int64{}
+		for _, b := range bins {
+			fi, err := os.Stat(b.path)
+			if err != nil {
+				return err
+			}
+			ss.BinarySizes[b.pkg] = fi.Size()
+			if !o.nm {
+				continue
+			}
+			if ss.PackageSizes == nil {
+				ss.PackageSizes = map[string]int64{}
+			}
+			if err = packageSizes(ctx, o, d, b.path, ss.PackageSizes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// packageSizes adds the size of the symbols of each package in the binary to
+// sizes. Only the symbols stored in the file are counted, e.g. not bss.
+func packageSizes(ctx context.Context, o *benchOptions, s *side, path string, sizes map[string]int64) error {
+	o.logCmd("%s tool nm -size %s", s.goCmd(), path)
+	/* #nosec G204 */
+	out, err := exec.CommandContext(ctx, s.goCmd(), "tool", "nm", "-size", path).Output()
+	if err != nil {
+		return fmt.Errorf("go tool nm %s: %w", path, err)
+	}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) < 4 || !strings.Contains("TtRrDd", f[2]) {
+			continue
+		}
+		n, err := strconv.ParseInt(f[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		sizes[symbolPackage(f[3])] += n
+	}
+	return sc.Err()
+}
+
+// symbolPackage returns the package of a symbol, e.g. "net/http" for
+// "net/http.(*Client).Do", or the prefix of compiler generated symbols like
+// "type:".
+func symbolPackage(sym string) string {
+	if i := strings.IndexByte(sym, ':'); i != -1 && !strings.ContainsAny(sym[:i], "./") {
+		return sym[:i+1]
+	}
+	end := len(sym)
+	if i := strings.IndexAny(sym, "(["); i != -1 {
+		end = i
+	}
+	slash := strings.LastIndexByte(sym[:end], '/')
+	if i := strings.IndexByte(sym[slash+1:], '.'); i != -1 {
+		return sym[:slash+1+i]
+	}
+	return "other"
+}
+
+// sizeTables returns the tables comparing the test binary sizes and the
+// package sizes of the sides, when they differ.
+func sizeTables(sides []*sessionSide) []*table {
+	names := make([]string, len(sides))
+	binaries := make([]map[string]int64, len(sides))
+	packages := make([]map[string]int64, len(sides))
+	for i, ss := range sides {
+		names[i] = ss.Name
+		binaries[i] = ss.BinarySizes
+		packages[i] = ss.PackageSizes
+	}
+	var out []*table
+	for _, t := range []struct {
+		unit  string
+		sizes []map[string]int64
+	}{{"binary-B", binaries}, {"package-B", packages}} {
+		if tbl := sizeTable(names, t.unit, t.sizes); tbl != nil {
+			out = append(out, tbl)
+		}
+	}
+	return out
+}
+
+// sizeTable returns a table with a row per key of sizes whose value differs
+// between the configurations, or nil if none does.
+func sizeTable(names []string, unit string, sizes []map[string]int64) *table {
+	keys := map[string]bool{}
+	for _, m := range sizes {
+		if m == nil {
+			// Not recorded for this configuration.
+			return nil
+		}
+		for k := range m {
+			keys[k] = true
+		}
+	}
+	tbl := &table{Unit: unit, Better: -1, Configs: names, Delta: len(names) == 2}
+	for _, k := range sortedSizeKeys(keys) {
+		r := &row{Benchmark: k, Cells: make([]*cell, len(names)), P: -1, EffectSize: math.NaN()}
+		same := true
+		for i, m := range sizes {
+			v, ok := m[k]
+			if ok {
+				r.Cells[i] = &cell{Summary: benchmath.Summary{Center: float64(v)}}
+			}
+			if !ok || v != sizes[0][k] {
+				same = false
+			}
+		}
+		if same {
+			continue
+		}
+		if tbl.Delta && r.Cells[0] != nil && r.Cells[1] != nil {
+			r.Delta = benchcmp.PctDelta(r.Cells[0].Center, r.Cells[1].Center)
+			r.Note = fmt.Sprintf("%+d B", sizes[1][k]-sizes[0][k])
+		}
+		tbl.Rows = append(tbl.Rows, r)
+	}
+	if len(tbl.Rows) == 0 {
+		return nil
+	}
+	return tbl
+}
+
+func sortedSizeKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maruel/pat/benchcmp"
+)
+
+func TestSymbolPackage(t *testing.T) {
+	data := []struct {
+		sym, want string
+	}{
+		{"runtime.mallocgc", "runtime"},
+		{"net/http.(*Client).Do", "net/http"},
+		{"github.com/a/b.F[go.shape.int]", "github.com/a/b"},
+		{"type:*github.com/a/b.T", "type:"},
+		{"go:buildinfo", "go:"},
+		{"_cgo_init", "other"},
+	}
+	for _, l := range data {
+		if got := symbolPackage(l.sym); got != l.want {
+			t.Errorf("%s: %q != %q", l.sym, got, l.want)
+		}
+	}
+}
+
+func TestSizeTables(t *testing.T) {
+	sides := []*sessionSide{
+		{Name: "old", BinarySizes: map[string]int64{"a": 1024, "b": 2048}},
+		{Name: "new", BinarySizes: map[string]int64{"a": 1024, "b": 3072}},
+	}
+	tables := sizeTables(sides)
+	if len(tables) != 1 {
+		t.Fatalf("%+v", tables)
+	}
+	var b bytes.Buffer
+	if err := benchcmp.WriteText(&b, tables, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "name  old binary-B  new binary-B    delta\n" +
+		"b          2.000Ki       3.000Ki  +50.00%  (+1024 B)\n"
+	if got := b.String(); got != want {
+		t.Fatalf("%q", got)
+	}
+	sides[1].BinarySizes["b"] = 2048
+	if tables = sizeTables(sides); len(tables) != 0 {
+		t.Fatalf("%+v", tables)
+	}
+}
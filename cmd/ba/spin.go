@@ -0,0 +1,101 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// spinPoll is the interval between two samples of the CPU frequency.
+	spinPoll = 100 * time.Millisecond
+	// spinSamples is the number of consecutive samples within spinTolerance
+	// of each other for the frequency to be considered stable.
+	spinSamples = 3
+	// spinTolerance is the relative spread allowed between the samples.
+	spinTolerance = 0.01
+	// spinMax is the time after which spinning gives up.
+	spinMax = 10 * time.Second
+)
+
+// checkSpin fails early if the CPU frequency cannot be read for -spin.
+func checkSpin() error {
+	if readThermal().freq == 0 {
+		return errors.New("-spin requires the current CPU frequency, which is not available on this machine")
+	}
+	return nil
+}
+
+// spinCPU keeps all the CPUs busy until their average frequency is stable, so
+// the benchmarks do not start while the CPUs ramp up from idle. It gives up
+// after spinMax and reports the frequency reached on stderr.
+func spinCPU(ctx context.Context) {
+	var stop int32
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&stop) == 0 {
+			}
+		}()
+	}
+	start := time.Now()
+	freq, ok := waitStableFreq(ctx, func() int64 { return readThermal().freq }, spinMax)
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+	d := time.Since(start).Round(spinPoll)
+	if ok {
+		stderrLog.infof("CPU frequency locked at %.2fGHz after %s", float64(freq)/1e6, d)
+	} else {
+		stderrLog.infof("CPU frequency still changing after %s, at %.2fGHz", d, float64(freq)/1e6)
+	}
+}
+
+// waitStableFreq samples the frequency every spinPoll until spinSamples
+// consecutive samples are within spinTolerance of each other, or max elapsed.
+// It returns the last sample and whether it is stable.
+func waitStableFreq(ctx context.Context, sample func() int64, max time.Duration) (int64, bool) {
+	t := time.NewTicker(spinPoll)
+	defer t.Stop()
+	deadline := time.Now().Add(max)
+	var last []int64
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, false
+		case <-t.C:
+		}
+		last = append(last, sample())
+		if len(last) > spinSamples {
+			last = last[1:]
+		}
+		if len(last) == spinSamples {
+			lo, hi := last[0], last[0]
+			for _, f := range last {
+				if f < lo {
+					lo = f
+				}
+				if f > hi {
+					hi = f
+				}
+			}
+			if float64(hi-lo) <= spinTolerance*float64(hi) {
+				return last[len(last)-1], true
+			}
+		}
+		if !time.Now().Before(deadline) {
+			return last[len(last)-1], false
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitStableFreq(t *testing.T) {
+	samples := []int64{1200000, 2500000, 3400000, 3410000, 3400000, 3405000}
+	i := 0
+	next := func() int64 {
+		v := samples[i]
+		if i < len(samples)-1 {
+			i++
+		}
+		return v
+	}
+	f, ok := waitStableFreq(context.Background(), next, time.Minute)
+	if !ok || f != 3400000 || i != 5 {
+		t.Fatal(f, ok, i)
+	}
+	// Never stable.
+	f, ok = waitStableFreq(context.Background(), func() int64 { i++; return int64(i) * 100000 }, 0)
+	if ok || f == 0 {
+		t.Fatal(f, ok)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"strings"
+
+	"golang.org/x/perf/benchfmt"
+	"golang.org/x/perf/benchmath"
+)
+
+// samples is the measured values of each benchmark metric of one side.
+type samples struct {
+	// keys is the "<pkg> <benchmark> <unit>" keys in order of appearance.
+	keys   []string
+	values map[string][]float64
+}
+
+// parseSamples parses go test -bench output.
+func parseSamples(out string) (*samples, error) {
+	s := &samples{values: map[string][]float64{}}
+	r := benchfmt.NewReader(strings.NewReader(out), "")
+	for r.Scan() {
+		res, ok := r.Result().(*benchfmt.Result)
+		if !ok {
+			continue
+		}
+		prefix := res.GetConfig("pkg") + " " + string(res.Name.Full()) + " "
+		for _, v := range res.Values {
+			k := prefix + v.Unit
+			if _, ok := s.values[k]; !ok {
+				s.keys = append(s.keys, k)
+			}
+			s.values[k] = append(s.values[k], v.Value)
+		}
+	}
+	return s, r.Err()
+}
+
+// widestCI returns the metric with the widest 95% confidence interval
+// relative to its median across all the sides, and the width in percent.
+func widestCI(stats []string) (string, float64, error) {
+	key := ""
+	widest := 0.
+	for _, out := range stats {
+		s, err := parseSamples(out)
+		if err != nil {
+			return "", 0, err
+		}
+		for _, k := range s.keys {
+			sum := benchmath.AssumeNothing.Summary(benchmath.NewSample(s.values[k], &benchmath.DefaultThresholds), 0.95)
+			if sum.Center == 0 {
+				continue
+			}
+			w := 100 * math.Max(sum.Hi-sum.Center, sum.Center-sum.Lo) / math.Abs(sum.Center)
+			if w > widest {
+				key = strings.TrimSpace(k)
+				widest = w
+			}
+		}
+	}
+	return key, widest, nil
+}
+
+// missingBenchmarks returns a line for each side that did not run all the
+// benchmarks run by the other sides, listing the missing ones, e.g. because
+// a benchmark was added in the new commit or -bench matches nothing on a
+// side.
+func missingBenchmarks(sides []*side, stats []string) ([]string, error) {
+	var all []string
+	ran := make([]map[string]bool, len(stats))
+	for i, out := range stats {
+		s, err := parseSamples(out)
+		if err != nil {
+			return nil, err
+		}
+		ran[i] = map[string]bool{}
+		for _, k := range s.keys {
+			// Strip the unit.
+			k = k[:strings.LastIndexByte(k, ' ')]
+			if !ran[i][k] {
+				ran[i][k] = true
+				if !contains(all, k) {
+					all = append(all, k)
+				}
+			}
+		}
+	}
+	var out []string
+	for i := range stats {
+		var missing []string
+		for _, k := range all {
+			if !ran[i][k] {
+				missing = append(missing, k[strings.IndexByte(k, ' ')+1:])
+			}
+		}
+		if len(missing) == len(all) {
+			out = append(out, sides[i].name+" ran no benchmark")
+		} else if len(missing) != 0 {
+			out = append(out, sides[i].name+" did not run "+strings.Join(missing, ", "))
+		}
+	}
+	return out, nil
+}
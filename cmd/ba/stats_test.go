@@ -0,0 +1,62 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestWidestCI(t *testing.T) {
+	stable := "pkg: foo\n"
+	noisy := "pkg: foo\n"
+	for i := 0; i < 10; i++ {
+		stable += "BenchmarkA 1 100 ns/op\nBenchmarkB 1 10 ns/op\n"
+		if i&1 == 0 {
+			noisy += "BenchmarkA 1 100 ns/op\nBenchmarkB 1 10 ns/op\n"
+		} else {
+			noisy += "BenchmarkA 1 100 ns/op\nBenchmarkB 1 20 ns/op\n"
+		}
+	}
+	k, w, err := widestCI([]string{stable, stable})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != 0 {
+		t.Fatal(k, w)
+	}
+	k, w, err = widestCI([]string{stable, noisy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != "foo B sec/op" || w < 10 {
+		t.Fatal(k, w)
+	}
+	k, w, err = widestCI([]string{"BenchmarkA 1 100 ns/op\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != "A sec/op" || !math.IsInf(w, 1) {
+		t.Fatal(k, w)
+	}
+}
+
+func TestMissingBenchmarks(t *testing.T) {
+	sides := []*side{{name: "old"}, {name: "new"}, {name: "empty"}}
+	old := "pkg: foo\nBenchmarkA 1 100 ns/op 8 B/op\n"
+	new := "pkg: foo\nBenchmarkA 1 100 ns/op\nBenchmarkB/x 1 10 ns/op\npkg: bar\nBenchmarkC 1 10 ns/op\n"
+	got, err := missingBenchmarks(sides, []string{old, new, "PASS\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"old did not run B/x, C", "empty ran no benchmark"}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("%q", got)
+	}
+	if got, err = missingBenchmarks(sides[:2], []string{old, old}); err != nil || len(got) != 0 {
+		t.Fatal(got, err)
+	}
+}
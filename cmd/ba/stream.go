@@ -0,0 +1,41 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/maruel/pat/benchcmp"
+)
+
+// streamLine is a line written by -stream: the comparisons of the series
+// completed so far.
+type streamLine struct {
+	// Series is the number of series completed.
+	Series int
+	// Final is set on the last line, once all the series completed.
+	Final bool `json:",omitempty"`
+	// Partial is set on the last line when a series failed.
+	Partial     bool `json:",omitempty"`
+	Comparisons []*streamComparison
+}
+
+// streamComparison is a comparison in a streamLine.
+type streamComparison struct {
+	Old    string
+	New    string
+	Tables []*benchcmp.JSONTable
+}
+
+// writeStreamLine writes the comparisons of the session as a single JSON line.
+func writeStreamLine(w io.Writer, s *session, c []*comparison, final bool) error {
+	l := &streamLine{Series: s.Series, Final: final, Partial: final && s.Partial}
+	for _, cmp := range c {
+		l.Comparisons = append(l.Comparisons, &streamComparison{Old: cmp.old, New: cmp.new, Tables: benchcmp.ToJSON(cmp.tables)})
+	}
+	// Encode terminates the value with a newline and does not indent.
+	return json.NewEncoder(w).Encode(l)
+}
@@ -0,0 +1,44 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteStreamLine(t *testing.T) {
+	tables, err := genBenchTables("HEAD~1", "HEAD", testOld, testNew, defaultTableOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := []*comparison{{old: "HEAD~1", new: "HEAD", tables: tables}}
+	var buf bytes.Buffer
+	for _, final := range []bool{false, true} {
+		s := &session{Series: 2, Partial: true}
+		if err = writeStreamLine(&buf, s, c, final); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%q", buf.String())
+	}
+	for i, l := range lines {
+		got := streamLine{}
+		if err = json.Unmarshal([]byte(l), &got); err != nil {
+			t.Fatal(err)
+		}
+		final := i == 1
+		if got.Series != 2 || got.Final != final || got.Partial != final || len(got.Comparisons) != 1 {
+			t.Fatalf("#%d: %s", i, l)
+		}
+		if cmp := got.Comparisons[0]; cmp.Old != "HEAD~1" || cmp.New != "HEAD" || len(cmp.Tables) != len(tables) {
+			t.Fatalf("#%d: %s", i, l)
+		}
+	}
+}
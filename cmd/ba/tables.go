@@ -0,0 +1,24 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import "github.com/maruel/pat/benchcmp"
+
+// The comparison engine is in the benchcmp package so other tools can embed
+// it.
+type (
+	table        = benchcmp.Table
+	row          = benchcmp.Row
+	cell         = benchcmp.Cell
+	tableOptions = benchcmp.Options
+)
+
+// defaultTableOptions matches benchstat's defaults.
+var defaultTableOptions = benchcmp.DefaultOptions
+
+// genBenchTables compares the output of two configurations.
+func genBenchTables(against, head, o, n string, t *tableOptions) ([]*table, error) {
+	return benchcmp.Compare([]string{against, head}, []string{o, n}, t)
+}
@@ -0,0 +1,196 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// testBinary is a compiled test binary for one package.
+type testBinary struct {
+	// pkg is the package import path.
+	pkg string
+	// dir is the package source directory. The binary is run from there so
+	// it can access its testdata.
+	dir string
+	// path is the compiled binary.
+	path string
+	// root is the root of the checkout containing dir.
+	root string
+}
+
+// buildKey identifies the test binaries of a side. Sides with the same key
+// share the same binaries.
+func (s *side) buildKey() string {
+	return s.ref + "\x00" + s.dir + "\x00" + s.bin + "\x00" + s.goTool + "\x00" + strings.Join(s.buildFlags, " ")
+}
+
+// goCmd returns the go command to build the side's test binaries with.
+func (s *side) goCmd() string {
+	if s.goTool != "" {
+		return s.goTool
+	}
+	return "go"
+}
+
+// toolchainSides returns the sides to benchmark the current checkout built
+// with two Go toolchains, as specified by -go-old and -go-new. Each defaults
+// to the go command in PATH.
+//
+// The sides are named after the toolchain versions when they differ.
+func toolchainSides(goOld, goNew string) ([]*side, error) {
+	var sides []*side
+	var versions []string
+	for _, t := range []string{goOld, goNew} {
+		if t == "" {
+			t = "go"
+		}
+		p, err := exec.LookPath(t)
+		if err != nil {
+			return nil, err
+		}
+		// Do not let go.mod's toolchain directive switch to another toolchain.
+		c := exec.Command(p, "env", "GOVERSION")
+		c.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+		out, err := c.Output()
+		if err != nil {
+			return nil, fmt.Errorf("%s env GOVERSION: %w", t, err)
+		}
+		versions = append(versions, strings.TrimSpace(string(out)))
+		sides = append(sides, &side{name: t, goTool: p})
+	}
+	if versions[0] != versions[1] {
+		for i, v := range versions {
+			sides[i].name = v
+		}
+	}
+	return sides, nil
+}
+
+// binarySides returns the sides to compare two prebuilt test binaries, as
+// specified by -binary.
+func binarySides(v string) ([]*side, error) {
+	paths := strings.Split(v, ",")
+	if len(paths) != 2 {
+		return nil, errors.New("-binary requires exactly two comma separated test binaries")
+	}
+	var sides []*side
+	for _, p := range paths {
+		a, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = os.Stat(a); err != nil {
+			return nil, err
+		}
+		sides = append(sides, &side{name: p, bin: a})
+	}
+	return sides, nil
+}
+
+// buildEnv returns the environment variables to add to the go command to
+// build the side.
+func (s *side) buildEnv(o *benchOptions) []string {
+	if s.goTool == "" {
+		return o.buildEnv
+	}
+	// Do not let go.mod's toolchain directive switch to another toolchain.
+	return append([]string{"GOTOOLCHAIN=local"}, o.buildEnv...)
+}
+
+// goBuildFlags returns the flags to build the side with: the global ones,
+// e.g. -tags, then the side's own.
+func (s *side) goBuildFlags(o *benchOptions) []string {
+	return append(append([]string(nil), o.buildFlags...), s.buildFlags...)
+}
+
+// pgoSides returns the sides to benchmark the current checkout built without
+// and with the PGO profile, as specified by -pgo.
+func pgoSides(profile string) ([]*side, error) {
+	a, err := filepath.Abs(profile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(a); err != nil {
+		return nil, err
+	}
+	return []*side{
+		{name: "pgo=off", buildFlags: []string{"-pgo=off"}},
+		{name: "pgo=" + filepath.Base(a), buildFlags: []string{"-pgo=" + a}},
+	}, nil
+}
+
+// buildTestBinaries compiles the test binaries of the packages to benchmark
+// for the side, once. They are reused for all the following runs.
+//
+// It must be called with the side's ref checked out.
+func buildTestBinaries(ctx context.Context, o *benchOptions, s *side) ([]*testBinary, error) {
+	k := s.buildKey()
+	if b, ok := o.binaries[k]; ok {
+		return b, nil
+	}
+	if s.bin != "" {
+		b := []*testBinary{{pkg: s.bin, path: s.bin}}
+		o.binaries[k] = b
+		return b, nil
+	}
+	pkg := o.pkgPattern()
+	env := s.buildEnv(o)
+	root := s.dir
+	if r, err := repo.root(root); err == nil {
+		root = r
+	}
+	d := filepath.Join(o.binDir, strconv.Itoa(len(o.binaries)))
+	if o.isolateCache {
+		// Deleted along with the binaries.
+		env = append(env, "GOCACHE="+filepath.Join(d, "gocache"))
+	}
+	var bins []*testBinary
+	for _, m := range splitModules(s.dir, pkg) {
+		// Skip packages without tests.
+		/* #nosec G204 */
+		args := append(append([]string{"list"}, s.goBuildFlags(o)...), "-f", "{{if or .TestGoFiles .XTestGoFiles}}{{.ImportPath}}\t{{.Dir}}{{end}}", m.pattern)
+		c := exec.CommandContext(ctx, s.goCmd(), args...)
+		c.Dir = m.dir
+		if len(env) != 0 {
+			c.Env = append(os.Environ(), env...)
+		}
+		out, err := c.Output()
+		if err != nil {
+			return nil, fmt.Errorf("go list %s: %w", m.pattern, err)
+		}
+		for _, l := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			f := strings.SplitN(l, "\t", 2)
+			if len(f) != 2 || (o.only != nil && !o.only[f[0]]) {
+				continue
+			}
+			b := &testBinary{pkg: f[0], dir: f[1], path: filepath.Join(d, strconv.Itoa(len(bins))+".test"), root: root}
+			if runtime.GOOS == "windows" && len(o.buildEnv) == 0 {
+				b.path += ".exe"
+			}
+			args := append(append([]string{"test", "-c"}, s.goBuildFlags(o)...), "-o", b.path, b.pkg)
+			o.logCmd("%s%s %s", s.logPrefix(m.dir, env), s.goCmd(), strings.Join(args, " "))
+			c = command(s.goCmd(), args...)
+			c.Dir = m.dir
+			if len(env) != 0 {
+				c.Env = append(os.Environ(), env...)
+			}
+			if out, err = combinedOutput(ctx, c); err != nil {
+				return nil, fmt.Errorf("go test -c %s: %w\n%s", b.pkg, err, out)
+			}
+			bins = append(bins, b)
+		}
+	}
+	o.binaries[k] = bins
+	return bins, nil
+}
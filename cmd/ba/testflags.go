@@ -0,0 +1,106 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// goTestFlags are the flags of go test that are passed to the test binary, as
+// documented in go help testflag, and whether they are boolean. The others are
+// build flags.
+var goTestFlags = map[string]bool{
+	"benchmem":             true,
+	"blockprofile":         false,
+	"blockprofilerate":     false,
+	"coverprofile":         false,
+	"cpuprofile":           false,
+	"failfast":             true,
+	"fullpath":             true,
+	"memprofile":           false,
+	"memprofilerate":       false,
+	"mutexprofile":         false,
+	"mutexprofilefraction": false,
+	"outputdir":            false,
+	"parallel":             false,
+	"short":                true,
+	"shuffle":              false,
+	"skip":                 false,
+	"timeout":              false,
+	"trace":                false,
+}
+
+// ownTestFlags are the test flags that ba sets itself, and whether ba has a
+// flag of the same name to set it.
+var ownTestFlags = map[string]bool{
+	"bench":     true,
+	"benchtime": true,
+	"count":     true,
+	"cpu":       true,
+	"json":      false,
+	"run":       false,
+	"v":         false,
+}
+
+// boolBuildFlags are the build flags of go test that do not take a value.
+var boolBuildFlags = map[string]bool{
+	"a":          true,
+	"asan":       true,
+	"cover":      true,
+	"linkshared": true,
+	"modcacherw": true,
+	"msan":       true,
+	"race":       true,
+	"trimpath":   true,
+	"work":       true,
+	"x":          true,
+}
+
+// parseTestFlags splits the go test flags of -testflags into the build flags
+// passed to go test -c and the flags passed to the test binary, with their
+// -test. prefix.
+func parseTestFlags(v string) ([]string, []string, error) {
+	var build, test []string
+	args := strings.Fields(v)
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if !strings.HasPrefix(a, "-") || a == "-" || a == "--" {
+			return nil, nil, fmt.Errorf("-testflags: unexpected argument %q", a)
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(a, "-"), "-")
+		hasValue := strings.Contains(name, "=")
+		if hasValue {
+			name = name[:strings.Index(name, "=")]
+		}
+		if same, ok := ownTestFlags[name]; ok {
+			if same {
+				return nil, nil, fmt.Errorf("-testflags: use ba's -%s flag instead", name)
+			}
+			return nil, nil, fmt.Errorf("-testflags: -%s is set by ba", name)
+		}
+		isBool, isTest := goTestFlags[name]
+		if !isTest {
+			isBool = boolBuildFlags[name]
+		}
+		f := []string{a}
+		if isTest {
+			f[0] = "-test." + strings.TrimLeft(a, "-")
+		}
+		if !isBool && !hasValue {
+			if i+1 == len(args) {
+				return nil, nil, fmt.Errorf("-testflags: -%s requires a value", name)
+			}
+			i++
+			f = append(f, args[i])
+		}
+		if isTest {
+			test = append(test, f...)
+		} else {
+			build = append(build, f...)
+		}
+	}
+	return build, test, nil
+}
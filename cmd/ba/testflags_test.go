@@ -0,0 +1,31 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTestFlags(t *testing.T) {
+	build, test, err := parseTestFlags("-race -short -timeout 1m -gcflags all=-N --benchmem -tags=a,b -cpuprofile=x.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"-race", "-gcflags", "all=-N", "-tags=a,b"}; !reflect.DeepEqual(want, build) {
+		t.Fatalf("%q != %q", want, build)
+	}
+	if want := []string{"-test.short", "-test.timeout", "1m", "-test.benchmem", "-test.cpuprofile=x.out"}; !reflect.DeepEqual(want, test) {
+		t.Fatalf("%q != %q", want, test)
+	}
+	if build, test, err = parseTestFlags(""); build != nil || test != nil || err != nil {
+		t.Fatal(build, test, err)
+	}
+	for _, v := range []string{"-count 3", "-run=Foo", "./pkg", "-timeout", "-tags"} {
+		if _, _, err := parseTestFlags(v); err == nil {
+			t.Fatalf("%s: expected error", v)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// testEvent is an event as emitted by go test -json.
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+// parseTestOutput converts the output of a test binary run with -test.v into
+// go test -json events via test2json and checks them for failures.
+//
+// It returns the benchmark output. runErr is the error returned by running
+// the test binary, if any.
+func parseTestOutput(ctx context.Context, pkg, raw string, runErr error) (string, error) {
+	/* #nosec G204 */
+	c := exec.CommandContext(ctx, "go", "tool", "test2json", "-p", pkg)
+	c.Stdin = strings.NewReader(raw)
+	j, err := c.Output()
+	if err != nil {
+		return raw, fmt.Errorf("go tool test2json: %w", err)
+	}
+	out, err := checkTestEvents(j)
+	if err == nil && runErr != nil {
+		// e.g. the process crashed without any test failure reported.
+		err = fmt.Errorf("%s: %w\n%s", pkg, runErr, lastLines(out, 20))
+	}
+	return out, err
+}
+
+// checkTestEvents returns the concatenated output of the go test -json events
+// in j. Skipped benchmarks are reported on stderr. Failed ones, or a package
+// failure like a panic, are returned as an error with their output.
+func checkTestEvents(j []byte) (string, error) {
+	var out strings.Builder
+	var failed []testEvent
+	d := json.NewDecoder(bytes.NewReader(j))
+	for {
+		var e testEvent
+		if err := d.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return out.String(), err
+		}
+		switch e.Action {
+		case "output":
+			out.WriteString(e.Output)
+		case "skip":
+			if e.Test != "" {
+				stderrLog.infof("%s %s was skipped", e.Package, e.Test)
+			}
+		case "fail":
+			failed = append(failed, e)
+		}
+	}
+	var msgs []string
+	for _, e := range failed {
+		if e.Test != "" {
+			msgs = append(msgs, fmt.Sprintf("%s %s failed:\n%s", e.Package, e.Test, benchLog(out.String(), e.Test)))
+		}
+	}
+	if len(msgs) == 0 && len(failed) != 0 {
+		// Only report the package failure when no benchmark did, otherwise it
+		// is redundant.
+		msgs = append(msgs, fmt.Sprintf("%s failed:\n%s", failed[0].Package, lastLines(out.String(), 20)))
+	}
+	if len(msgs) != 0 {
+		return out.String(), errors.New(strings.TrimSpace(strings.Join(msgs, "\n")))
+	}
+	return out.String(), nil
+}
+
+// benchLog returns the lines logged by the benchmark name in the go test -v
+// output out, up to its "--- FAIL" line and the indented lines that follow.
+//
+// test2json attributes a benchmark's log to the benchmark before it, as the
+// log is printed before the "--- FAIL" line.
+func benchLog(out, name string) string {
+	lines := strings.Split(out, "\n")
+	end := -1
+	for i, l := range lines {
+		if l == "--- FAIL: "+name {
+			end = i
+			break
+		}
+	}
+	if end == -1 {
+		return ""
+	}
+	start := end
+	for start > 0 && lines[start-1] != name {
+		start--
+	}
+	for end+1 < len(lines) && strings.HasPrefix(lines[end+1], " ") {
+		end++
+	}
+	return strings.Join(lines[start:end+1], "\n")
+}
+
+// lastLines returns the last n lines of s.
+func lastLines(s string, n int) string {
+	l := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(l) > n {
+		l = l[len(l)-n:]
+	}
+	return strings.Join(l, "\n")
+}
@@ -0,0 +1,40 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestCheckTestEvents(t *testing.T) {
+	ok := `{"Action":"start","Package":"x"}
+{"Action":"output","Package":"x","Output":"BenchmarkOK\n"}
+{"Action":"output","Package":"x","Output":"BenchmarkOK \t100\t 10 ns/op\n"}
+{"Action":"output","Package":"x","Output":"PASS\n"}
+{"Action":"pass","Package":"x"}
+`
+	out, err := checkTestEvents([]byte(ok))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "BenchmarkOK\nBenchmarkOK \t100\t 10 ns/op\nPASS\n"; out != want {
+		t.Fatalf("%q", out)
+	}
+
+	fail := `{"Action":"output","Package":"x","Output":"BenchmarkFail\n"}
+{"Action":"output","Package":"x","Output":"    x_test.go:17: boom\n"}
+{"Action":"output","Package":"x","Test":"BenchmarkFail","Output":"--- FAIL: BenchmarkFail\n"}
+{"Action":"output","Package":"x","Test":"BenchmarkFail","Output":"BenchmarkOK\n"}
+{"Action":"output","Package":"x","Test":"BenchmarkFail","Output":"BenchmarkOK \t100\t 10 ns/op\n"}
+{"Action":"fail","Package":"x","Test":"BenchmarkFail"}
+{"Action":"output","Package":"x","Output":"FAIL\n"}
+{"Action":"fail","Package":"x"}
+`
+	_, err = checkTestEvents([]byte(fail))
+	if err == nil {
+		t.Fatal("expected failure")
+	}
+	if want := "x BenchmarkFail failed:\n    x_test.go:17: boom\n--- FAIL: BenchmarkFail"; err.Error() != want {
+		t.Fatalf("%q", err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// thermalSample is a snapshot of the CPU thermal state. Zero values mean
+// unknown.
+type thermalSample struct {
+	// throttles is the cumulative count of thermal throttling events across
+	// all CPUs. -1 if unknown.
+	throttles int64
+	// temp is the hottest thermal zone temperature in milli-degree Celsius.
+	temp int64
+	// freq is the average current CPU frequency in kHz.
+	freq int64
+}
+
+// String returns the known values of the sample.
+func (t thermalSample) String() string {
+	var out []string
+	if t.temp != 0 {
+		out = append(out, fmt.Sprintf("%.1f°C", float64(t.temp)/1000))
+	}
+	if t.freq != 0 {
+		out = append(out, fmt.Sprintf("%.2fGHz", float64(t.freq)/1e6))
+	}
+	return strings.Join(out, " ")
+}
+
+// throttled returns why the machine is considered to have been thermally
+// throttled while running between before and after, or "" if not. base is
+// the sample taken before the first series.
+//
+// The kernel throttling counters are used when available. Otherwise, a drop
+// of more than 10% of the CPU frequency relative to base is considered
+// throttling.
+func throttled(base, before, after thermalSample) string {
+	if before.throttles >= 0 && after.throttles >= 0 {
+		if d := after.throttles - before.throttles; d > 0 {
+			return fmt.Sprintf("throttled %d times, %s", d, after)
+		}
+		return ""
+	}
+	if base.freq != 0 && after.freq != 0 && after.freq*10 < base.freq*9 {
+		return fmt.Sprintf("CPU frequency dropped from %.2fGHz, %s", float64(base.freq)/1e6, after)
+	}
+	return ""
+}
+
+// cpuinfoFreq returns the average "cpu MHz" of /proc/cpuinfo in kHz, for
+// machines without cpufreq like most VMs.
+func cpuinfoFreq(cpuinfo string) int64 {
+	sum, n := 0., 0
+	for _, l := range strings.Split(cpuinfo, "\n") {
+		if !strings.HasPrefix(l, "cpu MHz") {
+			continue
+		}
+		if i := strings.IndexByte(l, ':'); i != -1 {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(l[i+1:]), 64); err == nil {
+				sum += v
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return int64(sum / float64(n) * 1000)
+}
@@ -0,0 +1,47 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+)
+
+// readThermal samples the CPU thermal state from sysfs.
+func readThermal() thermalSample {
+	t := thermalSample{throttles: -1}
+	if m, _ := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/thermal_throttle/*_throttle_count"); len(m) != 0 {
+		t.throttles = 0
+		for _, p := range m {
+			t.throttles += readSysInt(p)
+		}
+	}
+	m, _ := filepath.Glob("/sys/class/thermal/thermal_zone[0-9]*/temp")
+	for _, p := range m {
+		if v := readSysInt(p); v > t.temp {
+			t.temp = v
+		}
+	}
+	m, _ = filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_cur_freq")
+	n := int64(0)
+	for _, p := range m {
+		if v := readSysInt(p); v > 0 {
+			t.freq += v
+			n++
+		}
+	}
+	if n != 0 {
+		t.freq /= n
+	} else {
+		t.freq = cpuinfoFreq(readSys("/proc/cpuinfo"))
+	}
+	return t
+}
+
+// readSysInt returns the integer in a sysfs file, or 0 if it can't be read.
+func readSysInt(p string) int64 {
+	v, _ := strconv.ParseInt(readSys(p), 10, 64)
+	return v
+}
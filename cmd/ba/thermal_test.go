@@ -0,0 +1,36 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestThrottled(t *testing.T) {
+	data := []struct {
+		base, before, after thermalSample
+		want                string
+	}{
+		{thermalSample{throttles: 3}, thermalSample{throttles: 3}, thermalSample{throttles: 3}, ""},
+		{thermalSample{throttles: 3}, thermalSample{throttles: 3}, thermalSample{throttles: 5, temp: 95500}, "throttled 2 times, 95.5°C"},
+		// Without counters, fallback to the frequency.
+		{thermalSample{throttles: -1, freq: 3000000}, thermalSample{throttles: -1}, thermalSample{throttles: -1, freq: 2800000}, ""},
+		{thermalSample{throttles: -1, freq: 3000000}, thermalSample{throttles: -1}, thermalSample{throttles: -1, freq: 2000000}, "CPU frequency dropped from 3.00GHz, 2.00GHz"},
+		{thermalSample{throttles: -1}, thermalSample{throttles: -1}, thermalSample{throttles: -1}, ""},
+	}
+	for i, l := range data {
+		if got := throttled(l.base, l.before, l.after); got != l.want {
+			t.Fatalf("#%d: %q", i, got)
+		}
+	}
+}
+
+func TestCPUInfoFreq(t *testing.T) {
+	cpuinfo := "processor\t: 0\ncpu MHz\t\t: 2100.000\n\nprocessor\t: 1\ncpu MHz\t\t: 3000.500\n"
+	if got := cpuinfoFreq(cpuinfo); got != 2550250 {
+		t.Fatal(got)
+	}
+	if got := cpuinfoFreq("processor\t: 0\n"); got != 0 {
+		t.Fatal(got)
+	}
+}
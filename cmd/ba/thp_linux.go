@@ -0,0 +1,48 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// prSetTHPDisable and prGetTHPDisable are PR_SET_THP_DISABLE and
+// PR_GET_THP_DISABLE from linux/prctl.h.
+const (
+	prSetTHPDisable = 41
+	prGetTHPDisable = 42
+)
+
+// setTHP enables or disables transparent huge pages for the process. The
+// setting is inherited by child processes, which is what we care about.
+//
+// Enabling them only clears a disable inherited from the parent process; it
+// fails when they are disabled system wide.
+//
+// The returned function must be called to restore the previous setting.
+func setTHP(enabled bool) (func(), error) {
+	if enabled {
+		if b, err := os.ReadFile("/sys/kernel/mm/transparent_hugepage/enabled"); err == nil && strings.Contains(string(b), "[never]") {
+			return nil, errors.New("thp=on: transparent huge pages are disabled system wide in /sys/kernel/mm/transparent_hugepage/enabled")
+		}
+	}
+	prev, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prGetTHPDisable, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	v := uintptr(1)
+	if enabled {
+		v = 0
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetTHPDisable, v, 0); errno != 0 {
+		return nil, errno
+	}
+	return func() {
+		_, _, _ = syscall.RawSyscall(syscall.SYS_PRCTL, prSetTHPDisable, prev, 0)
+	}, nil
+}
@@ -0,0 +1,13 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+//go:build !linux
+
+package main
+
+import "errors"
+
+func setTHP(enabled bool) (func(), error) {
+	return nil, errors.New("setting transparent huge pages is only supported on linux")
+}
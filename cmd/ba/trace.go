@@ -0,0 +1,261 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// traceStats summarizes the execution trace of a benchmark.
+type traceStats struct {
+	// gcs is the number of garbage collections.
+	gcs int
+	// gcPause is the total duration of the stop-the-world phases of the GC.
+	gcPause time.Duration
+	// goroutines is the number of goroutines created and maxGoroutines the
+	// peak number of goroutines alive.
+	goroutines    int
+	maxGoroutines int
+	// latencies are the scheduler latencies, i.e. the durations between a
+	// goroutine becoming runnable and running, sorted.
+	latencies []time.Duration
+}
+
+// percentile returns the p percentile of the scheduler latencies.
+func (t *traceStats) percentile(p float64) time.Duration {
+	if len(t.latencies) == 0 {
+		return 0
+	}
+	return t.latencies[int(p*float64(len(t.latencies)-1))]
+}
+
+var (
+	traceTimeRe  = regexp.MustCompile(`\bTime=(\d+)`)
+	traceGoRe    = regexp.MustCompile(`\bGoID=(\d+) (\w+)->(\w+)`)
+	traceRangeRe = regexp.MustCompile(`\bName="([^"]*)" Scope=(\S+)`)
+)
+
+// parseTraceDump summarizes the events printed by go tool trace -d=parsed.
+// The format is meant for debugging so only the fields needed are parsed.
+func parseTraceDump(r io.Reader) (*traceStats, error) {
+	t := &traceStats{}
+	runnable := map[string]int64{}
+	ranges := map[string]int64{}
+	alive := 0
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for s.Scan() {
+		// The stack frames of the events are indented.
+		f := strings.Fields(s.Text())
+		if len(f) < 4 || !strings.HasPrefix(f[0], "M=") {
+			continue
+		}
+		m := traceTimeRe.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+		ts, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		switch f[3] {
+		case "StateTransition":
+			g := traceGoRe.FindStringSubmatch(s.Text())
+			if g == nil {
+				// A proc transition.
+				continue
+			}
+			id, from, to := g[1], g[2], g[3]
+			switch {
+			case from == "NotExist" && to != "NotExist":
+				t.goroutines++
+				alive++
+			case from == "Undetermined" && to != "NotExist":
+				// It existed before the trace started.
+				alive++
+			case to == "NotExist":
+				alive--
+			}
+			if alive > t.maxGoroutines {
+				t.maxGoroutines = alive
+			}
+			if to == "Runnable" {
+				runnable[id] = ts
+			} else if from == "Runnable" && to == "Running" {
+				if start, ok := runnable[id]; ok {
+					t.latencies = append(t.latencies, time.Duration(ts-start))
+					delete(runnable, id)
+				}
+			}
+		case "RangeBegin", "RangeEnd":
+			n := traceRangeRe.FindStringSubmatch(s.Text())
+			if n == nil {
+				continue
+			}
+			if f[3] == "RangeBegin" {
+				if n[1] == "GC concurrent mark phase" {
+					t.gcs++
+				}
+				ranges[n[1]+" "+n[2]] = ts
+			} else if start, ok := ranges[n[1]+" "+n[2]]; ok {
+				if strings.HasPrefix(n[1], "stop-the-world (GC ") {
+					t.gcPause += time.Duration(ts - start)
+				}
+				delete(ranges, n[1]+" "+n[2])
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(t.latencies, func(i, j int) bool { return t.latencies[i] < t.latencies[j] })
+	return t, nil
+}
+
+// benchRegexp returns the -test.bench regexp matching exactly the benchmark
+// name, e.g. "BenchmarkFoo/bar" becomes "^BenchmarkFoo$/^bar$".
+func benchRegexp(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(p) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+// traceSides runs the benchmark name once more on each side with the
+// execution tracer enabled, after the measured series since tracing adds
+// overhead, and writes the summary of the traces to w.
+//
+// The traces are kept in a new temporary directory so they can be opened with
+// go tool trace.
+func traceSides(ctx context.Context, w io.Writer, o *benchOptions, branch string, sides []*side, name string) error {
+	stderrLog.infof("tracing")
+	dir, err := os.MkdirTemp("", "ba-trace-")
+	if err != nil {
+		return err
+	}
+	want := "Benchmark" + strings.TrimPrefix(name, "Benchmark")
+	bench := benchRegexp(want)
+	var names, paths []string
+	var stats []*traceStats
+	for i, s := range sides {
+		if s.cmd != "" {
+			continue
+		}
+		bins, err := buildTestBinaries(ctx, o, s)
+		if err != nil {
+			return err
+		}
+		p := filepath.Join(dir, strconv.Itoa(i)+"-"+unsafeChars.ReplaceAllString(s.name, "_")+".trace")
+		found := false
+		err = inSide(o, branch, s, func() error {
+			for j, b := range bins {
+				f := filepath.Join(dir, strconv.Itoa(i)+"-"+strconv.Itoa(j)+".trace")
+				out, err2 := runTestBinary(ctx, o, s, b, bench, o.benchtime.String(), 1, "-test.trace", f)
+				if err2 != nil {
+					return err2
+				}
+				// The other packages do not have the benchmark and their trace
+				// only contains the test startup.
+				if !found && strings.Contains(out, want) {
+					found = true
+					if err2 = os.Rename(f, p); err2 != nil {
+						return err2
+					}
+					continue
+				}
+				_ = os.Remove(f)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("-trace: %s did not run on %s", want, s.name)
+		}
+		st, err := summarizeTrace(ctx, o, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, s.name)
+		paths = append(paths, p)
+		stats = append(stats, st)
+	}
+	if len(stats) == 0 {
+		return errors.New("-trace requires a side running go test")
+	}
+	return printTraceStats(w, want, names, paths, stats)
+}
+
+// summarizeTrace returns the summary of the trace file at p.
+func summarizeTrace(ctx context.Context, o *benchOptions, p string) (*traceStats, error) {
+	o.logCmd("go tool trace -d=parsed %s", p)
+	/* #nosec G204 */
+	c := exec.CommandContext(ctx, "go", "tool", "trace", "-d=parsed", p)
+	r, err := c.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr strings.Builder
+	c.Stderr = &stderr
+	if err = c.Start(); err != nil {
+		return nil, err
+	}
+	t, err := parseTraceDump(r)
+	if err != nil {
+		_ = c.Process.Kill()
+	}
+	if err2 := c.Wait(); err == nil && err2 != nil {
+		err = fmt.Errorf("go tool trace: %w\n%s", err2, stderr.String())
+	}
+	return t, err
+}
+
+// printTraceStats writes the summary of the trace of each side, one column
+// per side, followed by the paths of the traces.
+func printTraceStats(w io.Writer, bench string, names, paths []string, stats []*traceStats) error {
+	fmt.Fprintf(w, "trace of %s:\n", bench)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	row := func(label string, fn func(t *traceStats) string) {
+		fmt.Fprintf(tw, "%s\t", label)
+		for _, t := range stats {
+			fmt.Fprintf(tw, "%s\t", fn(t))
+		}
+		fmt.Fprintf(tw, "\n")
+	}
+	fmt.Fprintf(tw, "\t%s\t\n", strings.Join(names, "\t"))
+	row("GCs", func(t *traceStats) string { return strconv.Itoa(t.gcs) })
+	row("GC pauses", func(t *traceStats) string { return t.gcPause.String() })
+	row("goroutines created", func(t *traceStats) string { return strconv.Itoa(t.goroutines) })
+	row("max goroutines", func(t *traceStats) string { return strconv.Itoa(t.maxGoroutines) })
+	for _, p := range []struct {
+		label string
+		p     float64
+	}{{"sched latency p50", 0.5}, {"sched latency p90", 0.9}, {"sched latency p99", 0.99}, {"sched latency max", 1}} {
+		row(p.label, func(t *traceStats) string { return t.percentile(p.p).String() })
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for i, p := range paths {
+		fmt.Fprintf(w, "%s: %s\n", names[i], p)
+	}
+	_, err := fmt.Fprintf(w, "\n")
+	return err
+}
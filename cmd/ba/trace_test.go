@@ -0,0 +1,48 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTraceDump(t *testing.T) {
+	dump := `M=1 P=-1 G=-1 StateTransition Time=100 ProcID=0 Undetermined->Running Reason=""
+M=1 P=0 G=-1 StateTransition Time=100 GoID=1 Undetermined->Running Reason=""
+M=1 P=0 G=1 StateTransition Time=110 GoID=6 NotExist->Runnable Reason=""
+	main.main @ 0x5442ba
+	_testmain.go:46
+M=1 P=0 G=1 StateTransition Time=120 GoID=7 NotExist->Runnable Reason=""
+M=2 P=1 G=-1 StateTransition Time=130 GoID=6 Runnable->Running Reason=""
+M=3 P=2 G=-1 StateTransition Time=170 GoID=7 Runnable->Running Reason=""
+M=2 P=1 G=6 StateTransition Time=180 GoID=6 Running->NotExist Reason=""
+M=1 P=0 G=1 RangeBegin Time=200 Name="stop-the-world (GC sweep termination)" Scope=Goroutine(1)
+M=1 P=0 G=1 RangeEnd Time=250 Name="stop-the-world (GC sweep termination)" Scope=Goroutine(1) Attributes=[]
+M=1 P=0 G=1 RangeBegin Time=250 Name="GC concurrent mark phase" Scope=Global
+M=1 P=0 G=1 RangeEnd Time=400 Name="GC concurrent mark phase" Scope=Global Attributes=[]
+M=1 P=0 G=1 RangeBegin Time=400 Name="stop-the-world (GC mark termination)" Scope=Goroutine(1)
+M=1 P=0 G=1 RangeEnd Time=430 Name="stop-the-world (GC mark termination)" Scope=Goroutine(1) Attributes=[]
+M=1 P=0 G=1 RangeBegin Time=500 Name="stop-the-world (read mem stats)" Scope=Goroutine(1)
+M=1 P=0 G=1 RangeEnd Time=900 Name="stop-the-world (read mem stats)" Scope=Goroutine(1) Attributes=[]
+`
+	got, err := parseTraceDump(strings.NewReader(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.gcs != 1 || got.gcPause != 80 || got.goroutines != 2 || got.maxGoroutines != 3 {
+		t.Fatalf("%+v", got)
+	}
+	if got.percentile(0) != 20 || got.percentile(0.5) != 20 || got.percentile(1) != 50*time.Nanosecond {
+		t.Fatal(got.latencies)
+	}
+}
+
+func TestBenchRegexp(t *testing.T) {
+	if got := benchRegexp("BenchmarkFoo/a.b"); got != `^BenchmarkFoo$/^a\.b$` {
+		t.Fatal(got)
+	}
+}
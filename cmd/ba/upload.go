@@ -0,0 +1,200 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/benchmath"
+)
+
+// uploadUnits are the metrics published by -upload, with their Prometheus
+// metric name.
+var uploadUnits = map[string]string{
+	"ns/op":     "ba_ns_per_op",
+	"B/op":      "ba_bytes_per_op",
+	"allocs/op": "ba_allocs_per_op",
+}
+
+// metricPoint is the median of one benchmark metric of a side.
+type metricPoint struct {
+	side, sha1, pkg, name, unit string
+	value                       float64
+}
+
+// sessionPoints returns the median of each published metric of each side.
+func sessionPoints(s *session) ([]metricPoint, error) {
+	var out []metricPoint
+	for _, ss := range s.Sides {
+		smp, err := parseSamples(ss.Output)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range smp.keys {
+			f := strings.SplitN(k, " ", 3)
+			unit, scale := f[2], 1.
+			if unit == "sec/op" {
+				// benchfmt tidies ns/op.
+				unit, scale = "ns/op", 1e9
+			}
+			if _, ok := uploadUnits[unit]; !ok {
+				continue
+			}
+			sum := benchmath.AssumeNothing.Summary(benchmath.NewSample(smp.values[k], &benchmath.DefaultThresholds), 0.95)
+			out = append(out, metricPoint{side: ss.Name, sha1: ss.SHA1, pkg: f[0], name: f[1], unit: unit, value: scale * sum.Center})
+		}
+	}
+	return out, nil
+}
+
+// uploader publishes the results to a metrics store, as specified by -upload.
+type uploader struct {
+	// influx is true for InfluxDB, false for a Prometheus Pushgateway.
+	influx bool
+	method string
+	target string
+	// machine labels the results.
+	machine string
+}
+
+// newUploader parses the -upload URL:
+//
+//   - influx://host:8086/db, or influxs:// over https, writes InfluxDB line
+//     protocol. $INFLUX_TOKEN is sent as the token if set.
+//   - pushgateway://host:9091/job, or pushgateways:// over https, pushes to a
+//     Prometheus Pushgateway, grouped by job and machine.
+func newUploader(u string) (*uploader, error) {
+	p, err := url.Parse(u)
+	if err != nil {
+		return nil, fmt.Errorf("-upload: %w", err)
+	}
+	up := &uploader{machine: machineFingerprint()}
+	scheme := "http"
+	if strings.HasSuffix(p.Scheme, "s") {
+		scheme = "https"
+	}
+	switch p.Scheme {
+	case "influx", "influxs":
+		db := strings.Trim(p.Path, "/")
+		if db == "" {
+			return nil, fmt.Errorf("-upload %s: missing database", u)
+		}
+		up.influx = true
+		up.method = "POST"
+		up.target = scheme + "://" + p.Host + "/write?db=" + url.QueryEscape(db) + "&precision=s"
+	case "pushgateway", "pushgateways":
+		job := strings.Trim(p.Path, "/")
+		if job == "" {
+			job = "ba"
+		}
+		// PUT replaces the previous results of this machine.
+		up.method = "PUT"
+		up.target = scheme + "://" + p.Host + "/metrics/job/" + url.PathEscape(job) + "/machine/" + up.machine
+	default:
+		return nil, fmt.Errorf("-upload %s: unsupported scheme; use influx:// or pushgateway://", u)
+	}
+	return up, nil
+}
+
+// upload publishes the median ns/op, B/op and allocs/op of each benchmark of
+// each side, labeled with the commit and the machine.
+//
+// It returns the number of points published.
+func (up *uploader) upload(ctx context.Context, c *http.Client, s *session) (int, error) {
+	points, err := sessionPoints(s)
+	if err != nil || len(points) == 0 {
+		return 0, err
+	}
+	body := promLines(points)
+	contentType := "text/plain; version=0.0.4"
+	if up.influx {
+		body = influxLines(points, up.machine, s.Start.Unix())
+		contentType = "text/plain; charset=utf-8"
+	}
+	req, err := http.NewRequestWithContext(ctx, up.method, up.target, strings.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if t := os.Getenv("INFLUX_TOKEN"); t != "" && up.influx {
+		req.Header.Set("Authorization", "Token "+t)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("-upload: %s\n%s", resp.Status, b)
+	}
+	return len(points), nil
+}
+
+// influxLines formats the points in InfluxDB line protocol, one line per
+// benchmark with a field per unit.
+func influxLines(points []metricPoint, machine string, ts int64) string {
+	var out strings.Builder
+	prev := ""
+	for _, p := range points {
+		tags := "ba,machine=" + influxEscape(machine) + ",side=" + influxEscape(p.side)
+		if p.sha1 != "" {
+			tags += ",commit=" + influxEscape(p.sha1)
+		}
+		tags += ",pkg=" + influxEscape(p.pkg) + ",benchmark=" + influxEscape(p.name)
+		if tags == prev {
+			out.WriteString(",")
+		} else {
+			if prev != "" {
+				out.WriteString(" " + strconv.FormatInt(ts, 10) + "\n")
+			}
+			out.WriteString(tags + " ")
+			prev = tags
+		}
+		out.WriteString(influxEscape(p.unit) + "=" + strconv.FormatFloat(p.value, 'g', -1, 64))
+	}
+	if prev != "" {
+		out.WriteString(" " + strconv.FormatInt(ts, 10) + "\n")
+	}
+	return out.String()
+}
+
+// influxEscape escapes a tag or field key or a tag value.
+func influxEscape(s string) string {
+	return strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `).Replace(s)
+}
+
+// promLines formats the points in the Prometheus text exposition format.
+func promLines(points []metricPoint) string {
+	var out strings.Builder
+	typed := map[string]bool{}
+	// A metric's samples must be grouped together.
+	for _, unit := range []string{"ns/op", "B/op", "allocs/op"} {
+		m := uploadUnits[unit]
+		for _, p := range points {
+			if p.unit != unit {
+				continue
+			}
+			if !typed[m] {
+				typed[m] = true
+				fmt.Fprintf(&out, "# TYPE %s gauge\n", m)
+			}
+			fmt.Fprintf(&out, "%s{side=%s,commit=%s,pkg=%s,benchmark=%s} %s\n", m, promQuote(p.side), promQuote(p.sha1), promQuote(p.pkg), promQuote(p.name), strconv.FormatFloat(p.value, 'g', -1, 64))
+		}
+	}
+	return out.String()
+}
+
+// promQuote quotes a Prometheus label value.
+func promQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpload(t *testing.T) {
+	out := "pkg: example.com/a\nBenchmarkFoo 10 100 ns/op 8 B/op 1 allocs/op 3 widgets/op\nBenchmarkFoo 10 120 ns/op 8 B/op 1 allocs/op 3 widgets/op\n"
+	s := &session{
+		Sides: []*sessionSide{{Name: "HEAD~1", SHA1: "abc", Output: out}, {Name: "HEAD", Output: out}},
+		Start: time.Unix(1700000000, 0),
+	}
+	var method, path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.String(), string(b)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	ctx := context.Background()
+
+	up, err := newUploader("influx://" + host + "/bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := up.upload(ctx, srv.Client(), s); n != 6 || err != nil {
+		t.Fatal(n, err)
+	}
+	if method != "POST" || path != "/write?db=bench&precision=s" {
+		t.Fatal(method, path)
+	}
+	m := up.machine
+	want := "ba,machine=" + m + ",side=HEAD~1,commit=abc,pkg=example.com/a,benchmark=Foo ns/op=110,B/op=8,allocs/op=1 1700000000\n" +
+		"ba,machine=" + m + ",side=HEAD,pkg=example.com/a,benchmark=Foo ns/op=110,B/op=8,allocs/op=1 1700000000\n"
+	if body != want {
+		t.Fatalf("%q\n!=\n%q", want, body)
+	}
+
+	if up, err = newUploader("pushgateway://" + host); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := up.upload(ctx, srv.Client(), s); n != 6 || err != nil {
+		t.Fatal(n, err)
+	}
+	if method != "PUT" || path != "/metrics/job/ba/machine/"+m {
+		t.Fatal(method, path)
+	}
+	if !strings.HasPrefix(body, "# TYPE ba_ns_per_op gauge\nba_ns_per_op{side=\"HEAD~1\",commit=\"abc\",pkg=\"example.com/a\",benchmark=\"Foo\"} 110\n") {
+		t.Fatal(body)
+	}
+
+	for _, u := range []string{"http://localhost", "influx://localhost"} {
+		if _, err := newUploader(u); err == nil {
+			t.Fatalf("%s: expected error", u)
+		}
+	}
+}
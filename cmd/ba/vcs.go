@@ -0,0 +1,494 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// vcs is the version control system of the repository being benchmarked.
+//
+// Refs are passed as is, except for the git style "HEAD", "HEAD~N", "HEAD^"
+// and "origin/<branch>", e.g. the default -against origin/main, which are
+// translated for the other systems, so the defaults work everywhere.
+type vcs interface {
+	// name is the command of the version control system, e.g. "git".
+	name() string
+	// root returns the root of the checkout containing dir, the current
+	// directory when empty.
+	root(dir string) (string, error)
+	// resolve returns the commit hash of ref.
+	resolve(ref string) (string, error)
+	// commitTime returns the commit time of ref.
+	commitTime(ref string) (time.Time, error)
+	// current returns the ref to check back out after checkout.
+	current() (string, error)
+	// modified returns the uncommitted changes, empty when the checkout is
+	// pristine.
+	modified() (string, error)
+	// count returns the number of commits between a and b.
+	count(a, b string) (int, error)
+	// checkoutCmd, addWorktreeCmd and removeWorktreeCmd describe the commands
+	// run by checkout, addWorktree and removeWorktree.
+	checkoutCmd(ref string) string
+	addWorktreeCmd(dir, ref string) string
+	removeWorktreeCmd(dir string) string
+	// checkout checks out ref in the current checkout.
+	checkout(ref string) error
+	// addWorktree checks out ref in the new directory dir, leaving the current
+	// checkout untouched.
+	addWorktree(dir, ref string) error
+	// removeWorktree unregisters the directory created by addWorktree. The
+	// caller deletes it.
+	removeWorktree(dir string) error
+}
+
+// repo is the version control system of the current directory, as detected
+// by detectVCS.
+var repo vcs = gitVCS{}
+
+// detectVCS returns the version control system of the checkout containing
+// dir.
+//
+// jj is preferred over git since a jj repository can be colocated with a git
+// one. Defaults to git.
+func detectVCS(dir string) vcs {
+	d, err := filepath.Abs(dir)
+	if err != nil {
+		return gitVCS{}
+	}
+	for {
+		for _, v := range []vcs{jjVCS{}, hgVCS{}, gitVCS{}} {
+			if fi, err := os.Stat(filepath.Join(d, "."+v.name())); err == nil && (fi.IsDir() || v.name() == "git") {
+				// .git is a file in a git worktree.
+				return v
+			}
+		}
+		p := filepath.Dir(d)
+		if p == d {
+			return gitVCS{}
+		}
+		d = p
+	}
+}
+
+// headDepth returns N for the git style refs "HEAD", "HEAD~N" and "HEAD^^".
+func headDepth(ref string) (int, bool) {
+	if !strings.HasPrefix(ref, "HEAD") {
+		return 0, false
+	}
+	r := ref[len("HEAD"):]
+	if r == "" {
+		return 0, true
+	}
+	if r[0] == '~' {
+		if r == "~" {
+			return 1, true
+		}
+		n, err := strconv.Atoi(r[1:])
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		return n, true
+	}
+	if strings.Trim(r, "^") == "" {
+		return len(r), true
+	}
+	return 0, false
+}
+
+// remoteBranch returns the branch of the git style remote-tracking ref
+// "origin/<branch>".
+func remoteBranch(ref string) (string, bool) {
+	if b := strings.TrimPrefix(ref, "origin/"); b != ref && b != "" {
+		return b, true
+	}
+	return "", false
+}
+
+// run runs the version control command and returns its trimmed combined
+// output.
+func run(tool string, args ...string) (string, error) {
+	out, err := command(tool, args...).CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// runErr is like run but returns the output as the error.
+func runErr(tool string, args ...string) error {
+	if out, err := run(tool, args...); err != nil {
+		if out == "" {
+			return err
+		}
+		return errors.New(out)
+	}
+	return nil
+}
+
+// gitVCS is git.
+type gitVCS struct{}
+
+func (gitVCS) name() string { return "git" }
+
+func (gitVCS) root(dir string) (string, error) {
+	args := []string{"rev-parse", "--show-toplevel"}
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	out, err := git(args...)
+	if err != nil {
+		return "", errors.New(out)
+	}
+	return filepath.FromSlash(out), nil
+}
+
+func (gitVCS) resolve(ref string) (string, error) {
+	out, err := git("rev-parse", ref)
+	if err != nil {
+		return "", errors.New(out)
+	}
+	return out, nil
+}
+
+func (gitVCS) commitTime(ref string) (time.Time, error) {
+	out, err := git("log", "-1", "--format=%cI", ref)
+	if err != nil {
+		return time.Time{}, errors.New(out)
+	}
+	return time.Parse(time.RFC3339, out)
+}
+
+func (v gitVCS) current() (string, error) {
+	branch, err := git("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", errors.New(branch)
+	}
+	if branch == "HEAD" {
+		// We're in detached head. It's fine, just save the head.
+		sha1, err := v.resolve("HEAD")
+		if err != nil {
+			return "", err
+		}
+		branch = sha1[:16]
+	}
+	return branch, nil
+}
+
+func (gitVCS) modified() (string, error) {
+	out, err := git("status", "--porcelain")
+	if err != nil {
+		return "", errors.New(out)
+	}
+	return out, nil
+}
+
+func (gitVCS) count(a, b string) (int, error) {
+	out, err := git("rev-list", "--count", a+"..."+b)
+	if err != nil {
+		return 0, errors.New(out)
+	}
+	return strconv.Atoi(out)
+}
+
+func (gitVCS) checkoutCmd(ref string) string {
+	return "git checkout " + ref
+}
+
+func (gitVCS) addWorktreeCmd(dir, ref string) string {
+	return "git worktree add " + dir + " " + ref
+}
+
+func (gitVCS) removeWorktreeCmd(dir string) string {
+	return "git worktree remove " + dir
+}
+
+func (v gitVCS) checkout(ref string) error {
+	if err := runErr("git", "checkout", "-q", ref); err != nil {
+		return err
+	}
+	return v.sync(".")
+}
+
+func (v gitVCS) addWorktree(dir, ref string) error {
+	if err := runErr("git", "worktree", "add", "-q", "--detach", dir, ref); err != nil {
+		return err
+	}
+	return v.sync(dir)
+}
+
+// sync updates the submodules and the Git LFS files of the checkout containing
+// dir to match its commit. git checkout leaves both as is, so a side would
+// silently build with the content of another commit.
+func (gitVCS) sync(dir string) error {
+	root, err := git("-C", dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return errors.New(root)
+	}
+	if _, err = os.Stat(filepath.Join(root, ".gitmodules")); err == nil {
+		if err = runErr("git", "-C", root, "submodule", "update", "--init", "--recursive", "-q"); err != nil {
+			return fmt.Errorf("git submodule update: %w", err)
+		}
+	}
+	// git grep exits with 1 without output when nothing matches.
+	out, err := git("-C", root, "grep", "-l", "filter=lfs", "--", ".gitattributes", "*/.gitattributes")
+	if err != nil && out != "" {
+		return errors.New(out)
+	}
+	if out == "" {
+		return nil
+	}
+	if _, err = git("-C", root, "lfs", "version"); err != nil {
+		return errors.New("the repository uses Git LFS but git-lfs is not installed")
+	}
+	for i := 0; ; i++ {
+		if out, err = git("-C", root, "lfs", "ls-files"); err != nil {
+			return errors.New(out)
+		}
+		missing := lfsPointers(out)
+		if len(missing) == 0 {
+			return nil
+		}
+		if i == 1 {
+			return fmt.Errorf("missing Git LFS content for %s", strings.Join(missing, ", "))
+		}
+		// The objects are not in the local cache.
+		if err = runErr("git", "-C", root, "lfs", "pull"); err != nil {
+			return fmt.Errorf("git lfs pull: %w", err)
+		}
+	}
+}
+
+// lfsPointers returns the files listed by git lfs ls-files that are still
+// pointers instead of their content.
+func lfsPointers(out string) []string {
+	var l []string
+	for _, line := range strings.Split(out, "\n") {
+		// "<oid> * <path>" when checked out, "<oid> - <path>" otherwise.
+		if f := strings.SplitN(line, " ", 3); len(f) == 3 && f[1] == "-" {
+			l = append(l, f[2])
+		}
+	}
+	return l
+}
+
+func (gitVCS) removeWorktree(dir string) error {
+	return runErr("git", "worktree", "remove", "--force", dir)
+}
+
+// hgVCS is Mercurial.
+//
+// Mercurial has no worktrees so the other refs are exported with hg archive.
+type hgVCS struct{}
+
+func (hgVCS) name() string { return "hg" }
+
+// rev translates git style refs. Mercurial has no remote-tracking refs, so
+// "origin/<branch>" is the local branch, with main and master being default.
+func (hgVCS) rev(ref string) string {
+	if n, ok := headDepth(ref); ok {
+		if n == 0 {
+			return "."
+		}
+		return ".~" + strconv.Itoa(n)
+	}
+	if b, ok := remoteBranch(ref); ok {
+		if b == "main" || b == "master" {
+			return "default"
+		}
+		return b
+	}
+	return ref
+}
+
+func (hgVCS) root(dir string) (string, error) {
+	args := []string{"root"}
+	if dir != "" {
+		args = append(args, "--cwd", dir)
+	}
+	out, err := run("hg", args...)
+	if err != nil {
+		return "", errors.New(out)
+	}
+	return out, nil
+}
+
+func (v hgVCS) resolve(ref string) (string, error) {
+	out, err := run("hg", "log", "-l", "1", "-r", v.rev(ref), "-T", "{node}")
+	if err != nil {
+		return "", errors.New(out)
+	}
+	return out, nil
+}
+
+func (v hgVCS) commitTime(ref string) (time.Time, error) {
+	out, err := run("hg", "log", "-l", "1", "-r", v.rev(ref), "-T", "{date|rfc3339date}")
+	if err != nil {
+		return time.Time{}, errors.New(out)
+	}
+	return time.Parse(time.RFC3339, out)
+}
+
+func (v hgVCS) current() (string, error) {
+	out, err := run("hg", "log", "-r", ".", "-T", "{activebookmark}")
+	if err != nil {
+		return "", errors.New(out)
+	}
+	if out != "" {
+		return out, nil
+	}
+	node, err := v.resolve(".")
+	if err != nil {
+		return "", err
+	}
+	return node[:16], nil
+}
+
+func (hgVCS) modified() (string, error) {
+	out, err := run("hg", "status")
+	if err != nil {
+		return "", errors.New(out)
+	}
+	return out, nil
+}
+
+func (v hgVCS) count(a, b string) (int, error) {
+	a, b = v.rev(a), v.rev(b)
+	out, err := run("hg", "log", "-r", fmt.Sprintf("only(%s, %s) or only(%s, %s)", a, b, b, a), "-T", "x")
+	if err != nil {
+		return 0, errors.New(out)
+	}
+	return len(out), nil
+}
+
+func (v hgVCS) checkoutCmd(ref string) string {
+	return "hg update -r " + v.rev(ref)
+}
+
+func (v hgVCS) addWorktreeCmd(dir, ref string) string {
+	return "hg archive -r " + v.rev(ref) + " " + dir
+}
+
+func (hgVCS) removeWorktreeCmd(dir string) string {
+	return "rm -rf " + dir
+}
+
+func (v hgVCS) checkout(ref string) error {
+	return runErr("hg", "update", "-q", "-r", v.rev(ref))
+}
+
+func (v hgVCS) addWorktree(dir, ref string) error {
+	// hg archive refuses to write into an existing directory.
+	if err := os.Remove(dir); err != nil {
+		return err
+	}
+	return runErr("hg", "archive", "-r", v.rev(ref), dir)
+}
+
+func (hgVCS) removeWorktree(dir string) error {
+	return nil
+}
+
+// jjVCS is Jujutsu.
+//
+// The working copy commit @ must be empty, i.e. "HEAD" is its parent @-.
+type jjVCS struct{}
+
+func (jjVCS) name() string { return "jj" }
+
+// rev translates git style refs, e.g. "origin/main" is the remote bookmark
+// "main@origin".
+func (jjVCS) rev(ref string) string {
+	if n, ok := headDepth(ref); ok {
+		return "@" + strings.Repeat("-", n+1)
+	}
+	if b, ok := remoteBranch(ref); ok {
+		return b + "@origin"
+	}
+	return ref
+}
+
+func (jjVCS) root(dir string) (string, error) {
+	args := []string{"root"}
+	if dir != "" {
+		args = append(args, "-R", dir)
+	}
+	out, err := run("jj", args...)
+	if err != nil {
+		return "", errors.New(out)
+	}
+	return out, nil
+}
+
+func (v jjVCS) resolve(ref string) (string, error) {
+	out, err := run("jj", "log", "--no-graph", "-n", "1", "-r", v.rev(ref), "-T", "commit_id")
+	if err != nil {
+		return "", errors.New(out)
+	}
+	return out, nil
+}
+
+func (v jjVCS) commitTime(ref string) (time.Time, error) {
+	out, err := run("jj", "log", "--no-graph", "-n", "1", "-r", v.rev(ref), "-T", `committer.timestamp().format("%Y-%m-%dT%H:%M:%S%:z")`)
+	if err != nil {
+		return time.Time{}, errors.New(out)
+	}
+	return time.Parse(time.RFC3339, out)
+}
+
+func (v jjVCS) current() (string, error) {
+	out, err := run("jj", "log", "--no-graph", "-r", "@-", "-T", "change_id.short(16)")
+	if err != nil {
+		return "", errors.New(out)
+	}
+	return out, nil
+}
+
+func (jjVCS) modified() (string, error) {
+	out, err := run("jj", "diff", "--summary", "-r", "@")
+	if err != nil {
+		return "", errors.New(out)
+	}
+	return out, nil
+}
+
+func (v jjVCS) count(a, b string) (int, error) {
+	a, b = v.rev(a), v.rev(b)
+	out, err := run("jj", "log", "--no-graph", "-r", fmt.Sprintf("(%s..%s) | (%s..%s)", a, b, b, a), "-T", `"x"`)
+	if err != nil {
+		return 0, errors.New(out)
+	}
+	return len(out), nil
+}
+
+func (v jjVCS) checkoutCmd(ref string) string {
+	return "jj new " + v.rev(ref)
+}
+
+func (v jjVCS) addWorktreeCmd(dir, ref string) string {
+	return "jj workspace add -r " + v.rev(ref) + " " + dir
+}
+
+func (jjVCS) removeWorktreeCmd(dir string) string {
+	return "jj workspace forget " + filepath.Base(dir)
+}
+
+// checkout creates a new empty working copy commit on top of ref. The
+// previous one is abandoned by jj since it is empty.
+func (v jjVCS) checkout(ref string) error {
+	return runErr("jj", "new", "--quiet", v.rev(ref))
+}
+
+func (v jjVCS) addWorktree(dir, ref string) error {
+	return runErr("jj", "workspace", "add", "--quiet", "--name", filepath.Base(dir), "-r", v.rev(ref), dir)
+}
+
+func (jjVCS) removeWorktree(dir string) error {
+	return runErr("jj", "workspace", "forget", filepath.Base(dir))
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRevTranslation(t *testing.T) {
+	data := []struct {
+		ref, hg, jj string
+	}{
+		{"HEAD", ".", "@-"},
+		{"HEAD~1", ".~1", "@--"},
+		{"HEAD~", ".~1", "@--"},
+		{"HEAD^^", ".~2", "@---"},
+		{"HEAD~x", "HEAD~x", "HEAD~x"},
+		{"main", "main", "main"},
+		{"origin/main", "default", "main@origin"},
+		{"origin/master", "default", "master@origin"},
+		{"origin/stable", "stable", "stable@origin"},
+		{"origin/", "origin/", "origin/"},
+	}
+	for _, l := range data {
+		if got := (hgVCS{}).rev(l.ref); got != l.hg {
+			t.Errorf("hg %q: %q != %q", l.ref, l.hg, got)
+		}
+		if got := (jjVCS{}).rev(l.ref); got != l.jj {
+			t.Errorf("jj %q: %q != %q", l.ref, l.jj, got)
+		}
+	}
+}
+
+func TestDetectVCS(t *testing.T) {
+	d := t.TempDir()
+	sub := filepath.Join(d, "a", "b")
+	if err := os.MkdirAll(sub, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{".git", ".hg", ".jj"} {
+		if err := os.Mkdir(filepath.Join(d, name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		// The last one created wins, e.g. jj is preferred since it can be
+		// colocated with git.
+		if got, want := detectVCS(sub).name(), name[1:]; got != want {
+			t.Fatalf("%s: %q != %q", name, want, got)
+		}
+	}
+}
+
+func TestLFSPointers(t *testing.T) {
+	out := "3b18e512db * data/a.bin\n" +
+		"0c2d9fe4ab - data/b c.bin\n" +
+		"e3b0c44298 * weights.pt"
+	got := lfsPointers(out)
+	if len(got) != 1 || got[0] != "data/b c.bin" {
+		t.Fatal(got)
+	}
+	if got := lfsPointers(""); len(got) != 0 {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// verifyArgs returns the go test arguments to run the unit tests matching
+// -verify on the side's packages matching pattern.
+func verifyArgs(o *benchOptions, s *side, pattern string) []string {
+	args := append([]string{"test", "-run", o.verify, "-count=1"}, s.goBuildFlags(o)...)
+	return append(args, pattern)
+}
+
+// verifySides runs the unit tests matching -verify on each side, once per set
+// of test binaries, so a broken side is not benchmarked.
+func verifySides(ctx context.Context, o *benchOptions, branch string, sides []*side) error {
+	seen := map[string]bool{}
+	for _, s := range sides {
+		// There is no source to test.
+		if s.cmd != "" || s.bin != "" {
+			continue
+		}
+		k := s.buildKey()
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		err := inSide(o, branch, s, func() error {
+			for _, m := range splitModules(s.dir, o.pkgPattern()) {
+				args := verifyArgs(o, s, m.pattern)
+				o.logCmd("%s%s %s", s.logPrefix(m.dir, s.env), s.goCmd(), strings.Join(args, " "))
+				c := command(s.goCmd(), args...)
+				c.Dir = m.dir
+				c.Env = append(os.Environ(), s.env...)
+				if out, err := combinedOutput(ctx, c); err != nil {
+					return fmt.Errorf("-verify: the tests of %s fail: %w\n%s", s.name, err, out)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/perf/benchmath"
+)
+
+// warmupAlpha is the p-value cutoff to consider a leading series slower than
+// the following ones. It is divided by the number of benchmarks compared.
+const warmupAlpha = 0.05
+
+// warmupSeries returns the number of leading series that are warmup outliers:
+// at least one benchmark of one side is significantly slower than in the
+// following series combined, e.g. due to cold caches. At least two series are
+// kept.
+//
+// marks are the offsets in stats where each series starts.
+func warmupSeries(stats []string, marks [][]int) (int, error) {
+	n := 0
+	for ; n < len(marks)-2; n++ {
+		warm := false
+		for j := range stats {
+			end := marks[n+1][j]
+			slower, err := isSlower(stats[j][marks[n][j]:end], stats[j][end:])
+			if err != nil {
+				return 0, err
+			}
+			if slower {
+				warm = true
+				break
+			}
+		}
+		if !warm {
+			break
+		}
+	}
+	return n, nil
+}
+
+// isSlower returns true if any benchmark is significantly slower in head than
+// in rest.
+func isSlower(head, rest string) (bool, error) {
+	h, err := parseSamples(head)
+	if err != nil {
+		return false, err
+	}
+	r, err := parseSamples(rest)
+	if err != nil {
+		return false, err
+	}
+	var keys []string
+	for _, k := range h.keys {
+		if strings.HasSuffix(k, " sec/op") && len(r.values[k]) != 0 {
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range keys {
+		a := benchmath.NewSample(h.values[k], &benchmath.DefaultThresholds)
+		b := benchmath.NewSample(r.values[k], &benchmath.DefaultThresholds)
+		c := benchmath.AssumeNothing.Compare(a, b)
+		if c.P < warmupAlpha/float64(len(keys)) && median(a) > median(b) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// median returns the median of a sample.
+func median(s *benchmath.Sample) float64 {
+	return benchmath.AssumeNothing.Summary(s, 0.95).Center
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWarmupSeries(t *testing.T) {
+	series := func(ns ...int) string {
+		out := "pkg: example.com/a\n"
+		for _, v := range ns {
+			out += fmt.Sprintf("BenchmarkFoo 10 %d ns/op\nBenchmarkBar 10 50 ns/op\n", v)
+		}
+		return out
+	}
+	data := []struct {
+		series [][]int
+		want   int
+	}{
+		// Cold first series.
+		{[][]int{{200, 210, 205, 220, 215}, {100, 101, 99, 100, 102}, {100, 98, 101, 99, 100}, {101, 100, 99, 100, 98}}, 1},
+		// Faster first series are kept.
+		{[][]int{{50, 51, 52, 50, 51}, {100, 101, 99, 100, 102}, {100, 98, 101, 99, 100}}, 0},
+		// Two cold series.
+		{[][]int{{300, 310, 305, 320, 315}, {200, 210, 205, 220, 215}, {100, 101, 99, 100, 102}, {100, 98, 101, 99, 100}, {101, 100, 99, 100, 98}}, 2},
+		// At least two series are kept.
+		{[][]int{{200, 210, 205, 220, 215}, {100, 101, 99, 100, 102}}, 0},
+	}
+	for i, l := range data {
+		// The first side is stable, the second one has the warmup effect.
+		stats := []string{"previous\n", "previous\n"}
+		var marks [][]int
+		for _, s := range l.series {
+			marks = append(marks, []int{len(stats[0]), len(stats[1])})
+			stats[0] += series(100, 100, 101, 99, 100)
+			stats[1] += series(s...)
+		}
+		n, err := warmupSeries(stats, marks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != l.want {
+			t.Fatalf("#%d: %d != %d", i, l.want, n)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// watchPoll is the interval between two checks of the source files.
+const watchPoll = 500 * time.Millisecond
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+// isSourceFile returns true if a change to the file at p, relative to the
+// root of the repository, may change the benchmark results.
+func isSourceFile(p string) bool {
+	switch filepath.Ext(p) {
+	case ".go", ".s", ".c", ".h", ".cc", ".cpp", ".syso":
+		return true
+	}
+	switch filepath.Base(p) {
+	case "go.mod", "go.sum", "go.work", "go.work.sum":
+		return true
+	}
+	return strings.HasPrefix(p, "testdata/") || strings.Contains(p, "/testdata/")
+}
+
+// sourceStamps returns the stamp of each source file of the working tree,
+// including the untracked ones that are not ignored.
+func sourceStamps() (map[string]fileStamp, error) {
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, errors.New(root)
+	}
+	files, err := git("-C", root, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, errors.New(files)
+	}
+	out := map[string]fileStamp{}
+	for _, f := range strings.Split(files, "\x00") {
+		if f == "" || !isSourceFile(f) {
+			continue
+		}
+		// A deleted file is a change too.
+		var st fileStamp
+		if fi, err := os.Stat(filepath.Join(root, filepath.FromSlash(f))); err == nil {
+			st = fileStamp{mod: fi.ModTime(), size: fi.Size()}
+		}
+		out[f] = st
+	}
+	return out, nil
+}
+
+// waitForChange returns the new stamps once the source files differ from
+// prev and stopped changing for a poll interval, e.g. while an editor saves
+// multiple files.
+func waitForChange(ctx context.Context, prev map[string]fileStamp) (map[string]fileStamp, error) {
+	t := time.NewTicker(watchPoll)
+	defer t.Stop()
+	var changed map[string]fileStamp
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		cur, err := sourceStamps()
+		if err != nil {
+			return nil, err
+		}
+		if changed != nil && reflect.DeepEqual(cur, changed) {
+			return cur, nil
+		}
+		changed = nil
+		if !reflect.DeepEqual(cur, prev) {
+			changed = cur
+		}
+	}
+}
+
+// watch benchmarks a copy of the working tree as the last side against the
+// other ones, then again each time a source file changes, until ctx is
+// canceled.
+//
+// A failed run, e.g. because the code does not compile while being edited, is
+// reported and the watch continues.
+func watch(ctx context.Context, o *benchOptions, sides []*side, series int, nowarm bool, print func(*session) error) error {
+	d := sides[len(sides)-1]
+	d.name = "working tree"
+	stamps, err := sourceStamps()
+	if err != nil {
+		return err
+	}
+	for {
+		err := func() error {
+			dir, cleanup, err := copyWorkingTree(o)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			d.dir = dir
+			// The copy has a new path so it is always built again.
+			s, err := runSession(ctx, o, sides, series, nowarm)
+			if err != nil || ctx.Err() != nil {
+				return err
+			}
+			fmt.Printf("\n%s\n", time.Now().Format("15:04:05"))
+			return print(s)
+		}()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			stderrLog.errorf("%s", err)
+		}
+		stderrLog.infof("watching for changes; press Ctrl-C to stop")
+		if stamps, err = waitForChange(ctx, stamps); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
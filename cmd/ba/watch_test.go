@@ -0,0 +1,25 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestIsSourceFile(t *testing.T) {
+	for p, want := range map[string]bool{
+		"x.go":                true,
+		"a/b/asm_amd64.s":     true,
+		"go.mod":              true,
+		"sub/go.sum":          true,
+		"testdata/in.txt":     true,
+		"a/testdata/x/y.json": true,
+		"README.md":           false,
+		"a/doc.txt":           false,
+		"mytestdata/x":        false,
+	} {
+		if got := isSourceFile(p); got != want {
+			t.Fatal(p, got)
+		}
+	}
+}
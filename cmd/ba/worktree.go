@@ -0,0 +1,151 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// addWorktrees creates a temporary worktree for each side that has a ref, so
+// the current checkout is never touched.
+//
+// The returned function must be called to delete the worktrees.
+func addWorktrees(o *benchOptions, sides []*side) (func(), error) {
+	// Run in the same relative directory in the worktree as the current one.
+	root, err := repo.root("")
+	if err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	// The root is reported with the symlinks resolved.
+	if wd, err = filepath.EvalSymlinks(wd); err != nil {
+		return nil, err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, err
+	}
+	prefix, err := filepath.Rel(root, wd)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	cleanup := func() {
+		for _, d := range dirs {
+			o.logCmd("%s", repo.removeWorktreeCmd(d))
+			if err2 := repo.removeWorktree(d); err2 != nil {
+				stderrLog.errorf("failed to remove worktree %s: %s", d, err2)
+			}
+			_ = os.RemoveAll(d)
+		}
+	}
+	// The sides of an -env-matrix share the worktree of their ref.
+	byRef := map[string]string{}
+	for _, s := range sides {
+		if s.ref == "" {
+			continue
+		}
+		if d, ok := byRef[s.ref]; ok {
+			s.dir = d
+			continue
+		}
+		d, err2 := os.MkdirTemp("", "ba-")
+		if err2 != nil {
+			cleanup()
+			return nil, err2
+		}
+		dirs = append(dirs, d)
+		o.logCmd("%s", repo.addWorktreeCmd(d, s.ref))
+		if err2 := repo.addWorktree(d, s.ref); err2 != nil {
+			cleanup()
+			return nil, err2
+		}
+		s.dir = filepath.Join(d, prefix)
+		byRef[s.ref] = s.dir
+	}
+	return cleanup, nil
+}
+
+// copyWorkingTree copies the working tree as is, including the uncommitted
+// changes and the untracked files that are not ignored, into a temporary
+// directory. It returns the directory matching the current one in the copy.
+//
+// The returned function must be called to delete the copy.
+func copyWorkingTree(o *benchOptions) (string, func(), error) {
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", nil, errors.New(root)
+	}
+	prefix, err := git("rev-parse", "--show-prefix")
+	if err != nil {
+		return "", nil, errors.New(prefix)
+	}
+	files, err := git("-C", root, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return "", nil, errors.New(files)
+	}
+	d, err := os.MkdirTemp("", "ba-tree-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(d)
+	}
+	o.logCmd("cp -a <working tree> %s", d)
+	for _, f := range strings.Split(files, "\x00") {
+		if f == "" {
+			continue
+		}
+		if err = copyFile(filepath.Join(root, filepath.FromSlash(f)), filepath.Join(d, filepath.FromSlash(f))); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+	}
+	return filepath.Join(d, filepath.FromSlash(prefix)), cleanup, nil
+}
+
+// copyFile copies a file or a symlink, creating the parent directories.
+// Deleted files and directories, e.g. submodules, are skipped.
+func copyFile(src, dst string) error {
+	fi, err := os.Lstat(src)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil || fi.IsDir() {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		l, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(l, dst)
+	}
+	/* #nosec G304 */
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	/* #nosec G304 */
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
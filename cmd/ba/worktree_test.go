@@ -0,0 +1,32 @@
+// Copyright 2026 Marc-Antoine Ruel. All rights reserved.
+// Use of this source code is governed under the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyFile(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "a"), []byte("hello"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyFile(filepath.Join(src, "a"), filepath.Join(dst, "d", "a")); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(dst, "d", "a"))
+	if err != nil || fi.Size() != 5 {
+		t.Fatal(fi, err)
+	}
+	// Deleted files are skipped.
+	if err = copyFile(filepath.Join(src, "b"), filepath.Join(dst, "b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Lstat(filepath.Join(dst, "b")); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+}